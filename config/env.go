@@ -19,6 +19,7 @@ type Env struct {
 	GinMode            string
 	ServerPort         string
 	JwtKey             string
+	JwtKeyringFile     string // JSON file with the signing keys; JwtKey is used when it is not set
 	log                ILogger
 }
 
@@ -50,6 +51,7 @@ func NewGetEnv() *Env {
 			GinMode:            os.Getenv("GIN_MODE"),
 			ServerPort:         os.Getenv("SERVER_PORT"),
 			JwtKey:             os.Getenv("JWT_KEY"),
+			JwtKeyringFile:     os.Getenv("JWT_KEYRING_FILE"),
 			log:                NewLogger(),
 		}
 	})
@@ -82,8 +84,8 @@ func validateEnvVariables(env *Env) {
 	if env.GinMode == "" {
 		log.Fatal("GIN_MODE is required but not set")
 	}
-	if env.JwtKey == "" {
-		log.Fatal("JWT_KEY is required but not set")
+	if env.JwtKey == "" && env.JwtKeyringFile == "" {
+		log.Fatal("JWT_KEY or JWT_KEYRING_FILE is required but not set")
 	}
 
 }
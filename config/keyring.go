@@ -0,0 +1,248 @@
+package config
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Algoritmos de firma admitidos
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmRS256 = "RS256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+// Estados de una clave del llavero. Solo la clave activa firma; las de verificación siguen aceptando
+// los tokens que firmaron mientras estaban activas, y las retiradas los rechazan.
+const (
+	KeyStatusActive  = "active"
+	KeyStatusVerify  = "verify"
+	KeyStatusRetired = "retired"
+)
+
+// defaultKeyID es el kid de la clave que se arma con JWT_KEY cuando no hay archivo de llavero
+const defaultKeyID = "default"
+
+// ErrUnknownKey is returned when a token names a key that isn't in the keyring or was retired
+var ErrUnknownKey = errors.New("unknown or retired signing key")
+
+// KeyConfig es una clave del archivo de llavero (JWT_KEYRING_FILE). Las claves HS256 llevan el
+// secreto; las RS256 y EdDSA llevan la clave privada en PEM, o solo la pública si ya no firman.
+type KeyConfig struct {
+	ID         string `json:"kid"`
+	Algorithm  string `json:"alg"`
+	Status     string `json:"status"`
+	Secret     string `json:"secret,omitempty"`
+	PrivateKey string `json:"private_key,omitempty"`
+	PublicKey  string `json:"public_key,omitempty"`
+}
+
+// signingKey es una clave del llavero ya interpretada
+type signingKey struct {
+	id        string
+	status    string
+	method    jwt.SigningMethod
+	signKey   interface{} // nil for keys that can only verify
+	verifyKey interface{}
+}
+
+// Keyring son las claves con las que se firman y verifican los tokens, identificadas por el kid
+// de la cabecera del token. Rotar la clave activa no invalida los tokens ya emitidos mientras la
+// anterior siga en el llavero con estado verify.
+type Keyring struct {
+	keys   map[string]*signingKey
+	order  []string
+	active *signingKey
+}
+
+// NewKeyring arma el llavero a partir de sus claves. Debe haber exactamente una clave activa.
+func NewKeyring(configs []KeyConfig) (*Keyring, error) {
+	keyring := &Keyring{keys: make(map[string]*signingKey, len(configs))}
+	for _, keyConfig := range configs {
+		if keyConfig.ID == "" {
+			return nil, errors.New("keyring: every key needs a kid")
+		}
+		if _, exists := keyring.keys[keyConfig.ID]; exists {
+			return nil, fmt.Errorf("keyring: duplicate kid %q", keyConfig.ID)
+		}
+		key, err := parseKey(keyConfig)
+		if err != nil {
+			return nil, fmt.Errorf("keyring: key %q: %w", keyConfig.ID, err)
+		}
+		if key.status == KeyStatusActive {
+			if keyring.active != nil {
+				return nil, fmt.Errorf("keyring: keys %q and %q are both active", keyring.active.id, key.id)
+			}
+			if key.signKey == nil {
+				return nil, fmt.Errorf("keyring: active key %q has no private key", key.id)
+			}
+			keyring.active = key
+		}
+		keyring.keys[key.id] = key
+		keyring.order = append(keyring.order, key.id)
+	}
+	if keyring.active == nil {
+		return nil, errors.New("keyring: no active key")
+	}
+	return keyring, nil
+}
+
+// LoadKeyring lee el llavero de JWT_KEYRING_FILE. Sin archivo, usa JWT_KEY como única clave HS256.
+func LoadKeyring(env *Env) (*Keyring, error) {
+	if env.JwtKeyringFile == "" {
+		if env.JwtKey == "" {
+			return nil, errors.New("JWT_KEYRING_FILE or JWT_KEY must be set")
+		}
+		return NewKeyring([]KeyConfig{{ID: defaultKeyID, Algorithm: AlgorithmHS256, Status: KeyStatusActive, Secret: env.JwtKey}})
+	}
+
+	data, err := os.ReadFile(env.JwtKeyringFile)
+	if err != nil {
+		return nil, fmt.Errorf("keyring: %w", err)
+	}
+	var file struct {
+		Keys []KeyConfig `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("keyring: %w", err)
+	}
+	return NewKeyring(file.Keys)
+}
+
+// parseKey interpreta el material de una clave según su algoritmo
+func parseKey(keyConfig KeyConfig) (*signingKey, error) {
+	key := &signingKey{id: keyConfig.ID, status: keyConfig.Status}
+	switch keyConfig.Status {
+	case KeyStatusActive, KeyStatusVerify, KeyStatusRetired:
+	default:
+		return nil, fmt.Errorf("invalid status %q", keyConfig.Status)
+	}
+
+	switch keyConfig.Algorithm {
+	case AlgorithmHS256:
+		if keyConfig.Secret == "" {
+			return nil, errors.New("HS256 keys need a secret")
+		}
+		key.method = jwt.SigningMethodHS256
+		key.signKey = []byte(keyConfig.Secret)
+		key.verifyKey = key.signKey
+	case AlgorithmRS256:
+		key.method = jwt.SigningMethodRS256
+		if keyConfig.PrivateKey != "" {
+			private, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(keyConfig.PrivateKey))
+			if err != nil {
+				return nil, err
+			}
+			key.signKey = private
+			key.verifyKey = &private.PublicKey
+		} else if keyConfig.PublicKey != "" {
+			public, err := jwt.ParseRSAPublicKeyFromPEM([]byte(keyConfig.PublicKey))
+			if err != nil {
+				return nil, err
+			}
+			key.verifyKey = public
+		}
+	case AlgorithmEdDSA:
+		key.method = jwt.SigningMethodEdDSA
+		if keyConfig.PrivateKey != "" {
+			private, err := jwt.ParseEdPrivateKeyFromPEM([]byte(keyConfig.PrivateKey))
+			if err != nil {
+				return nil, err
+			}
+			key.signKey = private
+			key.verifyKey = private.(ed25519.PrivateKey).Public()
+		} else if keyConfig.PublicKey != "" {
+			public, err := jwt.ParseEdPublicKeyFromPEM([]byte(keyConfig.PublicKey))
+			if err != nil {
+				return nil, err
+			}
+			key.verifyKey = public
+		}
+	default:
+		return nil, fmt.Errorf("unsupported algorithm %q", keyConfig.Algorithm)
+	}
+	if key.verifyKey == nil {
+		return nil, fmt.Errorf("%s keys need a private_key or a public_key", keyConfig.Algorithm)
+	}
+	return key, nil
+}
+
+// sign firma los claims con la clave activa y pone su kid en la cabecera del token
+func (k *Keyring) sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(k.active.method, claims)
+	token.Header["kid"] = k.active.id
+	return token.SignedString(k.active.signKey)
+}
+
+// verificationKey devuelve la clave con la que se verifica un token según su kid. El algoritmo de la
+// cabecera debe coincidir con el de la clave, para que no se pueda verificar una firma HS256 usando
+// como secreto una clave pública.
+func (k *Keyring) verificationKey(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, exists := k.keys[kid]
+	if !exists || key.status == KeyStatusRetired {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, kid)
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return key.verifyKey, nil
+}
+
+// JWK es una clave pública en formato JSON Web Key (RFC 7517)
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Algorithm string `json:"alg"`
+	Use       string `json:"use"`
+	Curve     string `json:"crv,omitempty"` // OKP keys
+	X         string `json:"x,omitempty"`   // OKP keys
+	Modulus   string `json:"n,omitempty"`   // RSA keys
+	Exponent  string `json:"e,omitempty"`   // RSA keys
+}
+
+// JWKS es el conjunto de claves públicas que otros servicios usan para verificar los tokens
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// PublicKeys devuelve las claves públicas que no están retiradas. Las claves HS256 son secretos
+// compartidos y nunca se publican.
+func (k *Keyring) PublicKeys() JWKS {
+	jwks := JWKS{Keys: []JWK{}}
+	for _, id := range k.order {
+		key := k.keys[id]
+		if key.status == KeyStatusRetired {
+			continue
+		}
+		switch public := key.verifyKey.(type) {
+		case *rsa.PublicKey:
+			jwks.Keys = append(jwks.Keys, JWK{
+				KeyType:   "RSA",
+				KeyID:     key.id,
+				Algorithm: AlgorithmRS256,
+				Use:       "sig",
+				Modulus:   base64.RawURLEncoding.EncodeToString(public.N.Bytes()),
+				Exponent:  base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			jwks.Keys = append(jwks.Keys, JWK{
+				KeyType:   "OKP",
+				KeyID:     key.id,
+				Algorithm: AlgorithmEdDSA,
+				Use:       "sig",
+				Curve:     "Ed25519",
+				X:         base64.RawURLEncoding.EncodeToString(public),
+			})
+		}
+	}
+	return jwks
+}
@@ -1,8 +1,12 @@
 package config
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -10,70 +14,140 @@ import (
 	"github.com/golang-jwt/jwt/v4"
 )
 
+// Vigencia de los tokens. El token de acceso dura poco porque solo se puede revocar consultando la
+// lista de revocados; la sesión se mantiene con el token de refresco, que se rota en cada uso.
+const (
+	AccessTokenTTL  = 15 * time.Minute
+	RefreshTokenTTL = 30 * 24 * time.Hour
+)
+
+// RevocationList indica si un token de acceso fue revocado antes de vencer, a partir de su ID (jti).
+type RevocationList interface {
+	IsRevoked(tokenID string) (bool, error)
+}
+
 // TokenManager es la estructura que manejará la generación y validación de tokens y el middleware.
 type TokenManager struct {
-	jwtKey []byte
-	env    *Env
+	keyring     *Keyring
+	revocations RevocationList
 }
 
-// Claims define la estructura de los datos que queremos almacenar en el token JWT.
+// Claims define la estructura de los datos que queremos almacenar en el token JWT. El ID del
+// usuario viaja como subject (sub); el nombre solo se conserva para mostrarlo, porque no es único.
 type Claims struct {
 	Username string `json:"username"`
+	Role     string `json:"role"`
+	StoreIDs []uint `json:"store_ids,omitempty"` // Stores the user works at, for store staff
 	jwt.RegisteredClaims
 }
 
-// NewTokenManager es el constructor que carga la clave JWT desde las variables de entorno.
+// NewTokenManager es el constructor que carga el llavero desde JWT_KEYRING_FILE, o la clave
+// JWT_KEY si no hay archivo.
 func NewTokenManager() *TokenManager {
-	jwtKey := NewGetEnv().JwtKey
-	if jwtKey == "" {
-		panic("JWT_KEY not set in environment variables")
+	keyring, err := LoadKeyring(NewGetEnv())
+	if err != nil {
+		panic(err.Error())
 	}
+	return NewTokenManagerWithKeyring(keyring)
+}
+
+// NewTokenManagerWithKeyring crea un TokenManager que firma y verifica con el llavero indicado.
+func NewTokenManagerWithKeyring(keyring *Keyring) *TokenManager {
 	return &TokenManager{
-		jwtKey: []byte(jwtKey),
+		keyring: keyring,
+	}
+}
+
+// PublicKeys devuelve el JWKS con las claves públicas del llavero.
+func (tm *TokenManager) PublicKeys() JWKS {
+	return tm.keyring.PublicKeys()
+}
+
+// WithRevocationList hace que AuthMiddleware rechace los tokens revocados en la lista indicada.
+func (tm *TokenManager) WithRevocationList(revocations RevocationList) *TokenManager {
+	tm.revocations = revocations
+	return tm
+}
+
+// NewTokenID genera un identificador aleatorio para un token.
+func NewTokenID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
 	}
+	return hex.EncodeToString(buf), nil
 }
 
-// GenerateToken genera un nuevo token JWT para el usuario proporcionado.
-func (tm *TokenManager) GenerateToken(username string) (string, error) {
-	expirationTime := time.Now().Add(24 * time.Hour) // Token válido por 24 horas
+// GenerateToken genera un nuevo token de acceso JWT para el usuario, su rol y las tiendas a las que está
+// vinculado. Devuelve también los claims, cuyo ID (jti) es el que se usa para revocarlo.
+func (tm *TokenManager) GenerateToken(userID uint, username string, role string, storeIDs []uint) (string, *Claims, error) {
+	tokenID, err := NewTokenID()
+	if err != nil {
+		return "", nil, err
+	}
+	now := time.Now()
 	claims := &Claims{
 		Username: username,
+		Role:     role,
+		StoreIDs: storeIDs,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expirationTime),
+			ID:        tokenID,
+			Subject:   strconv.FormatUint(uint64(userID), 10),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(AccessTokenTTL)),
 		},
 	}
 
-	// Crear el token con el método de firma HS256 y los claims definidos.
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString(tm.jwtKey)
+	// Firmar el token con la clave activa del llavero; su kid queda en la cabecera.
+	tokenString, err := tm.keyring.sign(claims)
 	if err != nil {
-		return "", err
+		return "", nil, err
 	}
 
-	return tokenString, nil
+	return tokenString, claims, nil
 }
 
 // ValidateToken valida el token JWT proporcionado.
 func (tm *TokenManager) ValidateToken(tokenString string) (*Claims, error) {
 	claims := &Claims{}
 
-	// Analizar el token y verificar si es válido.
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return tm.jwtKey, nil
-	})
+	// Analizar el token y verificar su firma con la clave del llavero que indica su kid.
+	token, err := jwt.ParseWithClaims(tokenString, claims, tm.keyring.verificationKey)
 	if err != nil {
 		return nil, err
 	}
 	if !token.Valid {
 		return nil, fmt.Errorf("invalid token")
 	}
+	if _, err := claims.UserID(); err != nil {
+		return nil, err
+	}
+	if claims.ID == "" {
+		return nil, errors.New("token has no ID")
+	}
 
 	return claims, nil
 }
 
+// UserID devuelve el ID del usuario guardado en el subject del token.
+func (c *Claims) UserID() (uint, error) {
+	id, err := strconv.ParseUint(c.Subject, 10, 64)
+	if err != nil || id == 0 {
+		return 0, errors.New("token subject is not a user ID")
+	}
+	return uint(id), nil
+}
+
+// UserIDFromContext devuelve el ID del usuario autenticado por AuthMiddleware.
+func UserIDFromContext(c *gin.Context) (uint, bool) {
+	value, exists := c.Get("user_id")
+	if !exists {
+		return 0, false
+	}
+	id, ok := value.(uint)
+	return id, ok && id != 0
+}
+
 // AuthMiddleware es el middleware que valida el token JWT.
 func (tm *TokenManager) AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -101,8 +175,28 @@ func (tm *TokenManager) AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		// Establecer el usuario en el contexto para acceder a él en los controladores
+		// Rechazar los tokens revocados antes de vencer, por ejemplo al cerrar sesión o cambiar la contraseña
+		if tm.revocations != nil {
+			revoked, err := tm.revocations.IsRevoked(claims.ID)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not check token revocation"})
+				c.Abort()
+				return
+			}
+			if revoked {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Token has been revoked"})
+				c.Abort()
+				return
+			}
+		}
+
+		// Establecer el usuario, su rol y sus tiendas en el contexto para acceder a ellos en los controladores
+		userID, _ := claims.UserID()
+		c.Set("user_id", userID)
+		c.Set("token_id", claims.ID)
 		c.Set("username", claims.Username)
+		c.Set("role", claims.Role)
+		c.Set("store_ids", claims.StoreIDs)
 		c.Next()
 	}
 }
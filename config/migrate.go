@@ -9,6 +9,11 @@ import (
 	"gorm.io/gorm"
 )
 
+// legacyAdditionalMinAmount es el monto mínimo de las campañas 'additional' anteriores a los parámetros.
+// Antes aplicaban a compras mayores a 20000, y MinAmount es inclusivo; como los montos tienen dos
+// decimales, exigir 20000.01 conserva el mismo límite.
+const legacyAdditionalMinAmount = 20000.01
+
 // Migrator es la clase encargada de las migraciones de la base de datos
 type Migrator struct {
 	db     *gorm.DB
@@ -32,6 +37,45 @@ func NewMigrator(connection IDatabaseConnection) (*Migrator, error) {
 
 // Migrate realiza las migraciones de las entidades de la base de datos
 func (m *Migrator) Migrate() error {
+	// Fusionar saldos duplicados antes de crear el índice único (user_id, store_id)
+	if m.db.Migrator().HasTable(&models.AccumulatedReward{}) {
+		if _, err := m.MergeDuplicateAccumulatedRewards(); err != nil {
+			m.logger.Error(fmt.Sprintf("Error al fusionar saldos duplicados: %v", err))
+			return err
+		}
+	}
+
+	// Los tipos de campaña válidos son las reglas registradas, ya no un CHECK en la tabla
+	legacyCampaigns := m.db.Migrator().HasTable(&models.Campaign{}) && !m.db.Migrator().HasColumn(&models.Campaign{}, "MinAmount")
+	if m.db.Migrator().HasConstraint(&models.Campaign{}, "chk_campaigns_type") {
+		if err := m.db.Migrator().DropConstraint(&models.Campaign{}, "chk_campaigns_type"); err != nil {
+			m.logger.Error(fmt.Sprintf("Error al eliminar la restricción de tipo de campaña: %v", err))
+			return err
+		}
+	}
+
+	// El rango de un nivel solo es único entre los no eliminados; el índice anterior incluía los eliminados
+	if m.db.Migrator().HasIndex(&models.Tier{}, "idx_tiers_store_rank") {
+		if err := m.db.Migrator().DropIndex(&models.Tier{}, "idx_tiers_store_rank"); err != nil {
+			m.logger.Error(fmt.Sprintf("Error al eliminar el índice de rangos de nivel: %v", err))
+			return err
+		}
+	}
+
+	// Un par de tiendas solo tiene una tasa no eliminada; el índice anterior incluía las eliminadas
+	if m.db.Migrator().HasIndex(&models.ExchangeRate{}, "idx_exchange_rates_pair") {
+		if err := m.db.Migrator().DropIndex(&models.ExchangeRate{}, "idx_exchange_rates_pair"); err != nil {
+			m.logger.Error(fmt.Sprintf("Error al eliminar el índice de pares de tasas: %v", err))
+			return err
+		}
+	}
+
+	// Los usuarios anteriores a los roles quedan como clientes, salvo el administrador por defecto
+	legacyUsers := m.db.Migrator().HasTable(&models.User{}) && !m.db.Migrator().HasColumn(&models.User{}, "Role")
+
+	// Las compras anteriores al pago con cashback se pagaron completas por otros medios
+	legacyTransactions := m.db.Migrator().HasTable(&models.Transaction{}) && !m.db.Migrator().HasColumn(&models.Transaction{}, "NetAmount")
+
 	// Realiza las migraciones de las entidades de la base de datos
 	err := m.db.AutoMigrate(
 		models.AccumulatedReward{},
@@ -41,16 +85,59 @@ func (m *Migrator) Migrate() error {
 		models.Transaction{},
 		models.User{}, // Realiza la migración de User
 		models.Store{},
+		models.PointsLedgerEntry{},
+		models.Redemption{},
+		models.TransactionCampaign{},
+		models.PointsLot{},
+		models.PointsLotConsumption{},
+		models.IdempotencyKey{},
+		models.Refund{},
+		models.Tier{},
+		models.UserTier{},
+		models.TierHistory{},
+		models.PointTransfer{},
+		models.ExchangeRate{},
+		models.PointsExchange{},
+		models.RefreshToken{},
+		models.RevokedToken{},
 	)
 	if err != nil {
 		m.logger.Error(fmt.Sprintf("Error al migrar la base de datos: %v", err))
 		return err
 	}
 
+	// Conservar el comportamiento anterior de las campañas 'additional': 30% sobre compras mayores a 20000
+	if legacyCampaigns {
+		if err := m.backfillLegacyCampaigns(); err != nil {
+			m.logger.Error(fmt.Sprintf("Error al actualizar las campañas existentes: %v", err))
+			return err
+		}
+	}
+
+	if legacyTransactions {
+		if err := m.backfillTransactionNetAmounts(); err != nil {
+			m.logger.Error(fmt.Sprintf("Error al actualizar el monto neto de las transacciones: %v", err))
+			return err
+		}
+	}
+
+	// Registrar el saldo de apertura de los acumulados que existían antes del libro de puntos
+	if err := m.seedLedgerOpeningBalances(); err != nil {
+		m.logger.Error(fmt.Sprintf("Error al registrar los saldos de apertura: %v", err))
+		return err
+	}
+
+	// Los puntos acumulados antes de los lotes se conservan en un lote de apertura que no vence
+	if err := m.seedOpeningPointsLots(); err != nil {
+		m.logger.Error(fmt.Sprintf("Error al registrar los lotes de apertura: %v", err))
+		return err
+	}
+
 	// Crear un usuario por defecto después de migrar la tabla User
 	defaultUser := models.User{
 		Name:  "Admin",
 		Email: "admin@example.com",
+		Role:  models.RolePlatformAdmin,
 	}
 
 	// Hashear la contraseña antes de guardarla
@@ -74,8 +161,167 @@ func (m *Migrator) Migrate() error {
 		m.logger.Success("Usuario por defecto creado exitosamente")
 	} else {
 		m.logger.Info("El usuario por defecto ya existe, no se creó uno nuevo")
+		if legacyUsers {
+			if err := m.db.Model(&user).Update("role", models.RolePlatformAdmin).Error; err != nil {
+				m.logger.Error(fmt.Sprintf("Error al asignar el rol al usuario por defecto: %v", err))
+				return err
+			}
+		}
 	}
 
 	m.logger.Success("Migraciones completadas exitosamente")
 	return nil
 }
+
+// seedLedgerOpeningBalances crea un movimiento de ajuste por cada saldo que todavía no tiene movimientos,
+// de forma que el saldo acumulado en el libro coincida con el de AccumulatedReward
+func (m *Migrator) seedLedgerOpeningBalances() error {
+	var balances []models.AccumulatedReward
+	err := m.db.
+		Where("points_accumulated <> 0 OR cashback_accumulated <> 0").
+		Where("NOT EXISTS (SELECT 1 FROM points_ledger_entries e WHERE e.user_id = accumulated_rewards.user_id AND e.store_id = accumulated_rewards.store_id)").
+		Find(&balances).Error
+	if err != nil {
+		return err
+	}
+
+	for _, balance := range balances {
+		entry := models.PointsLedgerEntry{
+			UserID:      balance.UserID,
+			StoreID:     balance.StoreID,
+			EntryType:   models.LedgerEntryAdjust,
+			Points:      balance.PointsAccumulated,
+			Cashback:    balance.CashbackAccumulated,
+			Description: "opening balance",
+		}
+		if err := m.db.Create(&entry).Error; err != nil {
+			return err
+		}
+	}
+	if len(balances) > 0 {
+		m.logger.Info("Saldos de apertura registrados en el libro de puntos: %d", len(balances))
+	}
+	return nil
+}
+
+// seedOpeningPointsLots crea un lote sin vencimiento por cada saldo con puntos que todavía no tiene lotes,
+// de forma que la suma de los lotes coincida con el saldo de AccumulatedReward
+func (m *Migrator) seedOpeningPointsLots() error {
+	var balances []models.AccumulatedReward
+	err := m.db.
+		Where("points_accumulated > 0").
+		Where("NOT EXISTS (SELECT 1 FROM points_lots l WHERE l.user_id = accumulated_rewards.user_id AND l.store_id = accumulated_rewards.store_id)").
+		Find(&balances).Error
+	if err != nil {
+		return err
+	}
+
+	for _, balance := range balances {
+		lot := models.PointsLot{
+			UserID:    balance.UserID,
+			StoreID:   balance.StoreID,
+			Points:    balance.PointsAccumulated,
+			Remaining: balance.PointsAccumulated,
+			EarnedAt:  balance.CreatedAt,
+		}
+		if err := m.db.Create(&lot).Error; err != nil {
+			return err
+		}
+	}
+	if len(balances) > 0 {
+		m.logger.Info("Lotes de apertura registrados: %d", len(balances))
+	}
+	return nil
+}
+
+// MergeDuplicateAccumulatedRewards fusiona las filas de accumulated_rewards que comparten (user_id, store_id).
+// Conserva la fila activa más antigua con la suma de los saldos activos y elimina físicamente el resto.
+// Devuelve el número de filas eliminadas.
+func (m *Migrator) MergeDuplicateAccumulatedRewards() (int, error) {
+	type duplicateKey struct {
+		UserID  uint
+		StoreID uint
+	}
+	var keys []duplicateKey
+	err := m.db.Unscoped().Model(&models.AccumulatedReward{}).
+		Select("user_id, store_id").
+		Group("user_id, store_id").
+		Having("COUNT(*) > 1").
+		Scan(&keys).Error
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	err = m.db.Transaction(func(tx *gorm.DB) error {
+		for _, key := range keys {
+			var rows []models.AccumulatedReward
+			if err := tx.Unscoped().
+				Where("user_id = ? AND store_id = ?", key.UserID, key.StoreID).
+				Order("id ASC").
+				Find(&rows).Error; err != nil {
+				return err
+			}
+
+			keep := rows[0]
+			points, cashback := 0.0, 0.0
+			var duplicateIDs []uint
+			for _, row := range rows {
+				if row.DeletedAt.Valid {
+					continue
+				}
+				if keep.DeletedAt.Valid {
+					keep = row
+				}
+				points += row.PointsAccumulated
+				cashback += row.CashbackAccumulated
+			}
+			for _, row := range rows {
+				if row.ID != keep.ID {
+					duplicateIDs = append(duplicateIDs, row.ID)
+				}
+			}
+
+			if err := tx.Unscoped().Model(&models.AccumulatedReward{}).
+				Where("id = ?", keep.ID).
+				Updates(map[string]interface{}{
+					"points_accumulated":   points,
+					"cashback_accumulated": cashback,
+				}).Error; err != nil {
+				return err
+			}
+			if err := tx.Unscoped().Delete(&models.AccumulatedReward{}, duplicateIDs).Error; err != nil {
+				return err
+			}
+			removed += len(duplicateIDs)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if removed > 0 {
+		m.logger.Info("Saldos duplicados fusionados: %d filas eliminadas", removed)
+	}
+	return removed, nil
+}
+
+// backfillLegacyCampaigns traslada a parámetros de campaña los valores que antes estaban fijos en el código
+func (m *Migrator) backfillLegacyCampaigns() error {
+	if err := m.db.Model(&models.Campaign{}).
+		Where("type = ?", "additional").
+		Update("min_amount", legacyAdditionalMinAmount).Error; err != nil {
+		return err
+	}
+	return m.db.Model(&models.Campaign{}).
+		Where("type = ? AND (percentage IS NULL OR percentage = 0)", "additional").
+		Update("percentage", 30).Error
+}
+
+// backfillTransactionNetAmounts copia el monto bruto como monto neto en las transacciones existentes
+func (m *Migrator) backfillTransactionNetAmounts() error {
+	return m.db.Model(&models.Transaction{}).
+		Where("net_amount IS NULL").
+		Update("net_amount", gorm.Expr("amount")).Error
+}
@@ -0,0 +1,136 @@
+package config
+
+import (
+	"net/http"
+	"strconv"
+
+	"leal-technical-test/internal/domain/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Permission es una acción protegida de la API
+type Permission string
+
+// Permisos de las rutas protegidas
+const (
+	PermProfileRead        Permission = "me:read"
+	PermStoresRead         Permission = "stores:read"
+	PermStoresWrite        Permission = "stores:write"
+	PermUsersRead          Permission = "users:read"
+	PermUsersWrite         Permission = "users:write"
+	PermBranchesRead       Permission = "branches:read"
+	PermBranchesWrite      Permission = "branches:write"
+	PermCampaignsRead      Permission = "campaigns:read"
+	PermCampaignsWrite     Permission = "campaigns:write"
+	PermBalancesRead       Permission = "balances:read"
+	PermBalancesReadAll    Permission = "balances:read_all"
+	PermBalancesAdjust     Permission = "balances:adjust"
+	PermCashbackRedeem     Permission = "cashback:redeem"
+	PermRewardsRead        Permission = "rewards:read"
+	PermRewardsWrite       Permission = "rewards:write"
+	PermRewardsClaim       Permission = "rewards:claim"
+	PermRedemptionsRead    Permission = "redemptions:read"
+	PermRedemptionsWrite   Permission = "redemptions:write"
+	PermTransactionsRead   Permission = "transactions:read"
+	PermTransactionsAll    Permission = "transactions:read_all"
+	PermTransactionsWrite  Permission = "transactions:write"
+	PermTransactionsRefund Permission = "transactions:refund"
+	PermTransactionsQuote  Permission = "transactions:preview"
+	PermTiersRead          Permission = "tiers:read"
+	PermTiersWrite         Permission = "tiers:write"
+	PermTransfersRead      Permission = "transfers:read"
+	PermTransfersWrite     Permission = "transfers:write"
+	PermExchangesRead      Permission = "exchanges:read"
+	PermExchangesWrite     Permission = "exchanges:write"
+	PermExchangeRatesWrite Permission = "exchange_rates:write"
+	PermSettlementsRead    Permission = "settlements:read"
+)
+
+// rolePermissions es la matriz de permisos de cada rol. El administrador de la plataforma
+// tiene todos los permisos y no necesita aparecer aquí.
+var rolePermissions = map[string][]Permission{
+	models.RoleStoreManager: {
+		PermProfileRead,
+		PermStoresRead, PermUsersRead,
+		PermBranchesRead, PermBranchesWrite,
+		PermCampaignsRead, PermCampaignsWrite,
+		PermBalancesRead, PermBalancesReadAll, PermBalancesAdjust, PermCashbackRedeem,
+		PermRewardsRead, PermRewardsWrite, PermRewardsClaim,
+		PermRedemptionsRead, PermRedemptionsWrite,
+		PermTransactionsRead, PermTransactionsAll, PermTransactionsWrite, PermTransactionsRefund, PermTransactionsQuote,
+		PermTiersRead, PermTiersWrite,
+		PermTransfersRead,
+		PermExchangesRead, PermExchangeRatesWrite, PermSettlementsRead,
+	},
+	models.RoleCashier: {
+		PermProfileRead,
+		PermStoresRead, PermBranchesRead, PermCampaignsRead,
+		PermBalancesRead, PermCashbackRedeem,
+		PermRewardsRead, PermRewardsClaim,
+		PermRedemptionsRead, PermRedemptionsWrite,
+		PermTransactionsRead, PermTransactionsWrite, PermTransactionsQuote,
+		PermTiersRead,
+	},
+	models.RoleCustomer: {
+		PermProfileRead,
+		PermStoresRead, PermBranchesRead, PermCampaignsRead,
+		PermBalancesRead,
+		PermRewardsRead, PermRewardsClaim,
+		PermTransactionsQuote,
+		PermTiersRead,
+		PermTransfersRead, PermTransfersWrite,
+		PermExchangesRead, PermExchangesWrite,
+	},
+}
+
+// HasPermission indica si un rol tiene un permiso
+func HasPermission(role string, permission Permission) bool {
+	if role == models.RolePlatformAdmin {
+		return true
+	}
+	for _, granted := range rolePermissions[role] {
+		if granted == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// RequirePermission es el middleware que rechaza con 403 las peticiones cuyo rol no tiene el permiso.
+// Debe ir después de AuthMiddleware, que deja el rol del token en el contexto.
+func RequirePermission(permission Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !HasPermission(c.GetString("role"), permission) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions", "permission": permission})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// OwnsUser indica si el usuario autenticado puede actuar sobre los datos del usuario indicado. Los
+// clientes solo alcanzan su propia cuenta; el personal y el administrador, cualquiera.
+func OwnsUser(c *gin.Context, userID uint) bool {
+	if c.GetString("role") != models.RoleCustomer {
+		return true
+	}
+	tokenUserID, ok := UserIDFromContext(c)
+	return ok && tokenUserID == userID
+}
+
+// RequireOwnUser es el middleware que rechaza con 403 a los clientes que piden datos de otro usuario
+// en una ruta con el ID del usuario en el parámetro indicado. El personal y el administrador pasan.
+// Debe ir después de AuthMiddleware, que deja el ID del usuario en el contexto.
+func RequireOwnUser(param string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := strconv.ParseUint(c.Param(param), 10, 64)
+		if c.GetString("role") == models.RoleCustomer && (err != nil || !OwnsUser(c, uint(userID))) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Customers can only access their own data"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"leal-technical-test/internal/domain/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Scope son las tiendas sobre las que un usuario puede leer y escribir. El personal de una tienda
+// (gerentes y cajeros) solo alcanza las tiendas a las que está vinculado; el administrador de la
+// plataforma y los clientes no tienen restricción de tienda.
+type Scope struct {
+	restricted bool
+	storeIDs   map[uint]bool
+}
+
+// Unrestricted devuelve un alcance que incluye todas las tiendas
+func Unrestricted() Scope {
+	return Scope{}
+}
+
+// StoreScope devuelve un alcance limitado a las tiendas indicadas
+func StoreScope(storeIDs []uint) Scope {
+	scope := Scope{restricted: true, storeIDs: make(map[uint]bool, len(storeIDs))}
+	for _, id := range storeIDs {
+		scope.storeIDs[id] = true
+	}
+	return scope
+}
+
+// ScopeFor devuelve el alcance de un rol con las tiendas vinculadas al usuario
+func ScopeFor(role string, storeIDs []uint) Scope {
+	switch role {
+	case models.RoleStoreManager, models.RoleCashier:
+		return StoreScope(storeIDs)
+	}
+	return Unrestricted()
+}
+
+// ScopeFromContext devuelve el alcance del usuario autenticado por AuthMiddleware
+func ScopeFromContext(c *gin.Context) Scope {
+	storeIDs, _ := c.Get("store_ids")
+	ids, _ := storeIDs.([]uint)
+	return ScopeFor(c.GetString("role"), ids)
+}
+
+// Allows indica si la tienda está dentro del alcance
+func (s Scope) Allows(storeID uint) bool {
+	return !s.restricted || s.storeIDs[storeID]
+}
+
+// StoreIDs devuelve las tiendas de un alcance restringido
+func (s Scope) StoreIDs() []uint {
+	ids := make([]uint, 0, len(s.storeIDs))
+	for id := range s.storeIDs {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// Restricted indica si el alcance está limitado a algunas tiendas
+func (s Scope) Restricted() bool {
+	return s.restricted
+}
@@ -0,0 +1,51 @@
+package config
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// IUnitOfWork agrupa varias escrituras en una única transacción de base de datos
+type IUnitOfWork interface {
+	Do(fn func(tx IDatabaseConnection) error) error
+}
+
+// unitOfWork struct
+type unitOfWork struct {
+	db IDatabaseConnection
+}
+
+// NewUnitOfWork es el constructor que recibe la conexión sobre la que se abrirán las transacciones
+func NewUnitOfWork(db IDatabaseConnection) IUnitOfWork {
+	return &unitOfWork{db: db}
+}
+
+// Do ejecuta fn dentro de una transacción. Si fn devuelve un error se hace rollback,
+// en caso contrario se hace commit. Los repositorios se unen a la transacción a través de WithTx(tx).
+func (u *unitOfWork) Do(fn func(tx IDatabaseConnection) error) error {
+	return u.db.GetDB().Transaction(func(tx *gorm.DB) error {
+		return fn(&txConnection{tx: tx})
+	})
+}
+
+// txConnection expone una transacción en curso como IDatabaseConnection
+type txConnection struct {
+	tx *gorm.DB
+}
+
+func (t *txConnection) GetDB() *gorm.DB {
+	return t.tx
+}
+
+func (t *txConnection) Connect() error {
+	return fmt.Errorf("cannot connect from inside a transaction")
+}
+
+func (t *txConnection) Close() error {
+	return fmt.Errorf("cannot close the database from inside a transaction")
+}
+
+func (t *txConnection) Ping() error {
+	return t.tx.Exec("SELECT 1").Error
+}
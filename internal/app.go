@@ -2,6 +2,7 @@ package internal
 
 import (
 	"leal-technical-test/config"
+	"leal-technical-test/internal/jobs"
 	"leal-technical-test/router"
 
 	"github.com/gin-gonic/gin"
@@ -37,6 +38,16 @@ func (s *Server) Run() error {
 	migratos.Migrate()
 	defer db.Close()
 
+	// Jobs en segundo plano que comparten el proceso con la API
+	scheduler := jobs.NewScheduler()
+	scheduler.Register(jobs.NewPointsExpiryJob())
+	scheduler.Register(jobs.NewIdempotencyKeyPurgeJob())
+	scheduler.Register(jobs.NewTierRecalculationJob())
+	scheduler.Register(jobs.NewVoucherExpiryJob())
+	scheduler.Register(jobs.NewTokenPurgeJob())
+	scheduler.Start()
+	defer scheduler.Stop()
+
 	appRouter := router.NewRouter(s.ginServer)
 	appRouter.InitializeRoutes()
 
@@ -2,10 +2,11 @@ package models
 
 import "gorm.io/gorm"
 
+// AccumulatedReward es el saldo de un usuario en una tienda. Solo puede existir una fila por (user_id, store_id).
 type AccumulatedReward struct {
 	gorm.Model
-	UserID              uint    `json:"user_id" gorm:"not null"`
-	StoreID             uint    `json:"store_id" gorm:"not null"`
+	UserID              uint    `json:"user_id" gorm:"not null;uniqueIndex:idx_accumulated_rewards_user_store"`
+	StoreID             uint    `json:"store_id" gorm:"not null;uniqueIndex:idx_accumulated_rewards_user_store"`
 	PointsAccumulated   float64 `json:"points_accumulated" gorm:"type:decimal(10,2);default:0"`
 	CashbackAccumulated float64 `json:"cashback_accumulated" gorm:"type:decimal(10,2);default:0"`
 	User                User    `json:"user" gorm:"foreignKey:UserID"`
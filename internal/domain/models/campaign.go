@@ -6,13 +6,37 @@ import (
 	"gorm.io/gorm"
 )
 
+// Campaign es una promoción de una sucursal. Type selecciona la regla de acumulación registrada
+// en el paquete rules y el resto de campos son los parámetros que esa regla lee.
 type Campaign struct {
 	gorm.Model
-	Name       string    `json:"name" gorm:"type:varchar(100);unique"`
-	BranchID   uint      `json:"branch_id" gorm:"not null"`
-	Type       string    `json:"type" gorm:"type:varchar(20);not null;check:type IN ('double', 'additional')"`
-	Percentage float64   `json:"percentage" gorm:"type:decimal(5,2)"`
-	StartDate  time.Time `json:"start_date" gorm:"type:date;not null"`
-	EndDate    time.Time `json:"end_date" gorm:"type:date;not null"`
-	Branch     Branch    `json:"branch" gorm:"foreignKey:BranchID"` // Relation to Branch
+	Name        string  `json:"name" gorm:"type:varchar(100);unique"`
+	BranchID    uint    `json:"branch_id" gorm:"not null"`
+	Type        string  `json:"type" gorm:"type:varchar(30);not null"`
+	Percentage  float64 `json:"percentage" gorm:"type:decimal(5,2)"`
+	Multiplier  float64 `json:"multiplier" gorm:"type:decimal(5,2);default:0"`
+	BonusPoints float64 `json:"bonus_points" gorm:"type:decimal(10,2);default:0"`
+	MinAmount   float64 `json:"min_amount" gorm:"type:decimal(12,2);default:0"` // Minimum purchase amount for the campaign to apply
+	Priority    int     `json:"priority" gorm:"default:0"`                      // Higher priority campaigns are applied first
+	Exclusive   bool    `json:"exclusive" gorm:"default:false"`                 // Under the priority policy, an exclusive campaign doesn't stack
+	// Límites de emisión de puntos extra; 0 significa sin límite
+	BudgetPoints      float64   `json:"budget_points" gorm:"type:decimal(12,2);default:0"`       // Total bonus points the campaign can issue
+	BudgetUsed        float64   `json:"budget_used" gorm:"type:decimal(12,2);default:0"`         // Bonus points already issued against the budget
+	PerCustomerCap    float64   `json:"per_customer_cap" gorm:"type:decimal(10,2);default:0"`    // Bonus points a customer can receive in total
+	PerTransactionCap float64   `json:"per_transaction_cap" gorm:"type:decimal(10,2);default:0"` // Bonus points a single purchase can receive
+	StartDate         time.Time `json:"start_date" gorm:"type:date;not null"`
+	EndDate           time.Time `json:"end_date" gorm:"type:date;not null"`
+	Branch            Branch    `json:"branch" gorm:"foreignKey:BranchID"` // Relation to Branch
+}
+
+// RemainingBudget devuelve los puntos extra que la campaña todavía puede otorgar, o nil si no tiene presupuesto
+func (c Campaign) RemainingBudget() *float64 {
+	if c.BudgetPoints <= 0 {
+		return nil
+	}
+	remaining := c.BudgetPoints - c.BudgetUsed
+	if remaining < 0 {
+		remaining = 0
+	}
+	return &remaining
 }
@@ -0,0 +1,44 @@
+package models
+
+import "gorm.io/gorm"
+
+// ExchangeRate es la tasa publicada para convertir puntos de una tienda en puntos de otra.
+// Cada par (origen, destino) tiene una sola tasa no eliminada; la conversión inversa es otra tasa.
+type ExchangeRate struct {
+	gorm.Model
+	FromStoreID          uint    `json:"from_store_id" gorm:"not null;uniqueIndex:idx_exchange_rates_pair_undeleted,where:deleted_at IS NULL"`
+	ToStoreID            uint    `json:"to_store_id" gorm:"not null;uniqueIndex:idx_exchange_rates_pair_undeleted,where:deleted_at IS NULL"`
+	Rate                 float64 `json:"rate" gorm:"type:decimal(10,4);not null"`                     // Points of ToStore given per point of FromStore
+	MaxPointsPerExchange float64 `json:"max_points_per_exchange" gorm:"type:decimal(10,2);default:0"` // Largest conversion, 0 means no limit
+	DailyCap             float64 `json:"daily_cap" gorm:"type:decimal(10,2);default:0"`               // Points of FromStore converted per day by every customer, 0 means no cap
+	Active               bool    `json:"active" gorm:"not null"`                                      // Inactive rates are kept but can't be used
+	FromStore            Store   `json:"from_store" gorm:"foreignKey:FromStoreID"`                    // Relation to the source Store
+	ToStore              Store   `json:"to_store" gorm:"foreignKey:ToStoreID"`                        // Relation to the destination Store
+}
+
+// PointsExchange es la conversión de puntos de un usuario entre dos tiendas. También es el registro de
+// liquidación: FromStore le debe a ToStore el valor de los puntos convertidos, porque ToStore asume su canje.
+type PointsExchange struct {
+	gorm.Model
+	UserID          uint    `json:"user_id" gorm:"not null;index"`
+	ExchangeRateID  uint    `json:"exchange_rate_id" gorm:"not null"`
+	FromStoreID     uint    `json:"from_store_id" gorm:"not null;index:idx_points_exchanges_pair"`
+	ToStoreID       uint    `json:"to_store_id" gorm:"not null;index:idx_points_exchanges_pair"`
+	Rate            float64 `json:"rate" gorm:"type:decimal(10,4);not null"`           // Rate applied, kept in case the published rate changes
+	PointsDebited   float64 `json:"points_debited" gorm:"type:decimal(10,2);not null"` // Points taken from the FromStore balance
+	PointsCredited  float64 `json:"points_credited" gorm:"type:decimal(10,2);not null"`
+	SettlementValue float64 `json:"settlement_value" gorm:"type:decimal(10,2);not null"` // Amount FromStore owes ToStore
+	User            User    `json:"user" gorm:"foreignKey:UserID"`
+	FromStore       Store   `json:"from_store" gorm:"foreignKey:FromStoreID"`
+	ToStore         Store   `json:"to_store" gorm:"foreignKey:ToStoreID"`
+}
+
+// StoreSettlement es el saldo neto entre una tienda y otra de la coalición. Un Balance positivo
+// significa que la otra tienda le debe a la tienda; uno negativo, que la tienda le debe a la otra.
+type StoreSettlement struct {
+	StoreID        uint    `json:"store_id"`
+	CounterpartyID uint    `json:"counterparty_id"`
+	Receivable     float64 `json:"receivable"` // Owed by the counterparty for points converted into the store
+	Payable        float64 `json:"payable"`    // Owed to the counterparty for points converted out of the store
+	Balance        float64 `json:"balance"`
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Ámbitos en los que se aceptan claves de idempotencia
+const (
+	IdempotencyScopeTransactions = "transactions"
+)
+
+// IdempotencyKey guarda la clave enviada por un cliente junto con el resultado de la petición original,
+// para que un reintento devuelva la misma respuesta en lugar de repetir la operación
+type IdempotencyKey struct {
+	gorm.Model
+	Scope         string    `json:"scope" gorm:"type:varchar(50);not null;uniqueIndex:idx_idempotency_keys_scope_key"`
+	Key           string    `json:"key" gorm:"type:varchar(100);not null;uniqueIndex:idx_idempotency_keys_scope_key"`
+	RequestHash   string    `json:"request_hash" gorm:"type:varchar(64);not null"` // Fingerprint of the original request
+	TransactionID *uint     `json:"transaction_id"`
+	ExpiresAt     time.Time `json:"expires_at" gorm:"not null;index"`
+}
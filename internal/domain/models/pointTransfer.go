@@ -0,0 +1,18 @@
+package models
+
+import "gorm.io/gorm"
+
+// PointTransfer es el traspaso de puntos entre dos usuarios de una misma tienda. El emisor paga
+// los puntos traspasados más la comisión; el receptor recibe los puntos sin la comisión.
+type PointTransfer struct {
+	gorm.Model
+	FromUserID uint    `json:"from_user_id" gorm:"not null;index:idx_point_transfers_from_store"`
+	ToUserID   uint    `json:"to_user_id" gorm:"not null;index"`
+	StoreID    uint    `json:"store_id" gorm:"not null;index:idx_point_transfers_from_store"`
+	Points     float64 `json:"points" gorm:"type:decimal(10,2);not null"` // Points received by ToUser
+	Fee        float64 `json:"fee" gorm:"type:decimal(10,2);default:0"`   // Points paid by FromUser on top of Points
+	Note       string  `json:"note" gorm:"type:varchar(200)"`
+	FromUser   User    `json:"from_user" gorm:"foreignKey:FromUserID"` // Relation to the sender
+	ToUser     User    `json:"to_user" gorm:"foreignKey:ToUserID"`     // Relation to the receiver
+	Store      Store   `json:"store" gorm:"foreignKey:StoreID"`        // Relation to Store
+}
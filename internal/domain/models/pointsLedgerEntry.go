@@ -0,0 +1,39 @@
+package models
+
+import "gorm.io/gorm"
+
+// Tipos de movimiento del libro de puntos
+const (
+	LedgerEntryEarn           = "earn"
+	LedgerEntryRedeem         = "redeem"
+	LedgerEntryRedeemReversal = "redeem_reversal"
+	LedgerEntryAdjust         = "adjust"
+	LedgerEntryExpire         = "expire"
+	LedgerEntryCashbackRedeem = "cashback_redeem"
+	LedgerEntryCashbackPay    = "cashback_payment"
+	LedgerEntryCashbackReturn = "cashback_payment_refund"
+	LedgerEntryRefund         = "refund"
+	LedgerEntryTransferOut    = "transfer_out"
+	LedgerEntryTransferIn     = "transfer_in"
+	LedgerEntryTransferFee    = "transfer_fee"
+	LedgerEntryExchangeOut    = "exchange_out"
+	LedgerEntryExchangeIn     = "exchange_in"
+)
+
+// PointsLedgerEntry es un movimiento inmutable sobre el saldo de puntos y cashback de un usuario en una tienda.
+// El saldo de AccumulatedReward es la caché de la suma de estos movimientos.
+type PointsLedgerEntry struct {
+	gorm.Model
+	UserID        uint    `json:"user_id" gorm:"not null;index:idx_points_ledger_user_store"`
+	StoreID       uint    `json:"store_id" gorm:"not null;index:idx_points_ledger_user_store"`
+	EntryType     string  `json:"entry_type" gorm:"type:varchar(30);not null"`
+	Points        float64 `json:"points" gorm:"type:decimal(10,2);not null"`
+	Cashback      float64 `json:"cashback" gorm:"type:decimal(10,2);default:0"`
+	TransactionID *uint   `json:"transaction_id"`
+	RedemptionID  *uint   `json:"redemption_id"`
+	TransferID    *uint   `json:"transfer_id"`
+	ExchangeID    *uint   `json:"exchange_id"`
+	Description   string  `json:"description" gorm:"type:varchar(200)"`
+	User          User    `json:"user" gorm:"foreignKey:UserID"`   // Relation to User
+	Store         Store   `json:"store" gorm:"foreignKey:StoreID"` // Relation to Store
+}
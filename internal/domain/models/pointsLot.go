@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PointsLot es un grupo de puntos ganados en un mismo momento. Los débitos consumen primero
+// los lotes más antiguos y los lotes vencidos se expiran con su saldo restante.
+type PointsLot struct {
+	gorm.Model
+	UserID        uint       `json:"user_id" gorm:"not null;index:idx_points_lots_user_store"`
+	StoreID       uint       `json:"store_id" gorm:"not null;index:idx_points_lots_user_store"`
+	TransactionID *uint      `json:"transaction_id"`
+	Points        float64    `json:"points" gorm:"type:decimal(10,2);not null"`    // Points originally credited
+	Remaining     float64    `json:"remaining" gorm:"type:decimal(10,2);not null"` // Points not yet consumed or expired
+	EarnedAt      time.Time  `json:"earned_at" gorm:"not null"`
+	ExpiresAt     *time.Time `json:"expires_at" gorm:"index"` // Nil when the points never expire
+}
+
+// PointsLotConsumption registra cuántos puntos de un lote consumió un canje, para poder devolverlos al cancelarlo
+type PointsLotConsumption struct {
+	gorm.Model
+	LotID        uint    `json:"lot_id" gorm:"not null;index"`
+	RedemptionID *uint   `json:"redemption_id" gorm:"index"`
+	Points       float64 `json:"points" gorm:"type:decimal(10,2);not null"`
+}
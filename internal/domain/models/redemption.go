@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Estados de un canje
+const (
+	RedemptionStatusReserved  = "reserved"
+	RedemptionStatusFulfilled = "fulfilled"
+	RedemptionStatusCancelled = "cancelled"
+	RedemptionStatusExpired   = "expired"
+)
+
+// Redemption registra el canje de una recompensa. Los puntos se descuentan al reservar
+// y se devuelven si el canje se cancela o si el voucher vence sin usarse.
+type Redemption struct {
+	gorm.Model
+	UserID         uint       `json:"user_id" gorm:"not null;index"`
+	StoreID        uint       `json:"store_id" gorm:"not null;index"`
+	RewardID       uint       `json:"reward_id" gorm:"not null;index"`
+	BranchID       *uint      `json:"branch_id"` // Branch where it was claimed, when the claim names one
+	PointsSpent    float64    `json:"points_spent" gorm:"type:decimal(10,2);not null"`
+	Status         string     `json:"status" gorm:"type:varchar(20);not null;default:'reserved'"`
+	VoucherCode    *string    `json:"voucher_code" gorm:"type:varchar(20);uniqueIndex"` // Code the cashier validates at the branch
+	ExpiresAt      *time.Time `json:"expires_at" gorm:"index"`                          // The voucher can't be used after this date
+	UsedAtBranchID *uint      `json:"used_at_branch_id"`                                // Branch where the voucher was used
+	FulfilledAt    *time.Time `json:"fulfilled_at"`
+	CancelledAt    *time.Time `json:"cancelled_at"`
+	User           User       `json:"user" gorm:"foreignKey:UserID"`     // Relation to User
+	Store          Store      `json:"store" gorm:"foreignKey:StoreID"`   // Relation to Store
+	Reward         Reward     `json:"reward" gorm:"foreignKey:RewardID"` // Relation to Reward
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RefreshToken es un token de refresco emitido al iniciar sesión. Solo se guarda su hash. Cada uso
+// lo rota por uno nuevo de la misma familia (la sesión); presentar uno ya usado indica que fue robado
+// y revoca la familia completa.
+type RefreshToken struct {
+	gorm.Model
+	UserID        uint       `json:"user_id" gorm:"not null;index"`
+	FamilyID      string     `json:"family_id" gorm:"type:varchar(64);not null;index"`
+	TokenHash     string     `json:"-" gorm:"type:varchar(64);not null;uniqueIndex"`   // SHA-256 of the token sent to the client
+	AccessTokenID string     `json:"access_token_id" gorm:"type:varchar(64);not null"` // jti of the access token issued with it
+	ExpiresAt     time.Time  `json:"expires_at" gorm:"not null;index"`
+	UsedAt        *time.Time `json:"used_at"`    // Set when it is exchanged for a new pair
+	RevokedAt     *time.Time `json:"revoked_at"` // Set on logout, reuse or password change
+}
+
+// RevokedToken es el ID (jti) de un token de acceso revocado antes de vencer. AuthMiddleware
+// rechaza los tokens de esta lista; al vencer el token la fila ya no hace falta y se elimina.
+type RevokedToken struct {
+	gorm.Model
+	TokenID   string    `json:"token_id" gorm:"type:varchar(64);not null;uniqueIndex"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"not null;index"`
+}
+
+// TokenPair es el token de acceso y el token de refresco que se entregan al cliente. No se persiste.
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresAt    time.Time // Expiration of the access token
+}
@@ -0,0 +1,18 @@
+package models
+
+import "gorm.io/gorm"
+
+// Refund es la devolución total o parcial de una compra. Retira del saldo los puntos y el cashback
+// proporcionales al monto devuelto.
+type Refund struct {
+	gorm.Model
+	TransactionID    uint        `json:"transaction_id" gorm:"not null;index"`
+	Amount           float64     `json:"amount" gorm:"type:decimal(10,2);not null"`
+	PointsReversed   float64     `json:"points_reversed" gorm:"type:decimal(10,2);not null"`    // Points debited from the balance
+	CashbackReversed float64     `json:"cashback_reversed" gorm:"type:decimal(10,2);not null"`  // Cashback debited from the balance
+	PointsWaived     float64     `json:"points_waived" gorm:"type:decimal(10,2);default:0"`     // Points not debited because the balance was clamped at zero
+	CashbackWaived   float64     `json:"cashback_waived" gorm:"type:decimal(10,2);default:0"`   // Cashback not debited because the balance was clamped at zero
+	CashbackReturned float64     `json:"cashback_returned" gorm:"type:decimal(10,2);default:0"` // Cashback used to pay the purchase given back to the balance
+	Reason           string      `json:"reason" gorm:"type:varchar(200)"`
+	Transaction      Transaction `json:"transaction" gorm:"foreignKey:TransactionID"` // Relation to the original Transaction
+}
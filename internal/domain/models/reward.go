@@ -1,11 +1,46 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type Reward struct {
 	gorm.Model
-	StoreID        uint    `json:"store_id" gorm:"not null"`
-	Description    string  `json:"description" gorm:"type:varchar(100)"`
-	PointsRequired float64 `json:"points_required" gorm:"type:decimal(10,2)"`
-	Store          Store   `json:"store" gorm:"foreignKey:StoreID"` // Relation to Store
+	StoreID        uint       `json:"store_id" gorm:"not null"`
+	Description    string     `json:"description" gorm:"type:varchar(100)"`
+	PointsRequired float64    `json:"points_required" gorm:"type:decimal(10,2)"`
+	Stock          *int       `json:"stock"`                           // Units left to claim, nil means unlimited
+	PerUserLimit   int        `json:"per_user_limit" gorm:"default:0"` // Claims allowed per user, 0 means no limit
+	PeriodLimit    int        `json:"period_limit" gorm:"default:0"`   // Claims allowed per user every PeriodDays, 0 means no limit
+	PeriodDays     int        `json:"period_days" gorm:"default:0"`
+	StartsAt       *time.Time `json:"starts_at"`                                  // Can't be claimed before, nil means already available
+	EndsAt         *time.Time `json:"ends_at"`                                    // Can't be claimed after, nil means no end
+	Store          Store      `json:"store" gorm:"foreignKey:StoreID"`            // Relation to Store
+	Branches       []Branch   `json:"branches" gorm:"many2many:reward_branches;"` // Branches where it can be claimed, empty means every branch
+}
+
+// AvailableAt indica si la recompensa está dentro de su ventana de disponibilidad
+func (r *Reward) AvailableAt(now time.Time) bool {
+	if r.StartsAt != nil && now.Before(*r.StartsAt) {
+		return false
+	}
+	if r.EndsAt != nil && !now.Before(*r.EndsAt) {
+		return false
+	}
+	return true
+}
+
+// EligibleBranch indica si la recompensa puede canjearse en la sucursal
+func (r *Reward) EligibleBranch(branchID uint) bool {
+	if len(r.Branches) == 0 {
+		return true
+	}
+	for _, branch := range r.Branches {
+		if branch.ID == branchID {
+			return true
+		}
+	}
+	return false
 }
@@ -1,11 +1,52 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Políticas para combinar varias campañas activas en una misma compra
+const (
+	StackingBestOf         = "best_of"
+	StackingAdditive       = "additive"
+	StackingMultiplicative = "multiplicative"
+	StackingPriority       = "priority"
+)
+
+// Políticas para una devolución que deja el saldo por debajo de cero
+const (
+	NegativeBalanceAllowDebt = "allow_debt"
+	NegativeBalanceClamp     = "clamp"
+	NegativeBalanceReject    = "reject"
+)
 
 type Store struct {
 	gorm.Model
-	Name             string   `json:"name" gorm:"type:varchar(100);not null"`
-	ConversionFactor float64  `json:"conversion_factor" gorm:"type:decimal(10,2);default:1.0"`
-	Branches         []Branch `json:"branches" gorm:"foreignKey:StoreID"` // Relation to branches
-	Rewards          []Reward `json:"rewards" gorm:"foreignKey:StoreID"`  // Relation to rewards
+	Name                   string   `json:"name" gorm:"type:varchar(100);not null"`
+	ConversionFactor       float64  `json:"conversion_factor" gorm:"type:decimal(10,2);default:1.0"`
+	CashbackPercentage     float64  `json:"cashback_percentage" gorm:"type:decimal(5,2);default:0"`           // Percentage of the amount returned as cashback
+	CashbackPaymentEarns   bool     `json:"cashback_payment_earns" gorm:"default:false"`                      // Whether the part of a purchase paid with cashback earns rewards
+	StackingPolicy         string   `json:"stacking_policy" gorm:"type:varchar(20);default:'best_of'"`        // How overlapping campaigns are combined
+	PointsExpirationMonths int      `json:"points_expiration_months" gorm:"default:0"`                        // Months until earned points expire, 0 means never
+	TierWindowDays         int      `json:"tier_window_days" gorm:"default:365"`                              // Rolling window used to qualify for tiers
+	TierGraceDays          int      `json:"tier_grace_days" gorm:"default:30"`                                // Days a user keeps a tier after no longer qualifying
+	NegativeBalancePolicy  string   `json:"negative_balance_policy" gorm:"type:varchar(20);default:'reject'"` // What a refund does when the balance can't cover it
+	TransferMinPoints      float64  `json:"transfer_min_points" gorm:"type:decimal(10,2);default:0"`          // Smallest transfer allowed
+	TransferMaxPoints      float64  `json:"transfer_max_points" gorm:"type:decimal(10,2);default:0"`          // Largest transfer allowed, 0 means no limit
+	TransferDailyCap       float64  `json:"transfer_daily_cap" gorm:"type:decimal(10,2);default:0"`           // Points a user can send per day, 0 means no cap
+	TransferFeePercentage  float64  `json:"transfer_fee_percentage" gorm:"type:decimal(5,2);default:0"`       // Percentage of the points charged to the sender
+	TransferMinAccountDays int      `json:"transfer_min_account_days" gorm:"default:7"`                       // Age an account needs before it can transfer
+	VoucherValidityDays    int      `json:"voucher_validity_days" gorm:"default:7"`                           // Days a redemption voucher can be used
+	Branches               []Branch `json:"branches" gorm:"foreignKey:StoreID"`                               // Relation to branches
+	Rewards                []Reward `json:"rewards" gorm:"foreignKey:StoreID"`                                // Relation to rewards
+}
+
+// PointsExpiresAt devuelve la fecha de vencimiento de los puntos ganados en earnedAt, o nil si no vencen
+func (s *Store) PointsExpiresAt(earnedAt time.Time) *time.Time {
+	if s.PointsExpirationMonths <= 0 {
+		return nil
+	}
+	expiresAt := earnedAt.AddDate(0, s.PointsExpirationMonths, 0)
+	return &expiresAt
 }
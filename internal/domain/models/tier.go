@@ -0,0 +1,73 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Medidas sobre las que se calcula el umbral de un nivel
+const (
+	TierThresholdSpend  = "spend"
+	TierThresholdPoints = "points"
+)
+
+// Motivos de un cambio de nivel
+const (
+	TierChangeUpgrade   = "upgrade"
+	TierChangeDowngrade = "downgrade"
+)
+
+// Tier es un nivel de membresía de una tienda. Un cliente alcanza el nivel cuando su gasto o sus puntos
+// en la ventana móvil de la tienda llegan al umbral, y mientras lo conserva gana puntos con su multiplicador.
+// El rango es único entre los niveles no eliminados de la tienda, así que un rango eliminado se puede reutilizar.
+type Tier struct {
+	gorm.Model
+	StoreID        uint    `json:"store_id" gorm:"not null;uniqueIndex:idx_tiers_store_rank_undeleted,where:deleted_at IS NULL"`
+	Name           string  `json:"name" gorm:"type:varchar(50);not null"`
+	Rank           int     `json:"rank" gorm:"not null;uniqueIndex:idx_tiers_store_rank_undeleted,where:deleted_at IS NULL"` // Higher ranks are better levels
+	ThresholdType  string  `json:"threshold_type" gorm:"type:varchar(10);not null"`                                          // spend or points
+	Threshold      float64 `json:"threshold" gorm:"type:decimal(12,2);not null"`
+	EarnMultiplier float64 `json:"earn_multiplier" gorm:"type:decimal(5,2);not null;default:1"`
+	Store          Store   `json:"store" gorm:"foreignKey:StoreID"` // Relation to Store
+}
+
+// UserTier es el nivel actual de un usuario en una tienda. TierID es nil mientras no alcanza ningún nivel.
+type UserTier struct {
+	gorm.Model
+	UserID      uint       `json:"user_id" gorm:"not null;uniqueIndex:idx_user_tiers_user_store"`
+	StoreID     uint       `json:"store_id" gorm:"not null;uniqueIndex:idx_user_tiers_user_store"`
+	TierID      *uint      `json:"tier_id"`
+	Since       time.Time  `json:"since"`
+	DowngradeAt *time.Time `json:"downgrade_at"` // End of the grace period when the user no longer qualifies
+	EvaluatedAt time.Time  `json:"evaluated_at"`
+	Tier        *Tier      `json:"tier" gorm:"foreignKey:TierID"` // Relation to Tier
+}
+
+// TierHistory registra cada cambio de nivel de un usuario en una tienda
+type TierHistory struct {
+	gorm.Model
+	UserID     uint    `json:"user_id" gorm:"not null;index:idx_tier_histories_user_store"`
+	StoreID    uint    `json:"store_id" gorm:"not null;index:idx_tier_histories_user_store"`
+	FromTierID *uint   `json:"from_tier_id"`
+	ToTierID   *uint   `json:"to_tier_id"`
+	Reason     string  `json:"reason" gorm:"type:varchar(20);not null"`
+	Spend      float64 `json:"spend" gorm:"type:decimal(12,2)"`  // Spend in the window when the change happened
+	Points     float64 `json:"points" gorm:"type:decimal(12,2)"` // Points in the window when the change happened
+	FromTier   *Tier   `json:"from_tier" gorm:"foreignKey:FromTierID"`
+	ToTier     *Tier   `json:"to_tier" gorm:"foreignKey:ToTierID"`
+}
+
+// TierProgress resume el nivel de un usuario y lo que le falta para el siguiente. No se guarda en la base de datos.
+type TierProgress struct {
+	UserID      uint
+	StoreID     uint
+	WindowDays  int
+	Spend       float64
+	Points      float64
+	Current     *Tier
+	Next        *Tier
+	Remaining   float64 // Spend or points still needed for the next tier
+	DowngradeAt *time.Time
+	History     []TierHistory
+}
@@ -6,15 +6,41 @@ import (
 	"gorm.io/gorm"
 )
 
+// Tipos de recompensa de una transacción
+const (
+	RewardTypePoints   = "points"
+	RewardTypeCashback = "cashback"
+)
+
 type Transaction struct {
 	gorm.Model
-	UserID         uint      `json:"user_id" gorm:"not null"`
-	BranchID       uint      `json:"branch_id" gorm:"not null"`
-	Amount         float64   `json:"amount" gorm:"type:decimal(10,2);not null"`
-	Date           time.Time `json:"date" gorm:"type:timestamp;default:current_timestamp"`
-	RewardType     string    `json:"reward_type" gorm:"type:varchar(20);not null;check:reward_type IN ('points', 'cashback')"`
-	PointsEarned   float64   `json:"points_earned" gorm:"type:decimal(10,2)"`
-	CashbackEarned float64   `json:"cashback_earned" gorm:"type:decimal(10,2)"`
-	User           User      `json:"user" gorm:"foreignKey:UserID"`     // Relation to User
-	Branch         Branch    `json:"branch" gorm:"foreignKey:BranchID"` // Relation to Branch
+	UserID            uint      `json:"user_id" gorm:"not null"`
+	BranchID          uint      `json:"branch_id" gorm:"not null;uniqueIndex:idx_transactions_branch_receipt"`
+	ExternalReceiptID *string   `json:"external_receipt_id" gorm:"type:varchar(100);uniqueIndex:idx_transactions_branch_receipt"` // Receipt number of the POS, unique per branch
+	Amount            float64   `json:"amount" gorm:"type:decimal(10,2);not null"`                                                // Gross amount of the purchase
+	CashbackApplied   float64   `json:"cashback_applied" gorm:"type:decimal(10,2);default:0"`                                     // Part of the amount paid with accumulated cashback
+	NetAmount         float64   `json:"net_amount" gorm:"type:decimal(10,2)"`                                                     // Part of the amount paid by other means
+	Date              time.Time `json:"date" gorm:"type:timestamp;default:current_timestamp"`
+	RewardType        string    `json:"reward_type" gorm:"type:varchar(20);not null;check:reward_type IN ('points', 'cashback')"`
+	PointsEarned      float64   `json:"points_earned" gorm:"type:decimal(10,2)"`
+	CashbackEarned    float64   `json:"cashback_earned" gorm:"type:decimal(10,2)"`
+	// Desglose de la acumulación, para poder explicar el resultado de cada compra
+	BasePoints         float64 `json:"base_points" gorm:"type:decimal(10,2)"`
+	ConversionFactor   float64 `json:"conversion_factor" gorm:"type:decimal(10,2)"`
+	CashbackPercentage float64 `json:"cashback_percentage" gorm:"type:decimal(5,2)"`
+	TierID             *uint   `json:"tier_id"`
+	TierMultiplier     float64 `json:"tier_multiplier" gorm:"type:decimal(5,2);default:1"`
+	TierBonusPoints    float64 `json:"tier_bonus_points" gorm:"type:decimal(10,2)"`   // Extra base points from the tier multiplier
+	BonusPoints        float64 `json:"bonus_points" gorm:"type:decimal(10,2)"`        // Sum of the bonuses of the applied campaigns
+	CapAdjustment      float64 `json:"cap_adjustment" gorm:"type:decimal(10,2)"`      // Points removed by caps (negative or zero)
+	RoundingAdjustment float64 `json:"rounding_adjustment" gorm:"type:decimal(10,4)"` // Difference between the earned and the calculated reward
+	// Devoluciones acumuladas sobre la compra
+	RefundedAmount   float64               `json:"refunded_amount" gorm:"type:decimal(10,2);default:0"`
+	PointsReversed   float64               `json:"points_reversed" gorm:"type:decimal(10,2);default:0"`   // Points clawed back by refunds, including waived ones
+	CashbackReversed float64               `json:"cashback_reversed" gorm:"type:decimal(10,2);default:0"` // Cashback clawed back by refunds, including waived ones
+	CashbackReturned float64               `json:"cashback_returned" gorm:"type:decimal(10,2);default:0"` // Applied cashback given back by refunds
+	Refunds          []Refund              `json:"refunds" gorm:"foreignKey:TransactionID"`               // Refunds of the purchase
+	AppliedCampaigns []TransactionCampaign `json:"applied_campaigns" gorm:"foreignKey:TransactionID"`     // Campaigns that contributed bonus points
+	User             User                  `json:"user" gorm:"foreignKey:UserID"`                         // Relation to User
+	Branch           Branch                `json:"branch" gorm:"foreignKey:BranchID"`                     // Relation to Branch
 }
@@ -0,0 +1,12 @@
+package models
+
+import "gorm.io/gorm"
+
+// TransactionCampaign registra una campaña aplicada a una transacción y los puntos extra que otorgó
+type TransactionCampaign struct {
+	gorm.Model
+	TransactionID uint     `json:"transaction_id" gorm:"not null;index"`
+	CampaignID    uint     `json:"campaign_id" gorm:"not null;index"`
+	BonusPoints   float64  `json:"bonus_points" gorm:"type:decimal(10,2);not null"`
+	Campaign      Campaign `json:"campaign" gorm:"foreignKey:CampaignID"` // Relation to Campaign
+}
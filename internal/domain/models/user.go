@@ -2,12 +2,41 @@ package models
 
 import "gorm.io/gorm"
 
+// Roles de un usuario
+const (
+	RolePlatformAdmin = "platform_admin"
+	RoleStoreManager  = "store_manager"
+	RoleCashier       = "cashier"
+	RoleCustomer      = "customer"
+)
+
+// ValidRole reports whether role is one of the known roles
+func ValidRole(role string) bool {
+	switch role {
+	case RolePlatformAdmin, RoleStoreManager, RoleCashier, RoleCustomer:
+		return true
+	}
+	return false
+}
+
 type User struct {
 	gorm.Model
 	Name         string              `json:"name" gorm:"type:varchar(100);not null"`
 	Email        string              `json:"email" gorm:"type:varchar(100);unique;not null"`
 	Phone        string              `json:"phone" gorm:"type:varchar(20)"`
 	Password     string              `json:"password" gorm:"type:varchar(255);not null"` // Password field
-	Rewards      []AccumulatedReward `json:"rewards" gorm:"foreignKey:UserID"`           // Relation to accumulated rewards
-	Transactions []Transaction       `json:"transactions" gorm:"foreignKey:UserID"`      // Relation to transactions
+	Role         string              `json:"role" gorm:"type:varchar(30);not null;default:customer"`
+	Suspended    bool                `json:"suspended" gorm:"default:false"`        // Suspended users can't transfer points
+	Stores       []Store             `json:"stores" gorm:"many2many:user_stores;"`  // Stores a manager or cashier works at
+	Rewards      []AccumulatedReward `json:"rewards" gorm:"foreignKey:UserID"`      // Relation to accumulated rewards
+	Transactions []Transaction       `json:"transactions" gorm:"foreignKey:UserID"` // Relation to transactions
+}
+
+// StoreIDs devuelve los IDs de las tiendas vinculadas al usuario
+func (u User) StoreIDs() []uint {
+	ids := make([]uint, len(u.Stores))
+	for i, store := range u.Stores {
+		ids[i] = store.ID
+	}
+	return ids
 }
@@ -0,0 +1,86 @@
+package rules
+
+import (
+	"fmt"
+	"leal-technical-test/internal/domain/models"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Context es la información de la compra que reciben las reglas de una campaña
+type Context struct {
+	Amount          float64
+	BasePoints      float64
+	Date            time.Time
+	IsFirstPurchase bool
+}
+
+// Rule es la estrategia de acumulación de un tipo de campaña. Los parámetros
+// (porcentaje, multiplicador, puntos fijos, monto mínimo) se leen del registro de la campaña.
+type Rule interface {
+	// Validate comprueba que la campaña tiene los parámetros que la regla necesita
+	Validate(campaign models.Campaign) error
+	// Bonus devuelve los puntos extra que la campaña otorga sobre los puntos base
+	Bonus(ctx Context, campaign models.Campaign) float64
+}
+
+var (
+	registry   = map[string]Rule{}
+	registryMu sync.RWMutex
+)
+
+// Register asocia una regla a un tipo de campaña
+func Register(campaignType string, rule Rule) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[campaignType] = rule
+}
+
+// Get devuelve la regla registrada para un tipo de campaña
+func Get(campaignType string) (Rule, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	rule, ok := registry[campaignType]
+	return rule, ok
+}
+
+// Types devuelve los tipos de campaña registrados
+func Types() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	types := make([]string, 0, len(registry))
+	for campaignType := range registry {
+		types = append(types, campaignType)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// Validate comprueba que el tipo de campaña existe y que sus parámetros son válidos para la regla
+func Validate(campaign models.Campaign) error {
+	rule, ok := Get(campaign.Type)
+	if !ok {
+		return fmt.Errorf("unknown campaign type %q, expected one of %v", campaign.Type, Types())
+	}
+	if campaign.MinAmount < 0 {
+		return fmt.Errorf("min_amount must not be negative")
+	}
+	if campaign.BudgetPoints < 0 || campaign.PerCustomerCap < 0 || campaign.PerTransactionCap < 0 {
+		return fmt.Errorf("budget_points, per_customer_cap and per_transaction_cap must not be negative")
+	}
+	return rule.Validate(campaign)
+}
+
+// Evaluate calcula los puntos extra de una campaña para una compra. El monto mínimo de la
+// campaña aplica a cualquier tipo: por debajo de él la campaña no otorga nada.
+func Evaluate(ctx Context, campaign models.Campaign) (float64, error) {
+	rule, ok := Get(campaign.Type)
+	if !ok {
+		return 0, fmt.Errorf("unknown campaign type %q", campaign.Type)
+	}
+	if ctx.Amount < campaign.MinAmount {
+		return 0, nil
+	}
+	return rule.Bonus(ctx, campaign), nil
+}
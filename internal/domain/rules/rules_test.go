@@ -0,0 +1,116 @@
+package rules
+
+import (
+	"leal-technical-test/internal/domain/models"
+	"math"
+	"testing"
+)
+
+// Prueba que cada tipo de campaña toma sus parámetros del registro de la campaña
+func TestEvaluateBuiltInRules(t *testing.T) {
+	ctx := Context{Amount: 30000, BasePoints: 300}
+
+	cases := []struct {
+		name     string
+		campaign models.Campaign
+		ctx      Context
+		expected float64
+	}{
+		{"double", models.Campaign{Type: TypeDouble}, ctx, 300},
+		{"multiplier", models.Campaign{Type: TypeMultiplier, Multiplier: 3}, ctx, 600},
+		{"additional honours percentage", models.Campaign{Type: TypeAdditional, Percentage: 10}, ctx, 30},
+		{"percentage", models.Campaign{Type: TypePercentage, Percentage: 50}, ctx, 150},
+		{"fixed bonus", models.Campaign{Type: TypeFixedBonus, BonusPoints: 25}, ctx, 25},
+		{"min spend reached", models.Campaign{Type: TypeMinSpend, MinAmount: 20000, BonusPoints: 40}, ctx, 40},
+		{"min spend not reached", models.Campaign{Type: TypeMinSpend, MinAmount: 50000, BonusPoints: 40}, ctx, 0},
+		{"first purchase", models.Campaign{Type: TypeFirstPurchase, BonusPoints: 100}, Context{Amount: 30000, BasePoints: 300, IsFirstPurchase: true}, 100},
+		{"not first purchase", models.Campaign{Type: TypeFirstPurchase, BonusPoints: 100}, ctx, 0},
+		{"threshold applies to any type", models.Campaign{Type: TypeDouble, MinAmount: 40000}, ctx, 0},
+	}
+
+	for _, c := range cases {
+		if err := Validate(c.campaign); err != nil {
+			t.Errorf("%s: unexpected validation error: %v", c.name, err)
+			continue
+		}
+		bonus, err := Evaluate(c.ctx, c.campaign)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", c.name, err)
+			continue
+		}
+		if bonus != c.expected {
+			t.Errorf("%s: expected bonus %f, got %f", c.name, c.expected, bonus)
+		}
+	}
+}
+
+// Prueba que se rechazan tipos desconocidos y parámetros inválidos
+func TestValidateRejectsInvalidCampaigns(t *testing.T) {
+	invalid := []models.Campaign{
+		{Type: "unknown"},
+		{Type: TypeMultiplier, Multiplier: 1},
+		{Type: TypePercentage},
+		{Type: TypeFixedBonus},
+		{Type: TypeMinSpend, Percentage: 10},
+	}
+	for _, campaign := range invalid {
+		if err := Validate(campaign); err == nil {
+			t.Errorf("Expected campaign %+v to be rejected", campaign)
+		}
+	}
+}
+
+// Prueba que se pueden registrar nuevas reglas sin modificar el motor
+func TestRegisterCustomRule(t *testing.T) {
+	Register("flat_ten", fixedBonusRule{})
+	defer func() {
+		registryMu.Lock()
+		delete(registry, "flat_ten")
+		registryMu.Unlock()
+	}()
+
+	bonus, err := Evaluate(Context{Amount: 100, BasePoints: 100}, models.Campaign{Type: "flat_ten", BonusPoints: 10})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if bonus != 10 {
+		t.Errorf("Expected bonus 10, got %f", bonus)
+	}
+}
+
+// Prueba que cada política combina de forma distinta las mismas campañas solapadas
+func TestStackPolicies(t *testing.T) {
+	ctx := Context{Amount: 30000, BasePoints: 100}
+	campaigns := []models.Campaign{
+		{Name: "double", Type: TypeDouble, Priority: 1},
+		{Name: "bonus", Type: TypeFixedBonus, BonusPoints: 50, Priority: 2},
+		{Name: "exclusive", Type: TypePercentage, Percentage: 10, Priority: 3, Exclusive: true},
+		{Name: "not reached", Type: TypeMinSpend, MinAmount: 50000, BonusPoints: 500},
+	}
+
+	cases := []struct {
+		policy   string
+		expected float64
+		applied  int
+	}{
+		{models.StackingBestOf, 200, 1},
+		{models.StackingAdditive, 260, 3},
+		{models.StackingMultiplicative, 330, 3},
+		{models.StackingPriority, 110, 1},
+	}
+
+	for _, c := range cases {
+		result, err := Stack(c.policy, ctx, campaigns)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", c.policy, err)
+			continue
+		}
+		if math.Abs(result.TotalPoints-c.expected) > 1e-9 || len(result.Applied) != c.applied {
+			t.Errorf("%s: expected %f points from %d campaigns, got %f from %d", c.policy, c.expected, c.applied, result.TotalPoints, len(result.Applied))
+		}
+	}
+
+	if _, err := Stack("unknown", ctx, campaigns); err == nil {
+		t.Error("Expected unknown policy to be rejected")
+	}
+}
@@ -0,0 +1,103 @@
+package rules
+
+import (
+	"fmt"
+	"leal-technical-test/internal/domain/models"
+	"sort"
+)
+
+// AppliedCampaign es una campaña que otorgó puntos extra en una compra
+type AppliedCampaign struct {
+	Campaign models.Campaign
+	Bonus    float64
+}
+
+// Result es el resultado de combinar las campañas activas según la política de la tienda
+type Result struct {
+	BasePoints  float64
+	Applied     []AppliedCampaign
+	TotalPoints float64
+}
+
+// ValidatePolicy comprueba que una política de acumulación es conocida
+func ValidatePolicy(policy string) error {
+	switch policy {
+	case models.StackingBestOf, models.StackingAdditive, models.StackingMultiplicative, models.StackingPriority:
+		return nil
+	}
+	return fmt.Errorf("unknown stacking policy %q", policy)
+}
+
+// Stack evalúa cada campaña y combina sus bonificaciones según la política de la tienda:
+//   - best_of: solo aplica la campaña que más puntos otorga
+//   - additive: suma las bonificaciones de todas las campañas
+//   - multiplicative: encadena el factor (base + bono) / base de cada campaña
+//   - priority: aplica por prioridad descendente; una campaña exclusiva solo aplica si es la
+//     primera que otorga puntos y, en ese caso, ninguna otra se suma
+//
+// Las campañas que no otorgan puntos (por ejemplo, por no alcanzar el monto mínimo) no cuentan como aplicadas.
+func Stack(policy string, ctx Context, campaigns []models.Campaign) (Result, error) {
+	if policy == "" {
+		policy = models.StackingBestOf
+	}
+	if err := ValidatePolicy(policy); err != nil {
+		return Result{}, err
+	}
+
+	// Ordenar por prioridad para que el resultado sea determinista con cualquier política
+	ordered := make([]models.Campaign, len(campaigns))
+	copy(ordered, campaigns)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return ordered[i].Priority > ordered[j].Priority
+	})
+
+	var candidates []AppliedCampaign
+	for _, campaign := range ordered {
+		bonus, err := Evaluate(ctx, campaign)
+		if err != nil {
+			return Result{}, err
+		}
+		if bonus > 0 {
+			candidates = append(candidates, AppliedCampaign{Campaign: campaign, Bonus: bonus})
+		}
+	}
+
+	var applied []AppliedCampaign
+	switch policy {
+	case models.StackingBestOf:
+		for _, candidate := range candidates {
+			if len(applied) == 0 || candidate.Bonus > applied[0].Bonus {
+				applied = []AppliedCampaign{candidate}
+			}
+		}
+	case models.StackingAdditive:
+		applied = candidates
+	case models.StackingMultiplicative:
+		if ctx.BasePoints > 0 {
+			total := ctx.BasePoints
+			for _, candidate := range candidates {
+				factor := (ctx.BasePoints + candidate.Bonus) / ctx.BasePoints
+				increment := total*factor - total
+				total += increment
+				applied = append(applied, AppliedCampaign{Campaign: candidate.Campaign, Bonus: increment})
+			}
+		}
+	case models.StackingPriority:
+		for _, candidate := range candidates {
+			if candidate.Campaign.Exclusive {
+				if len(applied) == 0 {
+					applied = append(applied, candidate)
+					break
+				}
+				continue
+			}
+			applied = append(applied, candidate)
+		}
+	}
+
+	result := Result{BasePoints: ctx.BasePoints, Applied: applied, TotalPoints: ctx.BasePoints}
+	for _, campaign := range applied {
+		result.TotalPoints += campaign.Bonus
+	}
+	return result, nil
+}
@@ -0,0 +1,112 @@
+package rules
+
+import (
+	"fmt"
+	"leal-technical-test/internal/domain/models"
+)
+
+// Tipos de campaña incluidos
+const (
+	TypeDouble        = "double"
+	TypeAdditional    = "additional"
+	TypeMultiplier    = "multiplier"
+	TypePercentage    = "percentage"
+	TypeFixedBonus    = "fixed_bonus"
+	TypeMinSpend      = "min_spend"
+	TypeFirstPurchase = "first_purchase"
+)
+
+func init() {
+	Register(TypeDouble, multiplierRule{defaultMultiplier: 2})
+	Register(TypeMultiplier, multiplierRule{})
+	Register(TypeAdditional, percentageRule{})
+	Register(TypePercentage, percentageRule{})
+	Register(TypeFixedBonus, fixedBonusRule{})
+	Register(TypeMinSpend, minSpendRule{})
+	Register(TypeFirstPurchase, firstPurchaseRule{})
+}
+
+// multiplierRule multiplica los puntos base por Campaign.Multiplier
+type multiplierRule struct {
+	defaultMultiplier float64
+}
+
+func (r multiplierRule) multiplier(campaign models.Campaign) float64 {
+	if campaign.Multiplier > 0 {
+		return campaign.Multiplier
+	}
+	return r.defaultMultiplier
+}
+
+func (r multiplierRule) Validate(campaign models.Campaign) error {
+	if r.multiplier(campaign) <= 1 {
+		return fmt.Errorf("multiplier must be greater than 1")
+	}
+	return nil
+}
+
+func (r multiplierRule) Bonus(ctx Context, campaign models.Campaign) float64 {
+	return ctx.BasePoints * (r.multiplier(campaign) - 1)
+}
+
+// percentageRule otorga Campaign.Percentage por ciento de los puntos base
+type percentageRule struct{}
+
+func (percentageRule) Validate(campaign models.Campaign) error {
+	if campaign.Percentage <= 0 {
+		return fmt.Errorf("percentage must be greater than 0")
+	}
+	return nil
+}
+
+func (percentageRule) Bonus(ctx Context, campaign models.Campaign) float64 {
+	return ctx.BasePoints * campaign.Percentage / 100
+}
+
+// fixedBonusRule otorga Campaign.BonusPoints por cada compra
+type fixedBonusRule struct{}
+
+func (fixedBonusRule) Validate(campaign models.Campaign) error {
+	if campaign.BonusPoints <= 0 {
+		return fmt.Errorf("bonus_points must be greater than 0")
+	}
+	return nil
+}
+
+func (fixedBonusRule) Bonus(ctx Context, campaign models.Campaign) float64 {
+	return campaign.BonusPoints
+}
+
+// minSpendRule otorga un porcentaje y/o puntos fijos cuando la compra alcanza Campaign.MinAmount
+type minSpendRule struct{}
+
+func (minSpendRule) Validate(campaign models.Campaign) error {
+	if campaign.MinAmount <= 0 {
+		return fmt.Errorf("min_amount must be greater than 0")
+	}
+	if campaign.Percentage <= 0 && campaign.BonusPoints <= 0 {
+		return fmt.Errorf("percentage or bonus_points must be greater than 0")
+	}
+	return nil
+}
+
+func (minSpendRule) Bonus(ctx Context, campaign models.Campaign) float64 {
+	return ctx.BasePoints*campaign.Percentage/100 + campaign.BonusPoints
+}
+
+// firstPurchaseRule otorga un porcentaje y/o puntos fijos en la primera compra del usuario en la tienda
+type firstPurchaseRule struct{}
+
+func (firstPurchaseRule) Validate(campaign models.Campaign) error {
+	if campaign.Percentage <= 0 && campaign.BonusPoints <= 0 {
+		return fmt.Errorf("percentage or bonus_points must be greater than 0")
+	}
+	return nil
+}
+
+func (firstPurchaseRule) Bonus(ctx Context, campaign models.Campaign) float64 {
+	if !ctx.IsFirstPurchase {
+		return 0
+	}
+	return ctx.BasePoints*campaign.Percentage/100 + campaign.BonusPoints
+}
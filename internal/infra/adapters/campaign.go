@@ -11,14 +11,24 @@ func ToCampaignDTO(campaign *models.Campaign) dtos.CampaignResponse {
 		return dtos.CampaignResponse{}
 	}
 	return dtos.CampaignResponse{
-		Id:         campaign.ID,
-		Name:       campaign.Name,
-		BranchID:   campaign.BranchID,
-		Type:       campaign.Type,
-		Percentage: campaign.Percentage,
-		StartDate:  campaign.StartDate,
-		EndDate:    campaign.EndDate,
-		Branch:     campaign.Branch.Name,
+		Id:                campaign.ID,
+		Name:              campaign.Name,
+		BranchID:          campaign.BranchID,
+		Type:              campaign.Type,
+		Percentage:        campaign.Percentage,
+		Multiplier:        campaign.Multiplier,
+		BonusPoints:       campaign.BonusPoints,
+		MinAmount:         campaign.MinAmount,
+		Priority:          campaign.Priority,
+		Exclusive:         campaign.Exclusive,
+		BudgetPoints:      campaign.BudgetPoints,
+		BudgetUsed:        campaign.BudgetUsed,
+		RemainingBudget:   campaign.RemainingBudget(),
+		PerCustomerCap:    campaign.PerCustomerCap,
+		PerTransactionCap: campaign.PerTransactionCap,
+		StartDate:         campaign.StartDate,
+		EndDate:           campaign.EndDate,
+		Branch:            campaign.Branch.Name,
 	}
 }
 
@@ -27,14 +37,24 @@ func ToCampaignDTOs(campaigns []models.Campaign) []dtos.CampaignResponse {
 	campaignsDTO := make([]dtos.CampaignResponse, len(campaigns))
 	for i, campaign := range campaigns {
 		campaignsDTO[i] = dtos.CampaignResponse{
-			Id:         campaign.ID,
-			Name:       campaign.Name,
-			BranchID:   campaign.BranchID,
-			Type:       campaign.Type,
-			Percentage: campaign.Percentage,
-			StartDate:  campaign.StartDate,
-			EndDate:    campaign.EndDate,
-			Branch:     campaign.Branch.Name,
+			Id:                campaign.ID,
+			Name:              campaign.Name,
+			BranchID:          campaign.BranchID,
+			Type:              campaign.Type,
+			Percentage:        campaign.Percentage,
+			Multiplier:        campaign.Multiplier,
+			BonusPoints:       campaign.BonusPoints,
+			MinAmount:         campaign.MinAmount,
+			Priority:          campaign.Priority,
+			Exclusive:         campaign.Exclusive,
+			BudgetPoints:      campaign.BudgetPoints,
+			BudgetUsed:        campaign.BudgetUsed,
+			RemainingBudget:   campaign.RemainingBudget(),
+			PerCustomerCap:    campaign.PerCustomerCap,
+			PerTransactionCap: campaign.PerTransactionCap,
+			StartDate:         campaign.StartDate,
+			EndDate:           campaign.EndDate,
+			Branch:            campaign.Branch.Name,
 		}
 	}
 	return campaignsDTO
@@ -43,11 +63,19 @@ func ToCampaignDTOs(campaigns []models.Campaign) []dtos.CampaignResponse {
 // Convierte un DTO en un modelo de dominio
 func ToCampaignModel(campaign dtos.CampaignRequest) models.Campaign {
 	return models.Campaign{
-		Name:       campaign.Name,
-		BranchID:   campaign.BranchID,
-		Type:       campaign.Type,
-		Percentage: campaign.Percentage,
-		StartDate:  campaign.StartDate,
-		EndDate:    campaign.EndDate,
+		Name:              campaign.Name,
+		BranchID:          campaign.BranchID,
+		Type:              campaign.Type,
+		Percentage:        campaign.Percentage,
+		Multiplier:        campaign.Multiplier,
+		BonusPoints:       campaign.BonusPoints,
+		MinAmount:         campaign.MinAmount,
+		Priority:          campaign.Priority,
+		Exclusive:         campaign.Exclusive,
+		BudgetPoints:      campaign.BudgetPoints,
+		PerCustomerCap:    campaign.PerCustomerCap,
+		PerTransactionCap: campaign.PerTransactionCap,
+		StartDate:         campaign.StartDate,
+		EndDate:           campaign.EndDate,
 	}
 }
@@ -0,0 +1,93 @@
+package adapters
+
+import (
+	"leal-technical-test/internal/domain/models"
+	"leal-technical-test/internal/infra/dtos"
+)
+
+// Convierte un modelo de dominio a un DTO
+func ToExchangeRateDTO(rate *models.ExchangeRate) dtos.ExchangeRateResponse {
+	if rate == nil {
+		return dtos.ExchangeRateResponse{}
+	}
+	return dtos.ExchangeRateResponse{
+		Id:                   rate.ID,
+		FromStoreID:          rate.FromStoreID,
+		FromStore:            rate.FromStore.Name,
+		ToStoreID:            rate.ToStoreID,
+		ToStore:              rate.ToStore.Name,
+		Rate:                 rate.Rate,
+		MaxPointsPerExchange: rate.MaxPointsPerExchange,
+		DailyCap:             rate.DailyCap,
+		Active:               rate.Active,
+	}
+}
+
+// Convierte una lista de modelos de dominio a una lista de DTOs
+func ToExchangeRateDTOs(rates []models.ExchangeRate) []dtos.ExchangeRateResponse {
+	ratesDTO := make([]dtos.ExchangeRateResponse, len(rates))
+	for i := range rates {
+		ratesDTO[i] = ToExchangeRateDTO(&rates[i])
+	}
+	return ratesDTO
+}
+
+// Convierte un DTO en un modelo de dominio. Una tasa es activa si no se indica lo contrario
+func ToExchangeRateModel(rate dtos.ExchangeRateRequest) *models.ExchangeRate {
+	active := true
+	if rate.Active != nil {
+		active = *rate.Active
+	}
+	return &models.ExchangeRate{
+		FromStoreID:          rate.FromStoreID,
+		ToStoreID:            rate.ToStoreID,
+		Rate:                 rate.Rate,
+		MaxPointsPerExchange: rate.MaxPointsPerExchange,
+		DailyCap:             rate.DailyCap,
+		Active:               active,
+	}
+}
+
+// Convierte un modelo de dominio a un DTO
+func ToPointsExchangeDTO(exchange *models.PointsExchange) dtos.PointsExchangeResponse {
+	if exchange == nil {
+		return dtos.PointsExchangeResponse{}
+	}
+	return dtos.PointsExchangeResponse{
+		Id:              exchange.ID,
+		UserID:          exchange.UserID,
+		FromStoreID:     exchange.FromStoreID,
+		FromStore:       exchange.FromStore.Name,
+		ToStoreID:       exchange.ToStoreID,
+		ToStore:         exchange.ToStore.Name,
+		Rate:            exchange.Rate,
+		PointsDebited:   exchange.PointsDebited,
+		PointsCredited:  exchange.PointsCredited,
+		SettlementValue: exchange.SettlementValue,
+		CreatedAt:       exchange.CreatedAt,
+	}
+}
+
+// Convierte una lista de modelos de dominio a una lista de DTOs
+func ToPointsExchangeDTOs(exchanges []models.PointsExchange) []dtos.PointsExchangeResponse {
+	exchangesDTO := make([]dtos.PointsExchangeResponse, len(exchanges))
+	for i := range exchanges {
+		exchangesDTO[i] = ToPointsExchangeDTO(&exchanges[i])
+	}
+	return exchangesDTO
+}
+
+// Convierte una lista de liquidaciones a una lista de DTOs
+func ToStoreSettlementDTOs(settlements []models.StoreSettlement) []dtos.StoreSettlementResponse {
+	settlementsDTO := make([]dtos.StoreSettlementResponse, len(settlements))
+	for i, settlement := range settlements {
+		settlementsDTO[i] = dtos.StoreSettlementResponse{
+			StoreID:        settlement.StoreID,
+			CounterpartyID: settlement.CounterpartyID,
+			Receivable:     settlement.Receivable,
+			Payable:        settlement.Payable,
+			Balance:        settlement.Balance,
+		}
+	}
+	return settlementsDTO
+}
@@ -0,0 +1,45 @@
+package adapters
+
+import (
+	"leal-technical-test/internal/domain/models"
+	"leal-technical-test/internal/infra/dtos"
+)
+
+// Convierte un modelo de dominio a un DTO
+func ToPointTransferDTO(transfer *models.PointTransfer) dtos.PointTransferResponse {
+	if transfer == nil {
+		return dtos.PointTransferResponse{}
+	}
+	return dtos.PointTransferResponse{
+		Id:         transfer.ID,
+		FromUserID: transfer.FromUserID,
+		FromUser:   transfer.FromUser.Name,
+		ToUserID:   transfer.ToUserID,
+		ToUser:     transfer.ToUser.Name,
+		StoreID:    transfer.StoreID,
+		Points:     transfer.Points,
+		Fee:        transfer.Fee,
+		Note:       transfer.Note,
+		CreatedAt:  transfer.CreatedAt,
+	}
+}
+
+// Convierte una lista de modelos de dominio a una lista de DTOs
+func ToPointTransferDTOs(transfers []models.PointTransfer) []dtos.PointTransferResponse {
+	transfersDTO := make([]dtos.PointTransferResponse, len(transfers))
+	for i := range transfers {
+		transfersDTO[i] = ToPointTransferDTO(&transfers[i])
+	}
+	return transfersDTO
+}
+
+// Convierte un DTO en un modelo de dominio
+func ToPointTransferModel(transfer dtos.PointTransferRequest) *models.PointTransfer {
+	return &models.PointTransfer{
+		FromUserID: transfer.FromUserID,
+		ToUserID:   transfer.ToUserID,
+		StoreID:    transfer.StoreID,
+		Points:     transfer.Points,
+		Note:       transfer.Note,
+	}
+}
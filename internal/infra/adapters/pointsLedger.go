@@ -0,0 +1,37 @@
+package adapters
+
+import (
+	"leal-technical-test/internal/domain/models"
+	"leal-technical-test/internal/infra/dtos"
+)
+
+// Convierte los movimientos del libro de puntos a un DTO con el saldo acumulado después de cada movimiento
+func ToLedgerDTO(userID uint, storeID uint, entries []models.PointsLedgerEntry) dtos.LedgerResponse {
+	entriesDTO := make([]dtos.LedgerEntryResponse, len(entries))
+	balance, cashback := 0.0, 0.0
+	for i, entry := range entries {
+		balance += entry.Points
+		cashback += entry.Cashback
+		entriesDTO[i] = dtos.LedgerEntryResponse{
+			Id:              entry.ID,
+			EntryType:       entry.EntryType,
+			Points:          entry.Points,
+			RunningBalance:  balance,
+			Cashback:        entry.Cashback,
+			RunningCashback: cashback,
+			TransactionID:   entry.TransactionID,
+			RedemptionID:    entry.RedemptionID,
+			TransferID:      entry.TransferID,
+			ExchangeID:      entry.ExchangeID,
+			Description:     entry.Description,
+			CreatedAt:       entry.CreatedAt,
+		}
+	}
+	return dtos.LedgerResponse{
+		UserID:          userID,
+		StoreID:         storeID,
+		Balance:         balance,
+		CashbackBalance: cashback,
+		Entries:         entriesDTO,
+	}
+}
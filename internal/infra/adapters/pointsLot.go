@@ -0,0 +1,29 @@
+package adapters
+
+import (
+	"leal-technical-test/internal/domain/models"
+	"leal-technical-test/internal/infra/dtos"
+)
+
+// Convierte los lotes que vencen en los próximos días a un DTO con el total de puntos a vencer
+func ToExpiringPointsDTO(userID uint, storeID uint, days int, lots []models.PointsLot) dtos.ExpiringPointsResponse {
+	lotsDTO := make([]dtos.PointsLotResponse, len(lots))
+	total := 0.0
+	for i, lot := range lots {
+		total += lot.Remaining
+		lotsDTO[i] = dtos.PointsLotResponse{
+			Id:        lot.ID,
+			Points:    lot.Points,
+			Remaining: lot.Remaining,
+			EarnedAt:  lot.EarnedAt,
+			ExpiresAt: lot.ExpiresAt,
+		}
+	}
+	return dtos.ExpiringPointsResponse{
+		UserID:      userID,
+		StoreID:     storeID,
+		Days:        days,
+		TotalPoints: total,
+		Lots:        lotsDTO,
+	}
+}
@@ -0,0 +1,40 @@
+package adapters
+
+import (
+	"leal-technical-test/internal/domain/models"
+	"leal-technical-test/internal/infra/dtos"
+)
+
+// Convierte un modelo de dominio a un DTO
+func ToRedemptionDTO(redemption *models.Redemption) dtos.RedemptionResponse {
+	if redemption == nil {
+		return dtos.RedemptionResponse{}
+	}
+	return dtos.RedemptionResponse{
+		Id:             redemption.ID,
+		UserID:         redemption.UserID,
+		User:           redemption.User.Name,
+		StoreID:        redemption.StoreID,
+		Store:          redemption.Store.Name,
+		RewardID:       redemption.RewardID,
+		Reward:         redemption.Reward.Description,
+		BranchID:       redemption.BranchID,
+		PointsSpent:    redemption.PointsSpent,
+		Status:         redemption.Status,
+		VoucherCode:    redemption.VoucherCode,
+		ExpiresAt:      redemption.ExpiresAt,
+		UsedAtBranchID: redemption.UsedAtBranchID,
+		CreatedAt:      redemption.CreatedAt,
+		FulfilledAt:    redemption.FulfilledAt,
+		CancelledAt:    redemption.CancelledAt,
+	}
+}
+
+// Convierte una lista de modelos de dominio a una lista de DTOs
+func ToRedemptionDTOs(redemptions []models.Redemption) []dtos.RedemptionResponse {
+	redemptionsDTO := make([]dtos.RedemptionResponse, len(redemptions))
+	for i := range redemptions {
+		redemptionsDTO[i] = ToRedemptionDTO(&redemptions[i])
+	}
+	return redemptionsDTO
+}
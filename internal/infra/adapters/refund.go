@@ -0,0 +1,34 @@
+package adapters
+
+import (
+	"leal-technical-test/internal/domain/models"
+	"leal-technical-test/internal/infra/dtos"
+)
+
+// Convierte un modelo de dominio a un DTO
+func ToRefundDTO(refund *models.Refund) dtos.RefundResponse {
+	if refund == nil {
+		return dtos.RefundResponse{}
+	}
+	return dtos.RefundResponse{
+		Id:               refund.ID,
+		TransactionID:    refund.TransactionID,
+		Amount:           refund.Amount,
+		PointsReversed:   refund.PointsReversed,
+		CashbackReversed: refund.CashbackReversed,
+		PointsWaived:     refund.PointsWaived,
+		CashbackWaived:   refund.CashbackWaived,
+		CashbackReturned: refund.CashbackReturned,
+		Reason:           refund.Reason,
+		CreatedAt:        refund.CreatedAt,
+	}
+}
+
+// Convierte una lista de modelos de dominio a una lista de DTOs
+func ToRefundDTOs(refunds []models.Refund) []dtos.RefundResponse {
+	refundsDTO := make([]dtos.RefundResponse, len(refunds))
+	for i := range refunds {
+		refundsDTO[i] = ToRefundDTO(&refunds[i])
+	}
+	return refundsDTO
+}
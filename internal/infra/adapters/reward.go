@@ -10,35 +10,53 @@ func ToRewardsDTO(reward *models.Reward) dtos.RewardResponse {
 	if reward == nil {
 		return dtos.RewardResponse{}
 	}
+	branchIDs := make([]uint, len(reward.Branches))
+	for i, branch := range reward.Branches {
+		branchIDs[i] = branch.ID
+	}
 	return dtos.RewardResponse{
 		Id:             reward.ID,
 		StoreID:        reward.StoreID,
 		Store:          reward.Store.Name,
 		Description:    reward.Description,
 		PointsRequired: reward.PointsRequired,
+		Stock:          reward.Stock,
+		PerUserLimit:   reward.PerUserLimit,
+		PeriodLimit:    reward.PeriodLimit,
+		PeriodDays:     reward.PeriodDays,
+		StartsAt:       reward.StartsAt,
+		EndsAt:         reward.EndsAt,
+		BranchIDs:      branchIDs,
 	}
 }
 
 // Convierte una lista de modelos de dominio a una lista de DTOs
 func ToRewardsDTOs(rewards []models.Reward) []dtos.RewardResponse {
 	rewardDTO := make([]dtos.RewardResponse, len(rewards))
-	for i, reward := range rewards {
-		rewardDTO[i] = dtos.RewardResponse{
-			Id:             reward.ID,
-			StoreID:        reward.StoreID,
-			Store:          reward.Store.Name,
-			Description:    reward.Description,
-			PointsRequired: reward.PointsRequired,
-		}
+	for i := range rewards {
+		rewardDTO[i] = ToRewardsDTO(&rewards[i])
 	}
 	return rewardDTO
 }
 
-// Convierte un DTO en un modelo de dominio
+// Convierte un DTO en un modelo de dominio. Las sucursales solo llevan el ID; el servicio las valida
 func ToRewardModel(reward dtos.RewardRequest) models.Reward {
-	return models.Reward{
+	model := models.Reward{
 		StoreID:        reward.StoreID,
 		Description:    reward.Description,
 		PointsRequired: reward.PointsRequired,
+		Stock:          reward.Stock,
+		PerUserLimit:   reward.PerUserLimit,
+		PeriodLimit:    reward.PeriodLimit,
+		PeriodDays:     reward.PeriodDays,
+		StartsAt:       reward.StartsAt,
+		EndsAt:         reward.EndsAt,
+	}
+	if reward.BranchIDs != nil {
+		model.Branches = make([]models.Branch, len(reward.BranchIDs))
+		for i, branchID := range reward.BranchIDs {
+			model.Branches[i].ID = branchID
+		}
 	}
+	return model
 }
@@ -8,9 +8,22 @@ import (
 // Convierte un modelo de dominio a un DTO
 func ToStoreDTO(store models.Store) dtos.StoreResponse {
 	return dtos.StoreResponse{
-		ID:               store.ID,
-		Name:             store.Name,
-		ConversionFactor: store.ConversionFactor,
+		ID:                     store.ID,
+		Name:                   store.Name,
+		ConversionFactor:       store.ConversionFactor,
+		CashbackPercentage:     store.CashbackPercentage,
+		CashbackPaymentEarns:   store.CashbackPaymentEarns,
+		StackingPolicy:         store.StackingPolicy,
+		PointsExpirationMonths: store.PointsExpirationMonths,
+		NegativeBalancePolicy:  store.NegativeBalancePolicy,
+		TierWindowDays:         store.TierWindowDays,
+		TierGraceDays:          store.TierGraceDays,
+		TransferMinPoints:      store.TransferMinPoints,
+		TransferMaxPoints:      store.TransferMaxPoints,
+		TransferDailyCap:       store.TransferDailyCap,
+		TransferFeePercentage:  store.TransferFeePercentage,
+		TransferMinAccountDays: store.TransferMinAccountDays,
+		VoucherValidityDays:    store.VoucherValidityDays,
 	}
 }
 
@@ -19,9 +32,22 @@ func ToStoreDTOs(stores []models.Store) []dtos.StoreResponse {
 	storesDTO := make([]dtos.StoreResponse, len(stores))
 	for i, store := range stores {
 		storesDTO[i] = dtos.StoreResponse{
-			ID:               store.ID,
-			Name:             store.Name,
-			ConversionFactor: store.ConversionFactor,
+			ID:                     store.ID,
+			Name:                   store.Name,
+			ConversionFactor:       store.ConversionFactor,
+			CashbackPercentage:     store.CashbackPercentage,
+			CashbackPaymentEarns:   store.CashbackPaymentEarns,
+			StackingPolicy:         store.StackingPolicy,
+			PointsExpirationMonths: store.PointsExpirationMonths,
+			NegativeBalancePolicy:  store.NegativeBalancePolicy,
+			TierWindowDays:         store.TierWindowDays,
+			TierGraceDays:          store.TierGraceDays,
+			TransferMinPoints:      store.TransferMinPoints,
+			TransferMaxPoints:      store.TransferMaxPoints,
+			TransferDailyCap:       store.TransferDailyCap,
+			TransferFeePercentage:  store.TransferFeePercentage,
+			TransferMinAccountDays: store.TransferMinAccountDays,
+			VoucherValidityDays:    store.VoucherValidityDays,
 			// Mapear otros campos específicos aquí
 		}
 	}
@@ -31,7 +57,20 @@ func ToStoreDTOs(stores []models.Store) []dtos.StoreResponse {
 // Convierte un DTO en un modelo de dominio
 func ToStoreModel(dto dtos.StoreRequest) models.Store {
 	return models.Store{
-		Name:             dto.Name,
-		ConversionFactor: dto.ConversionFactor,
+		Name:                   dto.Name,
+		ConversionFactor:       dto.ConversionFactor,
+		CashbackPercentage:     dto.CashbackPercentage,
+		CashbackPaymentEarns:   dto.CashbackPaymentEarns,
+		StackingPolicy:         dto.StackingPolicy,
+		PointsExpirationMonths: dto.PointsExpirationMonths,
+		NegativeBalancePolicy:  dto.NegativeBalancePolicy,
+		TierWindowDays:         dto.TierWindowDays,
+		TierGraceDays:          dto.TierGraceDays,
+		TransferMinPoints:      dto.TransferMinPoints,
+		TransferMaxPoints:      dto.TransferMaxPoints,
+		TransferDailyCap:       dto.TransferDailyCap,
+		TransferFeePercentage:  dto.TransferFeePercentage,
+		TransferMinAccountDays: dto.TransferMinAccountDays,
+		VoucherValidityDays:    dto.VoucherValidityDays,
 	}
 }
@@ -0,0 +1,81 @@
+package adapters
+
+import (
+	"leal-technical-test/internal/domain/models"
+	"leal-technical-test/internal/infra/dtos"
+)
+
+// Convierte un modelo de dominio a un DTO
+func ToTierDTO(tier models.Tier) dtos.TierResponse {
+	return dtos.TierResponse{
+		ID:             tier.ID,
+		StoreID:        tier.StoreID,
+		Name:           tier.Name,
+		Rank:           tier.Rank,
+		ThresholdType:  tier.ThresholdType,
+		Threshold:      tier.Threshold,
+		EarnMultiplier: tier.EarnMultiplier,
+	}
+}
+
+// Convierte una lista de modelos de dominio a una lista de DTOs
+func ToTierDTOs(tiers []models.Tier) []dtos.TierResponse {
+	tiersDTO := make([]dtos.TierResponse, len(tiers))
+	for i, tier := range tiers {
+		tiersDTO[i] = ToTierDTO(tier)
+	}
+	return tiersDTO
+}
+
+// Convierte un DTO en un modelo de dominio
+func ToTierModel(dto dtos.TierRequest) models.Tier {
+	return models.Tier{
+		StoreID:        dto.StoreID,
+		Name:           dto.Name,
+		Rank:           dto.Rank,
+		ThresholdType:  dto.ThresholdType,
+		Threshold:      dto.Threshold,
+		EarnMultiplier: dto.EarnMultiplier,
+	}
+}
+
+// Convierte el progreso de un usuario en los niveles de una tienda a un DTO
+func ToTierProgressDTO(progress *models.TierProgress) dtos.TierProgressResponse {
+	progressDTO := dtos.TierProgressResponse{
+		UserID:      progress.UserID,
+		StoreID:     progress.StoreID,
+		WindowDays:  progress.WindowDays,
+		Spend:       progress.Spend,
+		Points:      progress.Points,
+		Remaining:   progress.Remaining,
+		DowngradeAt: progress.DowngradeAt,
+		History:     make([]dtos.TierHistoryResponse, len(progress.History)),
+	}
+	if progress.Current != nil {
+		current := ToTierDTO(*progress.Current)
+		progressDTO.CurrentTier = &current
+	}
+	if progress.Next != nil {
+		next := ToTierDTO(*progress.Next)
+		progressDTO.NextTier = &next
+	}
+	for i, change := range progress.History {
+		progressDTO.History[i] = dtos.TierHistoryResponse{
+			FromTier:  tierName(change.FromTier),
+			ToTier:    tierName(change.ToTier),
+			Reason:    change.Reason,
+			Spend:     change.Spend,
+			Points:    change.Points,
+			CreatedAt: change.CreatedAt,
+		}
+	}
+	return progressDTO
+}
+
+// tierName devuelve el nombre de un nivel, o vacío cuando el usuario no tenía nivel
+func tierName(tier *models.Tier) string {
+	if tier == nil {
+		return ""
+	}
+	return tier.Name
+}
@@ -3,6 +3,7 @@ package adapters
 import (
 	"leal-technical-test/internal/domain/models"
 	"leal-technical-test/internal/infra/dtos"
+	"time"
 )
 
 // Convierte un modelo de dominio a un DTO
@@ -11,15 +12,22 @@ func ToTransactionDTO(transaction *models.Transaction) dtos.TransactionResponse
 		return dtos.TransactionResponse{}
 	}
 	return dtos.TransactionResponse{
-		UserID:         transaction.UserID,
-		User:           transaction.User.Name,
-		BranchID:       transaction.BranchID,
-		Branch:         transaction.Branch.Name,
-		Amount:         transaction.Amount,
-		Date:           transaction.Date,
-		RewardType:     transaction.RewardType,
-		PointsEarned:   transaction.PointsEarned,
-		CashbackEarned: transaction.CashbackEarned,
+		Id:                transaction.ID,
+		UserID:            transaction.UserID,
+		User:              transaction.User.Name,
+		BranchID:          transaction.BranchID,
+		Branch:            transaction.Branch.Name,
+		Amount:            transaction.Amount,
+		CashbackApplied:   transaction.CashbackApplied,
+		NetAmount:         transaction.NetAmount,
+		Date:              transaction.Date,
+		RewardType:        transaction.RewardType,
+		ExternalReceiptID: transaction.ExternalReceiptID,
+		PointsEarned:      transaction.PointsEarned,
+		CashbackEarned:    transaction.CashbackEarned,
+		RefundedAmount:    transaction.RefundedAmount,
+		CashbackReturned:  transaction.CashbackReturned,
+		Breakdown:         toEarningBreakdownDTO(transaction),
 	}
 }
 
@@ -28,25 +36,95 @@ func ToTransactionDTOs(transactions []models.Transaction) []dtos.TransactionResp
 	transactionsDTO := make([]dtos.TransactionResponse, len(transactions))
 	for i, transaction := range transactions {
 		transactionsDTO[i] = dtos.TransactionResponse{
-			UserID:         transaction.UserID,
-			User:           transaction.User.Name,
-			BranchID:       transaction.BranchID,
-			Branch:         transaction.Branch.Name,
-			Amount:         transaction.Amount,
-			Date:           transaction.Date,
-			RewardType:     transaction.RewardType,
-			PointsEarned:   transaction.PointsEarned,
-			CashbackEarned: transaction.CashbackEarned,
+			Id:                transaction.ID,
+			UserID:            transaction.UserID,
+			User:              transaction.User.Name,
+			BranchID:          transaction.BranchID,
+			Branch:            transaction.Branch.Name,
+			Amount:            transaction.Amount,
+			CashbackApplied:   transaction.CashbackApplied,
+			NetAmount:         transaction.NetAmount,
+			Date:              transaction.Date,
+			RewardType:        transaction.RewardType,
+			ExternalReceiptID: transaction.ExternalReceiptID,
+			PointsEarned:      transaction.PointsEarned,
+			CashbackEarned:    transaction.CashbackEarned,
+			RefundedAmount:    transaction.RefundedAmount,
+			CashbackReturned:  transaction.CashbackReturned,
+			Breakdown:         toEarningBreakdownDTO(&transaction),
 		}
 	}
 	return transactionsDTO
 }
 
+// Convierte el desglose de la recompensa de una transacción a un DTO
+func toEarningBreakdownDTO(transaction *models.Transaction) dtos.EarningBreakdownResponse {
+	return dtos.EarningBreakdownResponse{
+		BasePoints:         transaction.BasePoints,
+		ConversionFactor:   transaction.ConversionFactor,
+		CashbackPercentage: transaction.CashbackPercentage,
+		TierID:             transaction.TierID,
+		TierMultiplier:     transaction.TierMultiplier,
+		TierBonusPoints:    transaction.TierBonusPoints,
+		AppliedCampaigns:   toAppliedCampaignDTOs(transaction.AppliedCampaigns),
+		BonusPoints:        transaction.BonusPoints,
+		CapAdjustment:      transaction.CapAdjustment,
+		RoundingAdjustment: transaction.RoundingAdjustment,
+	}
+}
+
+// Convierte las campañas aplicadas a una transacción a DTOs
+func toAppliedCampaignDTOs(applied []models.TransactionCampaign) []dtos.AppliedCampaignResponse {
+	appliedDTO := make([]dtos.AppliedCampaignResponse, len(applied))
+	for i, campaign := range applied {
+		appliedDTO[i] = dtos.AppliedCampaignResponse{
+			CampaignID:  campaign.CampaignID,
+			Campaign:    campaign.Campaign.Name,
+			BonusPoints: campaign.BonusPoints,
+		}
+	}
+	return appliedDTO
+}
+
 // Convierte un DTO en un modelo de dominio
 func ToTransactionModel(transaction dtos.TransactionRequest) *models.Transaction {
+	model := &models.Transaction{
+		UserID:          transaction.UserID,
+		BranchID:        transaction.BranchID,
+		Amount:          transaction.Amount,
+		CashbackApplied: transaction.CashbackToApply,
+		RewardType:      transaction.RewardType,
+	}
+	if transaction.ExternalReceiptID != "" {
+		receiptID := transaction.ExternalReceiptID
+		model.ExternalReceiptID = &receiptID
+	}
+	return model
+}
+
+// Convierte una compra simulada a un DTO
+func ToTransactionPreviewDTO(transaction *models.Transaction, date time.Time) dtos.TransactionPreviewResponse {
+	return dtos.TransactionPreviewResponse{
+		UserID:          transaction.UserID,
+		BranchID:        transaction.BranchID,
+		Amount:          transaction.Amount,
+		CashbackApplied: transaction.CashbackApplied,
+		NetAmount:       transaction.NetAmount,
+		Date:            date,
+		RewardType:      transaction.RewardType,
+		PointsEarned:    transaction.PointsEarned,
+		CashbackEarned:  transaction.CashbackEarned,
+		Breakdown:       toEarningBreakdownDTO(transaction),
+	}
+}
+
+// Convierte la solicitud de una vista previa en un modelo de dominio
+func ToTransactionPreviewModel(preview dtos.TransactionPreviewRequest) *models.Transaction {
 	return &models.Transaction{
-		UserID:   transaction.UserID,
-		BranchID: transaction.BranchID,
-		Amount:   transaction.Amount,
+		UserID:          preview.UserID,
+		BranchID:        preview.BranchID,
+		Amount:          preview.Amount,
+		CashbackApplied: preview.CashbackToApply,
+		RewardType:      preview.RewardType,
 	}
 }
@@ -11,10 +11,13 @@ func ToUserDTO(user *models.User) dtos.UserResponse {
 		return dtos.UserResponse{}
 	}
 	return dtos.UserResponse{
-		Id:    user.ID,
-		Name:  user.Name,
-		Phone: user.Phone,
-		Email: user.Email,
+		Id:        user.ID,
+		Name:      user.Name,
+		Phone:     user.Phone,
+		Email:     user.Email,
+		Role:      user.Role,
+		StoreIDs:  user.StoreIDs(),
+		Suspended: user.Suspended,
 	}
 }
 
@@ -24,10 +27,13 @@ func ToUserDTOs(users []models.User) []dtos.UserResponse {
 	for i, user := range users {
 		// Mapear los campos directamente, aplicando transformaciones si es necesario
 		userDTOs[i] = dtos.UserResponse{
-			Id:    user.ID,
-			Name:  user.Name,
-			Phone: user.Phone,
-			Email: user.Email,
+			Id:        user.ID,
+			Name:      user.Name,
+			Phone:     user.Phone,
+			Email:     user.Email,
+			Role:      user.Role,
+			StoreIDs:  user.StoreIDs(),
+			Suspended: user.Suspended,
 		}
 	}
 	return userDTOs
@@ -42,3 +48,12 @@ func ToUserModel(user dtos.UserRequest) models.User {
 		Password: user.Password,
 	}
 }
+
+// Convierte el par de tokens de una sesión en la respuesta de login y refresh
+func ToTokenDTO(pair *models.TokenPair) dtos.TokenResponse {
+	return dtos.TokenResponse{
+		Token:        pair.AccessToken,
+		RefreshToken: pair.RefreshToken,
+		ExpiresAt:    pair.ExpiresAt,
+	}
+}
@@ -1,11 +1,13 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
 	"leal-technical-test/config"
 	"leal-technical-test/internal/infra/adapters"
+	"leal-technical-test/internal/infra/dtos"
 	"leal-technical-test/internal/infra/repository"
 	"leal-technical-test/internal/services"
 
@@ -20,8 +22,12 @@ type AccumulatedRewardController struct {
 // NewAccumulatedRewardController constructor
 func NewAccumulatedRewardController() *AccumulatedRewardController {
 	db := config.NewPostgresConnection()
+	uow := config.NewUnitOfWork(db)
 	repo := repository.NewAccumulatedRewardRepository(db)
-	service := services.NewAccumulatedRewardService(repo)
+	ledgerRepo := repository.NewPointsLedgerRepository(db)
+	lotRepo := repository.NewPointsLotRepository(db)
+	storeRepo := repository.NewStoreRepository(db)
+	service := services.NewAccumulatedRewardService(uow, repo, ledgerRepo, lotRepo, storeRepo)
 
 	return &AccumulatedRewardController{
 		service: service,
@@ -37,9 +43,9 @@ func NewAccumulatedRewardController() *AccumulatedRewardController {
 // @Security ApiKeyAuth
 // @Router /leal-test/acumulaterewards [get]
 func (c *AccumulatedRewardController) GetAllRewards(ctx *gin.Context) {
-	rewards, err := c.service.GetAllRewards()
+	rewards, err := c.service.WithScope(config.ScopeFromContext(ctx)).GetAllRewards()
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 	rewardsDTOs := adapters.ToAccumulateRewardDTOs(rewards)
@@ -47,6 +53,27 @@ func (c *AccumulatedRewardController) GetAllRewards(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, rewardsDTOs)
 }
 
+// GetMyRewards handles GET requests to retrieve the balances of the authenticated user
+// @Summary Get my balances
+// @Description Get the points and cashback of the user in the token, one entry per store
+// @Tags me
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Router /leal-test/me/balances [get]
+func (c *AccumulatedRewardController) GetMyRewards(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+	rewards, err := c.service.GetRewardsByUserId(userID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, adapters.ToAccumulateRewardDTOs(rewards))
+}
+
 // GetRewardById handles GET requests to retrieve an accumulated reward by its ID
 // @Summary Get accumulated reward by ID
 // @Description Get accumulated reward by ID
@@ -63,9 +90,9 @@ func (c *AccumulatedRewardController) GetRewardById(ctx *gin.Context) {
 		return
 	}
 
-	reward, err := c.service.GetRewardById(uint(id))
+	reward, err := c.service.WithScope(config.ScopeFromContext(ctx)).GetRewardById(uint(id))
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 	rewardDTO := adapters.ToAccumulateRewardDTO(reward)
@@ -95,12 +122,167 @@ func (c *AccumulatedRewardController) GetRewardByUserAndStore(ctx *gin.Context)
 		return
 	}
 
-	reward, err := c.service.GetRewardByUserAndStore(uint(userID), uint(storeID))
+	reward, err := c.service.WithScope(config.ScopeFromContext(ctx)).GetRewardByUserAndStore(uint(userID), uint(storeID))
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 	rewardDTO := adapters.ToAccumulateRewardDTO(reward)
 
 	ctx.JSON(http.StatusOK, rewardDTO)
 }
+
+// GetLedgerByUserAndStore handles GET requests to retrieve the points ledger of a user in a store
+// @Summary Get points ledger by UserID and StoreID
+// @Description Get every earn, redeem, adjust and expire entry with its running balance
+// @Tags accumulated_rewards
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param user_id path int true "User ID"
+// @Param store_id path int true "Store ID"
+// @Router /leal-test/acumulaterewards/user/{user_id}/store/{store_id}/ledger [get]
+func (c *AccumulatedRewardController) GetLedgerByUserAndStore(ctx *gin.Context) {
+	userID, err := strconv.Atoi(ctx.Param("user_id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	storeID, err := strconv.Atoi(ctx.Param("store_id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid store ID"})
+		return
+	}
+
+	entries, err := c.service.WithScope(config.ScopeFromContext(ctx)).GetLedger(uint(userID), uint(storeID))
+	if err != nil {
+		ctx.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	ledgerDTO := adapters.ToLedgerDTO(uint(userID), uint(storeID), entries)
+
+	ctx.JSON(http.StatusOK, ledgerDTO)
+}
+
+// GetExpiringPoints handles GET requests to retrieve the points of a user that expire in the next days
+// @Summary Get expiring points by UserID and StoreID
+// @Description Get the lots of points that expire in the next N days (30 by default)
+// @Tags accumulated_rewards
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param user_id path int true "User ID"
+// @Param store_id path int true "Store ID"
+// @Param days query int false "Days ahead"
+// @Router /leal-test/acumulaterewards/user/{user_id}/store/{store_id}/expiring [get]
+func (c *AccumulatedRewardController) GetExpiringPoints(ctx *gin.Context) {
+	userID, err := strconv.Atoi(ctx.Param("user_id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	storeID, err := strconv.Atoi(ctx.Param("store_id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid store ID"})
+		return
+	}
+
+	days, err := strconv.Atoi(ctx.DefaultQuery("days", "30"))
+	if err != nil || days <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid days"})
+		return
+	}
+
+	lots, err := c.service.WithScope(config.ScopeFromContext(ctx)).GetExpiringPoints(uint(userID), uint(storeID), days)
+	if err != nil {
+		ctx.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	expiringDTO := adapters.ToExpiringPointsDTO(uint(userID), uint(storeID), days, lots)
+
+	ctx.JSON(http.StatusOK, expiringDTO)
+}
+
+// AdjustPoints handles POST requests to apply a manual adjustment to a balance
+// @Summary Adjust points of a user in a store
+// @Description Apply a positive or negative manual adjustment recorded in the ledger
+// @Tags accumulated_rewards
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param user_id path int true "User ID"
+// @Param store_id path int true "Store ID"
+// @Param adjustment body dtos.AdjustPointsRequest true "Adjustment data"
+// @Router /leal-test/acumulaterewards/user/{user_id}/store/{store_id}/adjust [post]
+func (c *AccumulatedRewardController) AdjustPoints(ctx *gin.Context) {
+	userID, err := strconv.Atoi(ctx.Param("user_id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	storeID, err := strconv.Atoi(ctx.Param("store_id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid store ID"})
+		return
+	}
+
+	var adjustmentDTO dtos.AdjustPointsRequest
+	if err := ctx.ShouldBindJSON(&adjustmentDTO); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err = c.service.WithScope(config.ScopeFromContext(ctx)).AdjustPoints(uint(userID), uint(storeID), adjustmentDTO.Points, adjustmentDTO.Description)
+	if err != nil {
+		ctx.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Points adjusted successfully"})
+}
+
+// RedeemCashback handles POST requests to spend part of a cashback balance
+// @Summary Redeem cashback of a user in a store
+// @Description Spend accumulated cashback. Cashback is redeemed separately from points.
+// @Tags accumulated_rewards
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param user_id path int true "User ID"
+// @Param store_id path int true "Store ID"
+// @Param redemption body dtos.RedeemCashbackRequest true "Cashback redemption data"
+// @Router /leal-test/acumulaterewards/user/{user_id}/store/{store_id}/cashback/redeem [post]
+func (c *AccumulatedRewardController) RedeemCashback(ctx *gin.Context) {
+	userID, err := strconv.Atoi(ctx.Param("user_id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	storeID, err := strconv.Atoi(ctx.Param("store_id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid store ID"})
+		return
+	}
+
+	var redemptionDTO dtos.RedeemCashbackRequest
+	if err := ctx.ShouldBindJSON(&redemptionDTO); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err = c.service.WithScope(config.ScopeFromContext(ctx)).RedeemCashback(uint(userID), uint(storeID), redemptionDTO.Amount, redemptionDTO.Description)
+	if err != nil {
+		if errors.Is(err, repository.ErrInsufficientCashback) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Cashback redeemed successfully"})
+}
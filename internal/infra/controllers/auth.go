@@ -0,0 +1,60 @@
+package controllers
+
+import (
+	"net/http"
+
+	"leal-technical-test/config"
+	"leal-technical-test/internal/domain/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// currentUserID devuelve el ID del usuario del token. Si el contexto no lo tiene responde 401
+// y devuelve false, para que el handler termine.
+func currentUserID(ctx *gin.Context) (uint, bool) {
+	userID, ok := config.UserIDFromContext(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+		return 0, false
+	}
+	return userID, true
+}
+
+// actingUserID devuelve el usuario en cuyo nombre se hace una operación que trae el usuario en el cuerpo.
+// Los clientes solo actúan por sí mismos: sin usuario en el cuerpo se toma el del token, y con otro
+// usuario se responde 403 y se devuelve false, para que el handler termine.
+func actingUserID(ctx *gin.Context, bodyUserID uint) (uint, bool) {
+	if bodyUserID == 0 && ctx.GetString("role") == models.RoleCustomer {
+		return currentUserID(ctx)
+	}
+	if !config.OwnsUser(ctx, bodyUserID) {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "Customers can only act on their own account"})
+		return 0, false
+	}
+	return bodyUserID, true
+}
+
+// AuthController struct
+type AuthController struct {
+	tokens *config.TokenManager
+}
+
+// NewAuthController constructor
+func NewAuthController() *AuthController {
+	return &AuthController{
+		tokens: config.NewTokenManager(),
+	}
+}
+
+// GetJWKS handles GET requests to publish the public signing keys
+// @Summary Get the public signing keys
+// @Description Publish the RS256 and EdDSA keys that are not retired as a JWKS, so other services can
+// @Description verify access tokens by their kid. HS256 keys are shared secrets and are never published.
+// @Tags auth
+// @Produce json
+// @Router /leal-test/.well-known/jwks.json [get]
+func (c *AuthController) GetJWKS(ctx *gin.Context) {
+	// Permitir que los servicios guarden las claves un rato; una clave nueva debe publicarse antes de activarse
+	ctx.Header("Cache-Control", "public, max-age=300")
+	ctx.JSON(http.StatusOK, c.tokens.PublicKeys())
+}
@@ -0,0 +1,150 @@
+package controllers
+
+import (
+	"bytes"
+	"leal-technical-test/config"
+	"leal-technical-test/internal/domain/models"
+	"leal-technical-test/internal/services"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// spendRecorder registra en nombre de qué usuario se gastaron puntos
+type spendRecorder struct {
+	spentBy []uint
+}
+
+// Los stubs de cada servicio embeben su interfaz para implementar solo los métodos que usan los
+// handlers probados, y anotan el gasto en el mismo spendRecorder
+type transferStub struct {
+	services.PointTransferService
+	*spendRecorder
+}
+
+type exchangeStub struct {
+	services.ExchangeService
+	*spendRecorder
+}
+
+type redemptionStub struct {
+	services.RedemptionService
+	*spendRecorder
+}
+
+func (s transferStub) WithScope(scope config.Scope) services.PointTransferService { return s }
+
+func (s transferStub) TransferPoints(transfer *models.PointTransfer) error {
+	s.spentBy = append(s.spentBy, transfer.FromUserID)
+	return nil
+}
+
+// previewStub anota para qué usuario se calculó la vista previa de una compra
+type previewStub struct {
+	services.TransactionService
+	previewedFor *[]uint
+}
+
+func (s previewStub) WithScope(scope config.Scope) services.TransactionService { return s }
+
+func (s previewStub) PreviewTransaction(transaction *models.Transaction, at time.Time) (*models.Transaction, error) {
+	*s.previewedFor = append(*s.previewedFor, transaction.UserID)
+	return transaction, nil
+}
+
+func (s exchangeStub) WithScope(scope config.Scope) services.ExchangeService { return s }
+
+func (s exchangeStub) ExchangePoints(userID uint, fromStoreID uint, toStoreID uint, points float64) (*models.PointsExchange, error) {
+	s.spentBy = append(s.spentBy, userID)
+	return &models.PointsExchange{UserID: userID}, nil
+}
+
+func (s redemptionStub) WithScope(scope config.Scope) services.RedemptionService { return s }
+
+func (s redemptionStub) ClaimReward(userID uint, rewardID uint, branchID *uint) (*models.Redemption, error) {
+	s.spentBy = append(s.spentBy, userID)
+	return &models.Redemption{UserID: userID}, nil
+}
+
+func (s redemptionStub) GetRedemptionById(id uint) (*models.Redemption, error) {
+	return &models.Redemption{}, nil
+}
+
+// Prueba que un cliente no puede gastar los puntos de otro usuario nombrándolo en el cuerpo, que sin
+// usuario en el cuerpo se usa el del token y que el personal sí puede operar por un cliente
+func TestCustomersCannotSpendOtherUsersPoints(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cases := []struct {
+		role     string
+		body     string
+		expected int
+		spentBy  []uint
+	}{
+		{models.RoleCustomer, `{"user_id": 2, "from_user_id": 2, "to_user_id": 1, "points": 10}`, http.StatusForbidden, nil},
+		{models.RoleCustomer, `{"to_user_id": 2, "points": 10}`, http.StatusCreated, []uint{1}},
+		{models.RoleCustomer, `{"user_id": 1, "from_user_id": 1, "to_user_id": 2, "points": 10}`, http.StatusCreated, []uint{1}},
+		{models.RoleCashier, `{"user_id": 2, "from_user_id": 2, "to_user_id": 1, "points": 10}`, http.StatusCreated, []uint{2}},
+	}
+	for _, c := range cases {
+		recorder := &spendRecorder{}
+		engine := gin.New()
+		engine.Use(func(ctx *gin.Context) {
+			ctx.Set("role", c.role)
+			ctx.Set("user_id", uint(1))
+		})
+		engine.POST("/transfers", (&PointTransferController{service: transferStub{spendRecorder: recorder}}).TransferPoints)
+		engine.POST("/exchanges", (&ExchangeController{service: exchangeStub{spendRecorder: recorder}}).ExchangePoints)
+		engine.POST("/rewards/claim", (&RedemptionController{service: redemptionStub{spendRecorder: recorder}}).ClaimReward)
+
+		for _, path := range []string{"/transfers", "/exchanges", "/rewards/claim"} {
+			recorder.spentBy = nil
+			response := httptest.NewRecorder()
+			engine.ServeHTTP(response, httptest.NewRequest(http.MethodPost, path, bytes.NewBufferString(c.body)))
+			if response.Code != c.expected {
+				t.Errorf("%s as %s with %s: expected %d, got %d", path, c.role, c.body, c.expected, response.Code)
+			}
+			if len(recorder.spentBy) != len(c.spentBy) || (len(c.spentBy) == 1 && recorder.spentBy[0] != c.spentBy[0]) {
+				t.Errorf("%s as %s with %s: expected points spent by %v, got %v", path, c.role, c.body, c.spentBy, recorder.spentBy)
+			}
+		}
+	}
+}
+
+// Prueba que un cliente solo puede ver la recompensa de sus propias compras, porque revela el nivel
+// y los límites de campaña del usuario
+func TestCustomersCannotPreviewOtherUsersPurchases(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cases := []struct {
+		role         string
+		body         string
+		expected     int
+		previewedFor []uint
+	}{
+		{models.RoleCustomer, `{"user_id": 2, "branch_id": 1, "amount": 100}`, http.StatusForbidden, nil},
+		{models.RoleCustomer, `{"branch_id": 1, "amount": 100}`, http.StatusOK, []uint{1}},
+		{models.RoleCashier, `{"user_id": 2, "branch_id": 1, "amount": 100}`, http.StatusOK, []uint{2}},
+	}
+	for _, c := range cases {
+		var previewedFor []uint
+		engine := gin.New()
+		engine.Use(func(ctx *gin.Context) {
+			ctx.Set("role", c.role)
+			ctx.Set("user_id", uint(1))
+		})
+		engine.POST("/transactions/preview", (&TransactionController{service: previewStub{previewedFor: &previewedFor}}).PreviewTransaction)
+
+		response := httptest.NewRecorder()
+		engine.ServeHTTP(response, httptest.NewRequest(http.MethodPost, "/transactions/preview", bytes.NewBufferString(c.body)))
+		if response.Code != c.expected {
+			t.Errorf("preview as %s with %s: expected %d, got %d", c.role, c.body, c.expected, response.Code)
+		}
+		if len(previewedFor) != len(c.previewedFor) || (len(c.previewedFor) == 1 && previewedFor[0] != c.previewedFor[0]) {
+			t.Errorf("preview as %s with %s: expected a preview for %v, got %v", c.role, c.body, c.previewedFor, previewedFor)
+		}
+	}
+}
@@ -39,9 +39,9 @@ func NewBranchController() *BranchController {
 // @Security ApiKeyAuth
 // @Router /leal-test/branches [get]
 func (c *BranchController) GetAllBranches(ctx *gin.Context) {
-	branches, err := c.service.GetAllBranches()
+	branches, err := c.service.WithScope(config.ScopeFromContext(ctx)).GetAllBranches()
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 	branchesDTO := adapters.ToBranchDTOs(branches)
@@ -63,9 +63,9 @@ func (c *BranchController) GetBranchById(ctx *gin.Context) {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid branch ID"})
 		return
 	}
-	branch, err := c.service.GetBranchById(uint(id))
+	branch, err := c.service.WithScope(config.ScopeFromContext(ctx)).GetBranchById(uint(id))
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 	branchDTO := adapters.ToBranchDTO(branch)
@@ -88,9 +88,9 @@ func (c *BranchController) CreateBranch(ctx *gin.Context) {
 		return
 	}
 	branch := adapters.ToBranchModel(branchDTO)
-	err := c.service.CreateBranch(&branch)
+	err := c.service.WithScope(config.ScopeFromContext(ctx)).CreateBranch(&branch)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -121,9 +121,9 @@ func (c *BranchController) UpdateBranch(ctx *gin.Context) {
 	}
 
 	branch.ID = uint(id)
-	err = c.service.UpdateBranch(&branch)
+	err = c.service.WithScope(config.ScopeFromContext(ctx)).UpdateBranch(&branch)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -144,9 +144,9 @@ func (c *BranchController) DeleteBranch(ctx *gin.Context) {
 		return
 	}
 
-	err = c.service.DeleteBranch(uint(id))
+	err = c.service.WithScope(config.ScopeFromContext(ctx)).DeleteBranch(uint(id))
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -22,7 +22,8 @@ type CampaignController struct {
 func NewCampaignController() *CampaignController {
 	db := config.NewPostgresConnection()
 	repo := repository.NewCampaignRepository(db)
-	service := services.NewCampaignService(repo)
+	repoBranch := repository.NewBranchRepository(db)
+	service := services.NewCampaignService(repo, repoBranch)
 
 	return &CampaignController{
 		service: service,
@@ -38,9 +39,9 @@ func NewCampaignController() *CampaignController {
 // @Security ApiKeyAuth
 // @Router /leal-test/campaigns [get]
 func (c *CampaignController) GetAllCampaigns(ctx *gin.Context) {
-	campaigns, err := c.service.GetAllCampaigns()
+	campaigns, err := c.service.WithScope(config.ScopeFromContext(ctx)).GetAllCampaigns()
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 	campaignsDTO := adapters.ToCampaignDTOs(campaigns)
@@ -50,7 +51,7 @@ func (c *CampaignController) GetAllCampaigns(ctx *gin.Context) {
 
 // GetCampaignById handles GET requests to retrieve a campaign by its ID
 // @Summary Get campaign by ID
-// @Description Get campaign by ID
+// @Description Get campaign by ID, including the bonus points left in its budget
 // @Tags campaigns
 // @Accept  json
 // @Produce  json
@@ -64,9 +65,9 @@ func (c *CampaignController) GetCampaignById(ctx *gin.Context) {
 		return
 	}
 
-	campaign, err := c.service.GetCampaignById(uint(id))
+	campaign, err := c.service.WithScope(config.ScopeFromContext(ctx)).GetCampaignById(uint(id))
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 	campaignDTO := adapters.ToCampaignDTO(campaign)
@@ -91,9 +92,9 @@ func (c *CampaignController) CreateCampaign(ctx *gin.Context) {
 
 	campaign := adapters.ToCampaignModel(campaignDTO)
 
-	err := c.service.CreateCampaign(&campaign)
+	err := c.service.WithScope(config.ScopeFromContext(ctx)).CreateCampaign(&campaign)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -102,7 +103,7 @@ func (c *CampaignController) CreateCampaign(ctx *gin.Context) {
 
 // UpdateCampaign handles PUT requests to update a campaign
 // @Summary Update a campaign
-// @Description Update a campaign
+// @Description Update a campaign. The priority, exclusive flag, min_amount and limits are replaced, so omitting them resets them
 // @Tags campaigns
 // @Accept  json
 // @Produce  json
@@ -124,9 +125,9 @@ func (c *CampaignController) UpdateCampaign(ctx *gin.Context) {
 	}
 	campaign := adapters.ToCampaignModel(campaignDTO)
 
-	err = c.service.UpdateCampaign(uint(id), &campaign)
+	err = c.service.WithScope(config.ScopeFromContext(ctx)).UpdateCampaign(uint(id), &campaign)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -147,11 +148,23 @@ func (c *CampaignController) DeleteCampaign(ctx *gin.Context) {
 		return
 	}
 
-	err = c.service.DeleteCampaign(uint(id))
+	err = c.service.WithScope(config.ScopeFromContext(ctx)).DeleteCampaign(uint(id))
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 
 	ctx.JSON(http.StatusOK, gin.H{"message": "Campaign deleted successfully"})
 }
+
+// GetCampaignTypes handles GET requests to list the available campaign types
+// @Summary Get campaign types
+// @Description List the campaign types that have a registered earning rule
+// @Tags campaigns
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Router /leal-test/campaigns/types [get]
+func (c *CampaignController) GetCampaignTypes(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{"types": c.service.GetCampaignTypes()})
+}
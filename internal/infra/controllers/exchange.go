@@ -0,0 +1,234 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"leal-technical-test/config"
+	"leal-technical-test/internal/infra/adapters"
+	"leal-technical-test/internal/infra/dtos"
+	"leal-technical-test/internal/infra/repository"
+	"leal-technical-test/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExchangeController struct
+type ExchangeController struct {
+	service services.ExchangeService
+}
+
+// NewExchangeController constructor
+func NewExchangeController() *ExchangeController {
+	db := config.NewPostgresConnection()
+	uow := config.NewUnitOfWork(db)
+	repo := repository.NewExchangeRepository(db)
+	repoUser := repository.NewUserRepository(db)
+	repoStore := repository.NewStoreRepository(db)
+	accumulatedService := services.NewAccumulatedRewardService(
+		uow,
+		repository.NewAccumulatedRewardRepository(db),
+		repository.NewPointsLedgerRepository(db),
+		repository.NewPointsLotRepository(db),
+		repoStore,
+	)
+	service := services.NewExchangeService(uow, repo, repoUser, repoStore, accumulatedService)
+
+	return &ExchangeController{
+		service: service,
+	}
+}
+
+// GetRatesByStoreId handles GET requests to retrieve the exchange rates of a store
+// @Summary Get exchange rates by StoreID
+// @Description Get the rates that convert points from or into a store
+// @Tags exchanges
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param store_id path int true "Store ID"
+// @Router /leal-test/exchange-rates/store/{store_id} [get]
+func (c *ExchangeController) GetRatesByStoreId(ctx *gin.Context) {
+	storeID, err := strconv.Atoi(ctx.Param("store_id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid store ID"})
+		return
+	}
+
+	rates, err := c.service.WithScope(config.ScopeFromContext(ctx)).GetRatesByStoreId(uint(storeID))
+	if err != nil {
+		ctx.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, adapters.ToExchangeRateDTOs(rates))
+}
+
+// CreateRate handles POST requests to publish an exchange rate
+// @Summary Create an exchange rate
+// @Description Publish the rate to convert points of one store into points of another
+// @Tags exchanges
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param rate body dtos.ExchangeRateRequest true "Exchange rate data"
+// @Router /leal-test/exchange-rates [post]
+func (c *ExchangeController) CreateRate(ctx *gin.Context) {
+	var rateDTO dtos.ExchangeRateRequest
+	if err := ctx.ShouldBindJSON(&rateDTO); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rate := adapters.ToExchangeRateModel(rateDTO)
+	if err := c.service.WithScope(config.ScopeFromContext(ctx)).CreateRate(rate); err != nil {
+		ctx.JSON(scopedStatus(err, http.StatusBadRequest), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, adapters.ToExchangeRateDTO(rate))
+}
+
+// UpdateRate handles PUT requests to replace the terms of an exchange rate
+// @Summary Update an exchange rate
+// @Description Replace the rate, limits and status of an exchange rate. The stores don't change.
+// @Tags exchanges
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param id path int true "Exchange rate ID"
+// @Param rate body dtos.ExchangeRateRequest true "Exchange rate data"
+// @Router /leal-test/exchange-rates/{id} [put]
+func (c *ExchangeController) UpdateRate(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid exchange rate ID"})
+		return
+	}
+
+	var rateDTO dtos.ExchangeRateRequest
+	if err := ctx.ShouldBindJSON(&rateDTO); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := c.service.WithScope(config.ScopeFromContext(ctx)).UpdateRate(uint(id), adapters.ToExchangeRateModel(rateDTO)); err != nil {
+		ctx.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Exchange rate updated successfully"})
+}
+
+// DeleteRate handles DELETE requests to delete an exchange rate by ID
+// @Summary Delete exchange rate by ID
+// @Description Delete an exchange rate by ID
+// @Tags exchanges
+// @Security ApiKeyAuth
+// @Param id path int true "Exchange rate ID"
+// @Router /leal-test/exchange-rates/{id} [delete]
+func (c *ExchangeController) DeleteRate(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid exchange rate ID"})
+		return
+	}
+
+	if err := c.service.WithScope(config.ScopeFromContext(ctx)).DeleteRate(uint(id)); err != nil {
+		ctx.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Exchange rate deleted successfully"})
+}
+
+// ExchangePoints handles POST requests to convert points of a user between two stores
+// @Summary Exchange points between stores
+// @Description Convert points from one store into another at the published rate.
+// @Description Customers can only convert their own points; without user_id the user of the token is used.
+// @Tags exchanges
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param exchange body dtos.PointsExchangeRequest true "Exchange data"
+// @Router /leal-test/exchanges [post]
+func (c *ExchangeController) ExchangePoints(ctx *gin.Context) {
+	var exchangeDTO dtos.PointsExchangeRequest
+	if err := ctx.ShouldBindJSON(&exchangeDTO); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, ok := actingUserID(ctx, exchangeDTO.UserID)
+	if !ok {
+		return
+	}
+
+	exchange, err := c.service.WithScope(config.ScopeFromContext(ctx)).ExchangePoints(userID, exchangeDTO.FromStoreID, exchangeDTO.ToStoreID, exchangeDTO.Points)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrExchangeRateNotFound):
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrUserSuspended):
+			ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrExchangeDailyCap), errors.Is(err, repository.ErrInsufficientPoints):
+			ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			ctx.JSON(scopedStatus(err, http.StatusBadRequest), gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, adapters.ToPointsExchangeDTO(exchange))
+}
+
+// GetExchangesByUserId handles GET requests to retrieve the conversions of a user
+// @Summary Get exchanges by UserID
+// @Description Get the conversions of a user between stores, newest first
+// @Tags exchanges
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param user_id path int true "User ID"
+// @Router /leal-test/exchanges/user/{user_id} [get]
+func (c *ExchangeController) GetExchangesByUserId(ctx *gin.Context) {
+	userID, err := strconv.Atoi(ctx.Param("user_id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	exchanges, err := c.service.WithScope(config.ScopeFromContext(ctx)).GetExchangesByUserId(uint(userID))
+	if err != nil {
+		ctx.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, adapters.ToPointsExchangeDTOs(exchanges))
+}
+
+// GetSettlements handles GET requests to retrieve what a store owes and is owed by the other stores
+// @Summary Get exchange settlements by StoreID
+// @Description Get the net value of the points converted between a store and every other store
+// @Tags exchanges
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param store_id path int true "Store ID"
+// @Router /leal-test/exchanges/settlements/store/{store_id} [get]
+func (c *ExchangeController) GetSettlements(ctx *gin.Context) {
+	storeID, err := strconv.Atoi(ctx.Param("store_id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid store ID"})
+		return
+	}
+
+	settlements, err := c.service.WithScope(config.ScopeFromContext(ctx)).GetSettlements(uint(storeID))
+	if err != nil {
+		ctx.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, adapters.ToStoreSettlementDTOs(settlements))
+}
@@ -0,0 +1,112 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"leal-technical-test/config"
+	"leal-technical-test/internal/infra/adapters"
+	"leal-technical-test/internal/infra/dtos"
+	"leal-technical-test/internal/infra/repository"
+	"leal-technical-test/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PointTransferController struct
+type PointTransferController struct {
+	service services.PointTransferService
+}
+
+// NewPointTransferController constructor
+func NewPointTransferController() *PointTransferController {
+	db := config.NewPostgresConnection()
+	uow := config.NewUnitOfWork(db)
+	repo := repository.NewPointTransferRepository(db)
+	repoUser := repository.NewUserRepository(db)
+	repoStore := repository.NewStoreRepository(db)
+	accumulatedService := services.NewAccumulatedRewardService(
+		uow,
+		repository.NewAccumulatedRewardRepository(db),
+		repository.NewPointsLedgerRepository(db),
+		repository.NewPointsLotRepository(db),
+		repoStore,
+	)
+	service := services.NewPointTransferService(uow, repo, repoUser, repoStore, accumulatedService)
+
+	return &PointTransferController{
+		service: service,
+	}
+}
+
+// TransferPoints handles POST requests to move points between two users of a store
+// @Summary Transfer points
+// @Description Move points from one user to another at the same store. The store's fee is charged to the sender on top of the points.
+// @Description Customers can only send their own points; without from_user_id the user of the token is used.
+// @Tags transfers
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param transfer body dtos.PointTransferRequest true "Transfer data"
+// @Router /leal-test/transfers [post]
+func (c *PointTransferController) TransferPoints(ctx *gin.Context) {
+	var transferDTO dtos.PointTransferRequest
+	if err := ctx.ShouldBindJSON(&transferDTO); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	fromUserID, ok := actingUserID(ctx, transferDTO.FromUserID)
+	if !ok {
+		return
+	}
+	transferDTO.FromUserID = fromUserID
+
+	transfer := adapters.ToPointTransferModel(transferDTO)
+	if err := c.service.WithScope(config.ScopeFromContext(ctx)).TransferPoints(transfer); err != nil {
+		switch {
+		case errors.Is(err, services.ErrUserSuspended), errors.Is(err, services.ErrAccountTooNew):
+			ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrTransferDailyCap), errors.Is(err, repository.ErrInsufficientPoints):
+			ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			ctx.JSON(scopedStatus(err, http.StatusBadRequest), gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, adapters.ToPointTransferDTO(transfer))
+}
+
+// GetTransfersByUserAndStore handles GET requests to retrieve the transfers of a user in a store
+// @Summary Get transfers by UserID and StoreID
+// @Description Get the transfers sent or received by a user in a store, newest first
+// @Tags transfers
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param user_id path int true "User ID"
+// @Param store_id path int true "Store ID"
+// @Router /leal-test/transfers/user/{user_id}/store/{store_id} [get]
+func (c *PointTransferController) GetTransfersByUserAndStore(ctx *gin.Context) {
+	userID, err := strconv.Atoi(ctx.Param("user_id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	storeID, err := strconv.Atoi(ctx.Param("store_id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid store ID"})
+		return
+	}
+
+	transfers, err := c.service.WithScope(config.ScopeFromContext(ctx)).GetTransfersByUserAndStore(uint(userID), uint(storeID))
+	if err != nil {
+		ctx.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, adapters.ToPointTransferDTOs(transfers))
+}
@@ -0,0 +1,239 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"leal-technical-test/config"
+	"leal-technical-test/internal/infra/adapters"
+	"leal-technical-test/internal/infra/dtos"
+	"leal-technical-test/internal/infra/repository"
+	"leal-technical-test/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RedemptionController struct
+type RedemptionController struct {
+	service services.RedemptionService
+}
+
+// NewRedemptionController constructor
+func NewRedemptionController() *RedemptionController {
+	db := config.NewPostgresConnection()
+	uow := config.NewUnitOfWork(db)
+	repo := repository.NewRedemptionRepository(db)
+	repoReward := repository.NewRewardRepository(db)
+	repoBranch := repository.NewBranchRepository(db)
+	repoAcumulate := repository.NewAccumulatedRewardRepository(db)
+	repoLedger := repository.NewPointsLedgerRepository(db)
+	repoLot := repository.NewPointsLotRepository(db)
+	repoStore := repository.NewStoreRepository(db)
+	serviceAcumulate := services.NewAccumulatedRewardService(uow, repoAcumulate, repoLedger, repoLot, repoStore)
+	service := services.NewRedemptionService(uow, repo, repoReward, repoBranch, serviceAcumulate)
+
+	return &RedemptionController{
+		service: service,
+	}
+}
+
+// ClaimReward handles POST requests to claim a reward
+// @Summary Claim a reward
+// @Description Reserve a reward for a user and debit its points. Rewards with eligible branches need branch_id.
+// @Description Customers can only claim for themselves; without user_id the user of the token is used.
+// @Tags redemptions
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param claim body dtos.ClaimRewardRequest true "Claim data"
+// @Router /leal-test/rewards/claim [post]
+func (c *RedemptionController) ClaimReward(ctx *gin.Context) {
+	var claimDTO dtos.ClaimRewardRequest
+	if err := ctx.ShouldBindJSON(&claimDTO); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, ok := actingUserID(ctx, claimDTO.UserID)
+	if !ok {
+		return
+	}
+
+	redemption, err := c.service.WithScope(config.ScopeFromContext(ctx)).ClaimReward(userID, claimDTO.RewardID, claimDTO.BranchID)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrInsufficientPoints), errors.Is(err, services.ErrRewardUnavailable):
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, repository.ErrRewardOutOfStock), errors.Is(err, services.ErrRewardLimitReached):
+			ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			ctx.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	redemption, err = c.service.GetRedemptionById(redemption.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusCreated, adapters.ToRedemptionDTO(redemption))
+}
+
+// GetRedemptionById handles GET requests to retrieve a redemption by its ID
+// @Summary Get redemption by ID
+// @Description Get redemption by ID
+// @Tags redemptions
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param id path int true "Redemption ID"
+// @Router /leal-test/redemptions/{id} [get]
+func (c *RedemptionController) GetRedemptionById(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid redemption ID"})
+		return
+	}
+
+	redemption, err := c.service.WithScope(config.ScopeFromContext(ctx)).GetRedemptionById(uint(id))
+	if err != nil {
+		ctx.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, adapters.ToRedemptionDTO(redemption))
+}
+
+// GetRedemptionsByStoreId handles GET requests to list the redemptions of a store
+// @Summary Get redemptions by StoreID
+// @Description List the redemptions of a store. Pending (reserved) redemptions are returned by default.
+// @Tags redemptions
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param store_id path int true "Store ID"
+// @Param status query string false "reserved, fulfilled, cancelled or all"
+// @Router /leal-test/redemptions/store/{store_id} [get]
+func (c *RedemptionController) GetRedemptionsByStoreId(ctx *gin.Context) {
+	storeID, err := strconv.Atoi(ctx.Param("store_id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid store ID"})
+		return
+	}
+
+	status := ctx.DefaultQuery("status", "reserved")
+	if status == "all" {
+		status = ""
+	}
+
+	redemptions, err := c.service.WithScope(config.ScopeFromContext(ctx)).GetRedemptionsByStoreId(uint(storeID), status)
+	if err != nil {
+		ctx.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, adapters.ToRedemptionDTOs(redemptions))
+}
+
+// GetMyRedemptions handles GET requests to list the redemptions of the authenticated user
+// @Summary Get my redemptions
+// @Description List the redemptions of the user in the token, with their voucher codes
+// @Tags me
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Router /leal-test/me/redemptions [get]
+func (c *RedemptionController) GetMyRedemptions(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+	redemptions, err := c.service.GetRedemptionsByUserId(userID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, adapters.ToRedemptionDTOs(redemptions))
+}
+
+// FulfillRedemption handles POST requests to mark a redemption as fulfilled
+// @Summary Fulfill a redemption
+// @Description Mark a reserved redemption as handed over to the customer
+// @Tags redemptions
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param id path int true "Redemption ID"
+// @Router /leal-test/redemptions/{id}/fulfill [post]
+func (c *RedemptionController) FulfillRedemption(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid redemption ID"})
+		return
+	}
+
+	err = c.service.WithScope(config.ScopeFromContext(ctx)).FulfillRedemption(uint(id))
+	if err != nil {
+		ctx.JSON(scopedStatus(err, http.StatusConflict), gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "Redemption fulfilled successfully"})
+}
+
+// UseVoucher handles POST requests from a branch to validate a voucher and mark it as used
+// @Summary Use a voucher at a branch
+// @Description Look up a voucher code, check that the branch belongs to the reward's store and mark the redemption as fulfilled
+// @Tags redemptions
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param id path int true "Branch ID"
+// @Param code path string true "Voucher code"
+// @Router /leal-test/branches/{id}/vouchers/{code}/use [post]
+func (c *RedemptionController) UseVoucher(ctx *gin.Context) {
+	branchID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid branch ID"})
+		return
+	}
+
+	redemption, err := c.service.WithScope(config.ScopeFromContext(ctx)).UseVoucher(uint(branchID), ctx.Param("code"))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrVoucherNotFound):
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrVoucherWrongStore):
+			ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrVoucherNotUsable):
+			ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			ctx.JSON(scopedStatus(err, http.StatusBadRequest), gin.H{"error": err.Error()})
+		}
+		return
+	}
+	ctx.JSON(http.StatusOK, adapters.ToRedemptionDTO(redemption))
+}
+
+// CancelRedemption handles POST requests to cancel a redemption
+// @Summary Cancel a redemption
+// @Description Cancel a reserved redemption and give its points back
+// @Tags redemptions
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param id path int true "Redemption ID"
+// @Router /leal-test/redemptions/{id}/cancel [post]
+func (c *RedemptionController) CancelRedemption(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid redemption ID"})
+		return
+	}
+
+	err = c.service.WithScope(config.ScopeFromContext(ctx)).CancelRedemption(uint(id))
+	if err != nil {
+		ctx.JSON(scopedStatus(err, http.StatusConflict), gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "Redemption cancelled successfully"})
+}
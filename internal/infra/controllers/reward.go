@@ -15,21 +15,18 @@ import (
 
 // RewardController struct
 type RewardController struct {
-	service          services.RewardService
-	serviceAcumulate services.AccumulatedRewardService
+	service services.RewardService
 }
 
 // NewRewardController constructor
 func NewRewardController() *RewardController {
 	db := config.NewPostgresConnection()
 	repo := repository.NewRewardRepository(db)
-	service := services.NewRewardService(repo)
-	repoAcumulate := repository.NewAccumulatedRewardRepository(db)
-	servAcumulate := services.NewAccumulatedRewardService(repoAcumulate)
+	repoBranch := repository.NewBranchRepository(db)
+	service := services.NewRewardService(repo, repoBranch)
 
 	return &RewardController{
-		service:          service,
-		serviceAcumulate: servAcumulate,
+		service: service,
 	}
 }
 
@@ -42,9 +39,9 @@ func NewRewardController() *RewardController {
 // @Security ApiKeyAuth
 // @Router /leal-test/rewards [get]
 func (c *RewardController) GetAllRewards(ctx *gin.Context) {
-	rewards, err := c.service.GetAllRewards()
+	rewards, err := c.service.WithScope(config.ScopeFromContext(ctx)).GetAllRewards()
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 	rewardsDTOs := adapters.ToRewardsDTOs(rewards)
@@ -67,9 +64,9 @@ func (c *RewardController) GetRewardById(ctx *gin.Context) {
 		return
 	}
 
-	reward, err := c.service.GetRewardById(uint(id))
+	reward, err := c.service.WithScope(config.ScopeFromContext(ctx)).GetRewardById(uint(id))
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 	rewardDTO := adapters.ToRewardsDTO(reward)
@@ -92,9 +89,9 @@ func (c *RewardController) GetRewardsByStoreId(ctx *gin.Context) {
 		return
 	}
 
-	rewards, err := c.service.GetRewardsByStoreId(uint(storeID))
+	rewards, err := c.service.WithScope(config.ScopeFromContext(ctx)).GetRewardsByStoreId(uint(storeID))
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 	rewardsDTO := adapters.ToRewardsDTOs(rewards)
@@ -118,9 +115,9 @@ func (c *RewardController) CreateReward(ctx *gin.Context) {
 	}
 
 	reward := adapters.ToRewardModel(rewardDTO)
-	err := c.service.CreateReward(&reward)
+	err := c.service.WithScope(config.ScopeFromContext(ctx)).CreateReward(&reward)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -129,7 +126,7 @@ func (c *RewardController) CreateReward(ctx *gin.Context) {
 
 // UpdateReward handles PUT requests to update a reward
 // @Summary Update a reward
-// @Description Update a reward
+// @Description Update a reward. The stock, limits and availability window are replaced, so omitting them removes them
 // @Tags rewards
 // @Accept  json
 // @Produce  json
@@ -150,9 +147,9 @@ func (c *RewardController) UpdateReward(ctx *gin.Context) {
 	}
 
 	reward := adapters.ToRewardModel(rewardDTO)
-	err = c.service.UpdateReward(uint(id), &reward)
+	err = c.service.WithScope(config.ScopeFromContext(ctx)).UpdateReward(uint(id), &reward)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -173,67 +170,11 @@ func (c *RewardController) DeleteReward(ctx *gin.Context) {
 		return
 	}
 
-	err = c.service.DeleteReward(uint(id))
+	err = c.service.WithScope(config.ScopeFromContext(ctx)).DeleteReward(uint(id))
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 
 	ctx.JSON(http.StatusOK, gin.H{"message": "Reward deleted successfully"})
 }
-
-// GetClaimRewardPoints godoc
-// @Summary Claim reward points
-// @Description Claim reward points for a user at a specific store
-// @Tags rewards
-// @Accept json
-// @Produce json
-// @Security ApiKeyAuth
-// @Param user_id path int true "User ID"
-// @Param reward_id path int true "Reward ID"
-// @Param store_id path int true "Store ID"
-// @Router /leal-test/rewards/claim/{user_id}/{reward_id}/{store_id} [get]
-func (c *RewardController) GetClaimRewardPoints(ctx *gin.Context) {
-	userID, err := strconv.Atoi(ctx.Param("user_id"))
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
-		return
-	}
-	rewardID, err := strconv.Atoi(ctx.Param("reward_id"))
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid reward ID"})
-		return
-	}
-	storeID, err := strconv.Atoi(ctx.Param("store_id"))
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid store ID"})
-		return
-	}
-
-	reward, err := c.service.GetRewardById(uint(rewardID))
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-	acumulatedReward, err := c.serviceAcumulate.GetRewardByUserAndStore(uint(userID), uint(storeID))
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	claimRewardPoints := dtos.ClaimRewardRequest{
-		UserID:            uint(userID),
-		PointsAccumulated: acumulatedReward.PointsAccumulated,
-		RewardID:          uint(rewardID),
-		RewardRequired:    reward.PointsRequired,
-		StoreID:           uint(storeID),
-		Description:       reward.Description,
-	}
-
-	rewardDescription, err := c.serviceAcumulate.ClaimReward(claimRewardPoints)
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-	ctx.JSON(http.StatusOK, gin.H{"message": rewardDescription})
-}
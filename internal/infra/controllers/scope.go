@@ -0,0 +1,17 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"leal-technical-test/internal/services"
+)
+
+// scopedStatus devuelve 403 cuando el error se debe a una tienda fuera del alcance del usuario
+// y el estado indicado en cualquier otro caso
+func scopedStatus(err error, status int) int {
+	if errors.Is(err, services.ErrStoreOutOfScope) {
+		return http.StatusForbidden
+	}
+	return status
+}
@@ -41,9 +41,9 @@ func NewStoreController() *StoreController {
 // @Security ApiKeyAuth
 // @Router /leal-test/stores [get]
 func (c *StoreController) GetAllStores(ctx *gin.Context) {
-	stores, err := c.service.GetAllStores()
+	stores, err := c.service.WithScope(config.ScopeFromContext(ctx)).GetAllStores()
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 	storesDTO := adapters.ToStoreDTOs(stores)
@@ -68,9 +68,9 @@ func (c *StoreController) GetStoreById(ctx *gin.Context) {
 		return
 	}
 
-	store, err := c.service.GetStoreById(uint(id))
+	store, err := c.service.WithScope(config.ScopeFromContext(ctx)).GetStoreById(uint(id))
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 	storeDTO := adapters.ToStoreDTO(*store)
@@ -97,13 +97,13 @@ func (c *StoreController) DeleteStore(ctx *gin.Context) {
 		return
 	}
 
-	err = c.service.DeleteStore(uint(id))
+	err = c.service.WithScope(config.ScopeFromContext(ctx)).DeleteStore(uint(id))
 	if err != nil {
 		if err.Error() == "store not found" {
 			ctx.JSON(http.StatusNotFound, gin.H{"error": "Store not found"})
 			return
 		}
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -143,13 +143,13 @@ func (c *StoreController) UpdateStore(ctx *gin.Context) {
 	storeData := adapters.ToStoreModel(storeDTO)
 
 	// Llamar al servicio para actualizar la tienda
-	err = c.service.UpdateStore(uint(id), &storeData)
+	err = c.service.WithScope(config.ScopeFromContext(ctx)).UpdateStore(uint(id), &storeData)
 	if err != nil {
 		if err.Error() == "store not found" {
 			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 			return
 		}
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -175,9 +175,9 @@ func (c *StoreController) CreateStore(ctx *gin.Context) {
 		return
 	}
 
-	err := c.service.CreateStore(&store)
+	err := c.service.WithScope(config.ScopeFromContext(ctx)).CreateStore(&store)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 	ctx.JSON(http.StatusCreated, gin.H{"message": "Store created successfully"})
@@ -0,0 +1,178 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"leal-technical-test/config"
+	"leal-technical-test/internal/infra/adapters"
+	"leal-technical-test/internal/infra/dtos"
+	"leal-technical-test/internal/infra/repository"
+	"leal-technical-test/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TierController struct
+type TierController struct {
+	service services.TierService
+}
+
+// NewTierController constructor
+func NewTierController() *TierController {
+	db := config.NewPostgresConnection()
+	uow := config.NewUnitOfWork(db)
+	repo := repository.NewTierRepository(db)
+	repoStore := repository.NewStoreRepository(db)
+	repoTransaction := repository.NewTransactionRepository(db)
+	service := services.NewTierService(uow, repo, repoStore, repoTransaction)
+
+	return &TierController{
+		service: service,
+	}
+}
+
+// GetTiersByStoreId handles GET requests to retrieve the tiers of a store
+// @Summary Get tiers by StoreID
+// @Description Get the membership tiers of a store from the lowest to the highest rank
+// @Tags tiers
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param store_id path int true "Store ID"
+// @Router /leal-test/tiers/store/{store_id} [get]
+func (c *TierController) GetTiersByStoreId(ctx *gin.Context) {
+	storeID, err := strconv.Atoi(ctx.Param("store_id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid store ID"})
+		return
+	}
+
+	tiers, err := c.service.WithScope(config.ScopeFromContext(ctx)).GetTiersByStoreId(uint(storeID))
+	if err != nil {
+		ctx.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, adapters.ToTierDTOs(tiers))
+}
+
+// CreateTier handles POST requests to create a new tier
+// @Summary Create a new tier
+// @Description Create a membership tier with a spend or points threshold and an earn multiplier
+// @Tags tiers
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param tier body dtos.TierRequest true "Tier data"
+// @Router /leal-test/tiers [post]
+func (c *TierController) CreateTier(ctx *gin.Context) {
+	var tierDTO dtos.TierRequest
+	if err := ctx.ShouldBindJSON(&tierDTO); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tier := adapters.ToTierModel(tierDTO)
+
+	err := c.service.WithScope(config.ScopeFromContext(ctx)).CreateTier(&tier)
+	if err != nil {
+		ctx.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Tier created successfully"})
+}
+
+// UpdateTier handles PUT requests to update a tier
+// @Summary Update a tier
+// @Description Update a tier
+// @Tags tiers
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param id path int true "Tier ID"
+// @Param tier body dtos.TierRequest true "Tier data"
+// @Router /leal-test/tiers/{id} [put]
+func (c *TierController) UpdateTier(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tier ID"})
+		return
+	}
+
+	var tierDTO dtos.TierRequest
+	if err := ctx.ShouldBindJSON(&tierDTO); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	tier := adapters.ToTierModel(tierDTO)
+
+	err = c.service.WithScope(config.ScopeFromContext(ctx)).UpdateTier(uint(id), &tier)
+	if err != nil {
+		ctx.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Tier updated successfully"})
+}
+
+// DeleteTier handles DELETE requests to delete a tier by ID
+// @Summary Delete tier by ID
+// @Description Delete a tier by ID. Tiers that are still the current tier of some users can't be deleted
+// @Tags tiers
+// @Security ApiKeyAuth
+// @Param id path int true "Tier ID"
+// @Router /leal-test/tiers/{id} [delete]
+func (c *TierController) DeleteTier(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tier ID"})
+		return
+	}
+
+	err = c.service.WithScope(config.ScopeFromContext(ctx)).DeleteTier(uint(id))
+	if err != nil {
+		if errors.Is(err, services.ErrTierHasMembers) {
+			ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Tier deleted successfully"})
+}
+
+// GetTierProgress handles GET requests to retrieve the tier progress of a user in a store
+// @Summary Get tier progress by UserID and StoreID
+// @Description Get the current tier, what is still needed for the next one and the history of tier changes
+// @Tags tiers
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param user_id path int true "User ID"
+// @Param store_id path int true "Store ID"
+// @Router /leal-test/tiers/user/{user_id}/store/{store_id}/progress [get]
+func (c *TierController) GetTierProgress(ctx *gin.Context) {
+	userID, err := strconv.Atoi(ctx.Param("user_id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	storeID, err := strconv.Atoi(ctx.Param("store_id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid store ID"})
+		return
+	}
+
+	progress, err := c.service.WithScope(config.ScopeFromContext(ctx)).GetProgress(uint(userID), uint(storeID))
+	if err != nil {
+		ctx.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, adapters.ToTierProgressDTO(progress))
+}
@@ -1,8 +1,10 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
+	"time"
 
 	"leal-technical-test/config"
 	"leal-technical-test/internal/infra/adapters"
@@ -15,23 +17,28 @@ import (
 
 // TransactionController struct
 type TransactionController struct {
-	service          services.TransactionService
-	serviceAcumulate services.AccumulatedRewardService
+	service services.TransactionService
 }
 
 // NewTransactionController constructor
 func NewTransactionController() *TransactionController {
 	db := config.NewPostgresConnection()
+	uow := config.NewUnitOfWork(db)
 	repo := repository.NewTransactionRepository(db)
 	repobranch := repository.NewBranchRepository(db)
 	repoCampaign := repository.NewCampaignRepository(db)
-	service := services.NewTransactionService(repo, repobranch, repoCampaign)
+	repoKey := repository.NewIdempotencyKeyRepository(db)
+	repoRefund := repository.NewRefundRepository(db)
+	repoTier := repository.NewTierRepository(db)
 	repoAcumulate := repository.NewAccumulatedRewardRepository(db)
-	serviceAcumulate := services.NewAccumulatedRewardService(repoAcumulate)
+	repoLedger := repository.NewPointsLedgerRepository(db)
+	repoLot := repository.NewPointsLotRepository(db)
+	repoStore := repository.NewStoreRepository(db)
+	serviceAcumulate := services.NewAccumulatedRewardService(uow, repoAcumulate, repoLedger, repoLot, repoStore)
+	service := services.NewTransactionService(uow, repo, repobranch, repoCampaign, repoKey, repoRefund, repoTier, serviceAcumulate)
 
 	return &TransactionController{
-		service:          service,
-		serviceAcumulate: serviceAcumulate,
+		service: service,
 	}
 }
 
@@ -44,9 +51,9 @@ func NewTransactionController() *TransactionController {
 // @Security ApiKeyAuth
 // @Router /leal-test/transactions [get]
 func (c *TransactionController) GetAllTransactions(ctx *gin.Context) {
-	transactions, err := c.service.GetAllTransactions()
+	transactions, err := c.service.WithScope(config.ScopeFromContext(ctx)).GetAllTransactions()
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 	transactionsDTOs := adapters.ToTransactionDTOs(transactions)
@@ -70,9 +77,9 @@ func (c *TransactionController) GetTransactionById(ctx *gin.Context) {
 		return
 	}
 
-	transaction, err := c.service.GetTransactionById(uint(id))
+	transaction, err := c.service.WithScope(config.ScopeFromContext(ctx)).GetTransactionById(uint(id))
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 	transactionDTOs := adapters.ToTransactionDTO(transaction)
@@ -96,9 +103,9 @@ func (c *TransactionController) GetTransactionsByUserId(ctx *gin.Context) {
 		return
 	}
 
-	transactions, err := c.service.GetTransactionsByUserId(uint(userID))
+	transactions, err := c.service.WithScope(config.ScopeFromContext(ctx)).GetTransactionsByUserId(uint(userID))
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 	transactionsDTOs := adapters.ToTransactionDTOs(transactions)
@@ -106,13 +113,37 @@ func (c *TransactionController) GetTransactionsByUserId(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, transactionsDTOs)
 }
 
+// GetMyTransactions handles GET requests to retrieve the transactions of the authenticated user
+// @Summary Get my transactions
+// @Description Get the transactions of the user in the token
+// @Tags me
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Router /leal-test/me/transactions [get]
+func (c *TransactionController) GetMyTransactions(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+	transactions, err := c.service.GetTransactionsByUserId(userID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, adapters.ToTransactionDTOs(transactions))
+}
+
 // CreateTransaction handles POST requests to create a new transaction
 // @Summary Create a new transaction
-// @Description Create a new transaction
+// @Description Create a new transaction. Retries with the same Idempotency-Key header or the same
+// @Description external receipt ID of the branch return the original response. Part of the amount can be
+// @Description paid with the cashback of the user in the store.
 // @Tags transactions
 // @Accept  json
 // @Produce  json
 // @Security ApiKeyAuth
+// @Param Idempotency-Key header string false "Idempotency key"
 // @Param transaction body dtos.TransactionRequest true "Transaction data"
 // @Router /leal-test/transactions [post]
 func (c *TransactionController) CreateTransaction(ctx *gin.Context) {
@@ -123,15 +154,139 @@ func (c *TransactionController) CreateTransaction(ctx *gin.Context) {
 		return
 	}
 
+	idempotencyKey := ctx.GetHeader("Idempotency-Key")
+	if len(idempotencyKey) > 100 || len(transactionDTO.ExternalReceiptID) > 100 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Idempotency key and receipt ID must not exceed 100 characters"})
+		return
+	}
+
 	transaction := adapters.ToTransactionModel(transactionDTO)
-	transaction, storeId, err := c.service.CreateTransaction(transaction)
+	transaction, replayed, err := c.service.WithScope(config.ScopeFromContext(ctx)).CreateTransaction(transaction, idempotencyKey)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		switch {
+		case errors.Is(err, services.ErrIdempotencyConflict), errors.Is(err, repository.ErrInsufficientCashback):
+			ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrInvalidCashbackPayment):
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			ctx.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		}
 		return
 	}
-	err = c.serviceAcumulate.CreateReward(storeId, transaction)
+	if replayed {
+		ctx.Header("Idempotent-Replayed", "true")
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"point":            transaction.PointsEarned,
+		"cashback":         transaction.CashbackEarned,
+		"cashback_applied": transaction.CashbackApplied,
+		"net_amount":       transaction.NetAmount,
+	})
+}
+
+// PreviewTransaction handles POST requests to calculate the reward of a purchase without recording it
+// @Summary Preview the reward of a purchase
+// @Description Calculate the points or cashback a purchase would earn, with the same campaigns, tiers and caps
+// @Description as a real purchase. Nothing is saved and no campaign budget is consumed. Customers can only
+// @Description preview their own purchases; user_id defaults to the token user for them.
+// @Tags transactions
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param preview body dtos.TransactionPreviewRequest true "Purchase data"
+// @Router /leal-test/transactions/preview [post]
+func (c *TransactionController) PreviewTransaction(ctx *gin.Context) {
+	var previewDTO dtos.TransactionPreviewRequest
+	if err := ctx.ShouldBindJSON(&previewDTO); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	userID, ok := actingUserID(ctx, previewDTO.UserID)
+	if !ok {
+		return
+	}
+	previewDTO.UserID = userID
+
+	date := time.Now()
+	if previewDTO.Date != nil {
+		date = *previewDTO.Date
+	}
+
+	transaction, err := c.service.WithScope(config.ScopeFromContext(ctx)).PreviewTransaction(adapters.ToTransactionPreviewModel(previewDTO), date)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		if errors.Is(err, services.ErrInvalidCashbackPayment) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
 	}
-	ctx.JSON(http.StatusOK, gin.H{"point": transaction.PointsEarned})
+	ctx.JSON(http.StatusOK, adapters.ToTransactionPreviewDTO(transaction, date))
+}
+
+// RefundTransaction handles POST requests to refund all or part of a transaction
+// @Summary Refund a transaction
+// @Description Refund part of a purchase, or everything not yet refunded when amount is omitted.
+// @Description The proportional points and cashback are removed following the negative balance policy of the store.
+// @Tags transactions
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param id path int true "Transaction ID"
+// @Param refund body dtos.RefundRequest false "Refund data"
+// @Router /leal-test/transactions/{id}/refund [post]
+func (c *TransactionController) RefundTransaction(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid transaction ID"})
+		return
+	}
+
+	var refundDTO dtos.RefundRequest
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(&refundDTO); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	refund, err := c.service.WithScope(config.ScopeFromContext(ctx)).RefundTransaction(uint(id), refundDTO.Amount, refundDTO.Reason)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrRefundExceedsAmount):
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, repository.ErrInsufficientPoints), errors.Is(err, repository.ErrInsufficientCashback):
+			ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			ctx.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, adapters.ToRefundDTO(refund))
+}
+
+// GetRefundsByTransactionId handles GET requests to retrieve the refunds of a transaction
+// @Summary Get refunds of a transaction
+// @Description Get the refunds linked to a transaction
+// @Tags transactions
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param id path int true "Transaction ID"
+// @Router /leal-test/transactions/{id}/refunds [get]
+func (c *TransactionController) GetRefundsByTransactionId(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid transaction ID"})
+		return
+	}
+
+	refunds, err := c.service.WithScope(config.ScopeFromContext(ctx)).GetRefundsByTransactionId(uint(id))
+	if err != nil {
+		ctx.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, adapters.ToRefundDTOs(refunds))
 }
@@ -1,11 +1,11 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
 	"leal-technical-test/config"
-	"leal-technical-test/internal/domain/models"
 	"leal-technical-test/internal/infra/adapters"
 	"leal-technical-test/internal/infra/dtos"
 	"leal-technical-test/internal/infra/repository"
@@ -16,17 +16,24 @@ import (
 
 // UserController struct
 type UserController struct {
-	service services.UserService
+	service  services.UserService
+	sessions services.SessionService
 }
 
 // NewUserController constructor
 func NewUserController() *UserController {
 	db := config.NewPostgresConnection()
+	uow := config.NewUnitOfWork(db)
 	repo := repository.NewUserRepository(db)
-	service := services.NewUserService(repo)
+	repoStore := repository.NewStoreRepository(db)
+	repoRefresh := repository.NewRefreshTokenRepository(db)
+	repoRevoked := repository.NewRevokedTokenRepository(db)
+	sessions := services.NewSessionService(uow, repoRefresh, repoRevoked, repo, config.NewTokenManager())
+	service := services.NewUserService(repo, repoStore, sessions)
 
 	return &UserController{
-		service: service,
+		service:  service,
+		sessions: sessions,
 	}
 }
 
@@ -39,12 +46,12 @@ func NewUserController() *UserController {
 // @Security ApiKeyAuth
 // @Router /leal-test/users [get]
 func (c *UserController) GetAllUsers(ctx *gin.Context) {
-	users, err := c.service.GetAllUsers()
+	users, err := c.service.WithScope(config.ScopeFromContext(ctx)).GetAllUsers()
 
 	userDTO := adapters.ToUserDTOs(users)
 
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 	ctx.JSON(http.StatusOK, gin.H{"users": userDTO})
@@ -65,15 +72,36 @@ func (c *UserController) GetUserById(ctx *gin.Context) {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
 		return
 	}
-	user, err := c.service.GetUserById(uint(id))
+	user, err := c.service.WithScope(config.ScopeFromContext(ctx)).GetUserById(uint(id))
 	userDTO := adapters.ToUserDTO(user)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.JSON(scopedStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 	ctx.JSON(http.StatusOK, userDTO)
 }
 
+// GetMe godoc
+// @Summary Get the authenticated user
+// @Description Get the user identified by the token
+// @Tags me
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Router /leal-test/me [get]
+func (c *UserController) GetMe(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+	user, err := c.service.GetUserById(userID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, adapters.ToUserDTO(user))
+}
+
 // DeleteUser godoc
 // @Summary Delete user by ID
 // @Description Delete user by ID
@@ -132,9 +160,103 @@ func (c *UserController) UpdateUser(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, gin.H{"message": "User updated successfully"})
 }
 
+// SetUserSuspension godoc
+// @Summary Suspend or reactivate user
+// @Description Suspended users can't transfer points
+// @Tags users
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param id path int true "User ID"
+// @Param suspension body dtos.UserSuspensionRequest true "Suspension state"
+// @Router /leal-test/users/{id}/suspension [put]
+func (c *UserController) SetUserSuspension(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var suspensionDTO dtos.UserSuspensionRequest
+	if err := ctx.ShouldBindJSON(&suspensionDTO); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+
+	if err := c.service.SetSuspended(uint(id), suspensionDTO.Suspended); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "User suspension updated successfully"})
+}
+
+// SetUserRole godoc
+// @Summary Change user role
+// @Description Change the role that decides which routes a user can call
+// @Tags users
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param id path int true "User ID"
+// @Param role body dtos.UserRoleRequest true "Role"
+// @Router /leal-test/users/{id}/role [put]
+func (c *UserController) SetUserRole(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var roleDTO dtos.UserRoleRequest
+	if err := ctx.ShouldBindJSON(&roleDTO); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+
+	if err := c.service.SetRole(uint(id), roleDTO.Role); err != nil {
+		if errors.Is(err, services.ErrInvalidRole) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "User role updated successfully"})
+}
+
+// SetUserStores godoc
+// @Summary Link user to stores
+// @Description Replace the stores a manager or cashier works at. Store staff can only reach the resources of their stores.
+// @Tags users
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param id path int true "User ID"
+// @Param stores body dtos.UserStoresRequest true "Store IDs"
+// @Router /leal-test/users/{id}/stores [put]
+func (c *UserController) SetUserStores(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var storesDTO dtos.UserStoresRequest
+	if err := ctx.ShouldBindJSON(&storesDTO); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+
+	if err := c.service.SetStores(uint(id), storesDTO.StoreIDs); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "User stores updated successfully"})
+}
+
 // CreateUser godoc
 // @Summary Create user
-// @Description Create user
+// @Description Create a user with the customer role
 // @Tags users
 // @Accept  json
 // @Produce  json
@@ -142,12 +264,13 @@ func (c *UserController) UpdateUser(ctx *gin.Context) {
 // @Param user body dtos.UserRequest true "User to create"
 // @Router /leal-test/users [post]
 func (c *UserController) CreateUser(ctx *gin.Context) {
-	var user models.User
-	if err := ctx.ShouldBindJSON(&user); err != nil {
+	var userDTO dtos.UserRequest
+	if err := ctx.ShouldBindJSON(&userDTO); err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
 		return
 	}
 
+	user := adapters.ToUserModel(userDTO)
 	err := c.service.CreateUser(&user)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -174,11 +297,73 @@ func (ctrl *UserController) Login(c *gin.Context) {
 		return
 	}
 
-	token, err := ctrl.service.Login(loginData.Email, loginData.Password)
+	pair, err := ctrl.service.Login(loginData.Email, loginData.Password)
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"token": token})
+	c.JSON(http.StatusOK, adapters.ToTokenDTO(pair))
+}
+
+// Refresh handles token refresh
+// @Summary Refresh tokens
+// @Description Exchange a refresh token for a new access token and refresh token. Each refresh token
+// @Description can be used once; reusing one revokes the whole session.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param refresh body dtos.RefreshRequest true "Refresh token"
+// @Router /leal-test/refresh [post]
+func (ctrl *UserController) Refresh(c *gin.Context) {
+	var request dtos.RefreshRequest
+	if err := c.ShouldBindJSON(&request); err != nil || request.RefreshToken == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "refresh_token is required"})
+		return
+	}
+
+	pair, err := ctrl.sessions.Refresh(request.RefreshToken)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidRefreshToken), errors.Is(err, services.ErrRefreshTokenReused):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, adapters.ToTokenDTO(pair))
+}
+
+// Logout handles user logout
+// @Summary Logout user
+// @Description Revoke the access token of the request and the session of the refresh token, if sent
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param logout body dtos.RefreshRequest false "Refresh token of the session"
+// @Router /leal-test/logout [post]
+func (ctrl *UserController) Logout(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	// El cuerpo es opcional: sin token de refresco solo se revoca el token de acceso
+	var request dtos.RefreshRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if err := ctrl.sessions.Logout(userID, request.RefreshToken, c.GetString("token_id")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
 }
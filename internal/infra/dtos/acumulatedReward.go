@@ -1,5 +1,7 @@
 package dtos
 
+import "time"
+
 type AccumulatedRewardResponse struct {
 	Id                  uint    `json:"id"`
 	UserID              uint    `json:"user_id"`
@@ -15,3 +17,52 @@ type AccumulatedRewardRequest struct {
 	PointsAccumulated   float64 `json:"points_accumulated"`
 	CashbackAccumulated float64 `json:"cashback_accumulated"`
 }
+
+type LedgerEntryResponse struct {
+	Id              uint      `json:"id"`
+	EntryType       string    `json:"entry_type"`
+	Points          float64   `json:"points"`
+	RunningBalance  float64   `json:"running_balance"`
+	Cashback        float64   `json:"cashback"`
+	RunningCashback float64   `json:"running_cashback"`
+	TransactionID   *uint     `json:"transaction_id"`
+	RedemptionID    *uint     `json:"redemption_id"`
+	TransferID      *uint     `json:"transfer_id"`
+	ExchangeID      *uint     `json:"exchange_id"`
+	Description     string    `json:"description"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+type LedgerResponse struct {
+	UserID          uint                  `json:"user_id"`
+	StoreID         uint                  `json:"store_id"`
+	Balance         float64               `json:"balance"`
+	CashbackBalance float64               `json:"cashback_balance"`
+	Entries         []LedgerEntryResponse `json:"entries"`
+}
+
+type RedeemCashbackRequest struct {
+	Amount      float64 `json:"amount"`
+	Description string  `json:"description"`
+}
+
+type AdjustPointsRequest struct {
+	Points      float64 `json:"points"`
+	Description string  `json:"description"`
+}
+
+type PointsLotResponse struct {
+	Id        uint       `json:"id"`
+	Points    float64    `json:"points"`
+	Remaining float64    `json:"remaining"`
+	EarnedAt  time.Time  `json:"earned_at"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type ExpiringPointsResponse struct {
+	UserID      uint                `json:"user_id"`
+	StoreID     uint                `json:"store_id"`
+	Days        int                 `json:"days"`
+	TotalPoints float64             `json:"total_points"`
+	Lots        []PointsLotResponse `json:"lots"`
+}
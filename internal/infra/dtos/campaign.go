@@ -3,21 +3,39 @@ package dtos
 import "time"
 
 type CampaignResponse struct {
-	Id         uint      `json:"id"`
-	Name       string    `json:"name"`
-	BranchID   uint      `json:"branch_id"`
-	Branch     string    `json:"branch"`
-	Type       string    `json:"type"`
-	Percentage float64   `json:"percentage"`
-	StartDate  time.Time `json:"start_date"`
-	EndDate    time.Time `json:"end_date" `
+	Id                uint      `json:"id"`
+	Name              string    `json:"name"`
+	BranchID          uint      `json:"branch_id"`
+	Branch            string    `json:"branch"`
+	Type              string    `json:"type"`
+	Percentage        float64   `json:"percentage"`
+	Multiplier        float64   `json:"multiplier"`
+	BonusPoints       float64   `json:"bonus_points"`
+	MinAmount         float64   `json:"min_amount"`
+	Priority          int       `json:"priority"`
+	Exclusive         bool      `json:"exclusive"`
+	BudgetPoints      float64   `json:"budget_points"` // 0 means no budget
+	BudgetUsed        float64   `json:"budget_used"`
+	RemainingBudget   *float64  `json:"remaining_budget"`    // Null when the campaign has no budget
+	PerCustomerCap    float64   `json:"per_customer_cap"`    // 0 means no cap
+	PerTransactionCap float64   `json:"per_transaction_cap"` // 0 means no cap
+	StartDate         time.Time `json:"start_date"`
+	EndDate           time.Time `json:"end_date" `
 }
 
 type CampaignRequest struct {
-	Name       string    `json:"name"`
-	BranchID   uint      `json:"branch_id"`
-	Type       string    `json:"type"`
-	Percentage float64   `json:"percentage"`
-	StartDate  time.Time `json:"start_date"`
-	EndDate    time.Time `json:"end_date" `
+	Name              string    `json:"name"`
+	BranchID          uint      `json:"branch_id"`
+	Type              string    `json:"type"`
+	Percentage        float64   `json:"percentage"`
+	Multiplier        float64   `json:"multiplier"`
+	BonusPoints       float64   `json:"bonus_points"`
+	MinAmount         float64   `json:"min_amount"`
+	Priority          int       `json:"priority"`
+	Exclusive         bool      `json:"exclusive"`
+	BudgetPoints      float64   `json:"budget_points"`       // Total bonus points the campaign can issue, 0 means no budget
+	PerCustomerCap    float64   `json:"per_customer_cap"`    // Bonus points a customer can receive in total, 0 means no cap
+	PerTransactionCap float64   `json:"per_transaction_cap"` // Bonus points a single purchase can receive, 0 means no cap
+	StartDate         time.Time `json:"start_date"`
+	EndDate           time.Time `json:"end_date" `
 }
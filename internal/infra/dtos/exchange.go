@@ -0,0 +1,53 @@
+package dtos
+
+import "time"
+
+type ExchangeRateRequest struct {
+	FromStoreID          uint    `json:"from_store_id"`
+	ToStoreID            uint    `json:"to_store_id"`
+	Rate                 float64 `json:"rate"`                    // Points of to_store_id given per point of from_store_id
+	MaxPointsPerExchange float64 `json:"max_points_per_exchange"` // 0 means no limit
+	DailyCap             float64 `json:"daily_cap"`               // Points of from_store_id converted per day by every customer, 0 means no cap
+	Active               *bool   `json:"active"`                  // true when omitted
+}
+
+type ExchangeRateResponse struct {
+	Id                   uint    `json:"id"`
+	FromStoreID          uint    `json:"from_store_id"`
+	FromStore            string  `json:"from_store"`
+	ToStoreID            uint    `json:"to_store_id"`
+	ToStore              string  `json:"to_store"`
+	Rate                 float64 `json:"rate"`
+	MaxPointsPerExchange float64 `json:"max_points_per_exchange"`
+	DailyCap             float64 `json:"daily_cap"`
+	Active               bool    `json:"active"`
+}
+
+type PointsExchangeRequest struct {
+	UserID      uint    `json:"user_id"`
+	FromStoreID uint    `json:"from_store_id"`
+	ToStoreID   uint    `json:"to_store_id"`
+	Points      float64 `json:"points"` // Points taken from the from_store_id balance
+}
+
+type PointsExchangeResponse struct {
+	Id              uint      `json:"id"`
+	UserID          uint      `json:"user_id"`
+	FromStoreID     uint      `json:"from_store_id"`
+	FromStore       string    `json:"from_store"`
+	ToStoreID       uint      `json:"to_store_id"`
+	ToStore         string    `json:"to_store"`
+	Rate            float64   `json:"rate"`
+	PointsDebited   float64   `json:"points_debited"`
+	PointsCredited  float64   `json:"points_credited"`
+	SettlementValue float64   `json:"settlement_value"` // Owed by from_store_id to to_store_id
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+type StoreSettlementResponse struct {
+	StoreID        uint    `json:"store_id"`
+	CounterpartyID uint    `json:"counterparty_id"`
+	Receivable     float64 `json:"receivable"`
+	Payable        float64 `json:"payable"`
+	Balance        float64 `json:"balance"` // Positive when the counterparty owes the store
+}
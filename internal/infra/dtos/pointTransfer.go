@@ -0,0 +1,24 @@
+package dtos
+
+import "time"
+
+type PointTransferRequest struct {
+	FromUserID uint    `json:"from_user_id"`
+	ToUserID   uint    `json:"to_user_id"`
+	StoreID    uint    `json:"store_id"`
+	Points     float64 `json:"points"` // Points received by to_user_id; the fee is charged on top
+	Note       string  `json:"note"`
+}
+
+type PointTransferResponse struct {
+	Id         uint      `json:"id"`
+	FromUserID uint      `json:"from_user_id"`
+	FromUser   string    `json:"from_user"`
+	ToUserID   uint      `json:"to_user_id"`
+	ToUser     string    `json:"to_user"`
+	StoreID    uint      `json:"store_id"`
+	Points     float64   `json:"points"`
+	Fee        float64   `json:"fee"`
+	Note       string    `json:"note"`
+	CreatedAt  time.Time `json:"created_at"`
+}
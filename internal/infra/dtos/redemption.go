@@ -0,0 +1,22 @@
+package dtos
+
+import "time"
+
+type RedemptionResponse struct {
+	Id             uint       `json:"id"`
+	UserID         uint       `json:"user_id"`
+	User           string     `json:"user"`
+	StoreID        uint       `json:"store_id"`
+	Store          string     `json:"store"`
+	RewardID       uint       `json:"reward_id"`
+	Reward         string     `json:"reward"`
+	BranchID       *uint      `json:"branch_id"`
+	PointsSpent    float64    `json:"points_spent"`
+	Status         string     `json:"status"` // reserved, fulfilled, cancelled or expired
+	VoucherCode    *string    `json:"voucher_code"`
+	ExpiresAt      *time.Time `json:"expires_at"`
+	UsedAtBranchID *uint      `json:"used_at_branch_id"`
+	CreatedAt      time.Time  `json:"created_at"`
+	FulfilledAt    *time.Time `json:"fulfilled_at"`
+	CancelledAt    *time.Time `json:"cancelled_at"`
+}
@@ -1,24 +1,37 @@
 package dtos
 
+import "time"
+
 type RewardResponse struct {
-	Id             uint    `json:"id"`
-	StoreID        uint    `json:"store_id"`
-	Store          string  `json:"store"`
-	Description    string  `json:"description"`
-	PointsRequired float64 `json:"points_required" `
+	Id             uint       `json:"id"`
+	StoreID        uint       `json:"store_id"`
+	Store          string     `json:"store"`
+	Description    string     `json:"description"`
+	PointsRequired float64    `json:"points_required" `
+	Stock          *int       `json:"stock"` // null means unlimited
+	PerUserLimit   int        `json:"per_user_limit"`
+	PeriodLimit    int        `json:"period_limit"`
+	PeriodDays     int        `json:"period_days"`
+	StartsAt       *time.Time `json:"starts_at"`
+	EndsAt         *time.Time `json:"ends_at"`
+	BranchIDs      []uint     `json:"branch_ids"` // Empty means every branch of the store
 }
 
 type RewardRequest struct {
-	StoreID        uint    `json:"store_id"`
-	Description    string  `json:"description"`
-	PointsRequired float64 `json:"points_required" `
+	StoreID        uint       `json:"store_id"`
+	Description    string     `json:"description"`
+	PointsRequired float64    `json:"points_required" `
+	Stock          *int       `json:"stock"`          // Omit for unlimited stock
+	PerUserLimit   int        `json:"per_user_limit"` // 0 means no limit
+	PeriodLimit    int        `json:"period_limit"`   // Claims per user every period_days, 0 means no limit
+	PeriodDays     int        `json:"period_days"`
+	StartsAt       *time.Time `json:"starts_at"`
+	EndsAt         *time.Time `json:"ends_at"`
+	BranchIDs      []uint     `json:"branch_ids"` // Omit to keep the current branches, [] for every branch
 }
 
 type ClaimRewardRequest struct {
-	UserID            uint    `json:"user_id"`
-	PointsAccumulated float64 `json:"points_accumulated"`
-	RewardID          uint    `json:"reward_id"`
-	RewardRequired    float64 `json:"reward_required"`
-	StoreID           uint    `json:"store_id"`
-	Description       string  `json:"description"`
+	UserID   uint  `json:"user_id"`
+	RewardID uint  `json:"reward_id"`
+	BranchID *uint `json:"branch_id"` // Required when the reward is only offered at some branches
 }
@@ -1,12 +1,38 @@
 package dtos
 
 type StoreResponse struct {
-	ID               uint    `json:"id"`
-	Name             string  `json:"name"`
-	ConversionFactor float64 `json:"conversion_factor"`
+	ID                     uint    `json:"id"`
+	Name                   string  `json:"name"`
+	ConversionFactor       float64 `json:"conversion_factor"`
+	CashbackPercentage     float64 `json:"cashback_percentage"`
+	CashbackPaymentEarns   bool    `json:"cashback_payment_earns"`   // Whether the part paid with cashback earns rewards
+	StackingPolicy         string  `json:"stacking_policy"`          // best_of, additive, multiplicative or priority
+	PointsExpirationMonths int     `json:"points_expiration_months"` // 0 means points never expire
+	NegativeBalancePolicy  string  `json:"negative_balance_policy"`  // allow_debt, clamp or reject
+	TierWindowDays         int     `json:"tier_window_days"`         // Rolling window used to qualify for tiers
+	TierGraceDays          int     `json:"tier_grace_days"`          // Days a user keeps a tier after no longer qualifying
+	TransferMinPoints      float64 `json:"transfer_min_points"`
+	TransferMaxPoints      float64 `json:"transfer_max_points"`       // 0 means no limit
+	TransferDailyCap       float64 `json:"transfer_daily_cap"`        // Points a user can send per day, 0 means no cap
+	TransferFeePercentage  float64 `json:"transfer_fee_percentage"`   // Charged to the sender on top of the points
+	TransferMinAccountDays int     `json:"transfer_min_account_days"` // Age an account needs before it can transfer
+	VoucherValidityDays    int     `json:"voucher_validity_days"`     // Days a redemption voucher can be used
 }
 
 type StoreRequest struct {
-	Name             string  `json:"name"`
-	ConversionFactor float64 `json:"conversion_factor"`
+	Name                   string  `json:"name"`
+	ConversionFactor       float64 `json:"conversion_factor"`
+	CashbackPercentage     float64 `json:"cashback_percentage"`
+	CashbackPaymentEarns   bool    `json:"cashback_payment_earns"`   // Whether the part paid with cashback earns rewards
+	StackingPolicy         string  `json:"stacking_policy"`          // best_of, additive, multiplicative or priority
+	PointsExpirationMonths int     `json:"points_expiration_months"` // 0 means points never expire
+	NegativeBalancePolicy  string  `json:"negative_balance_policy"`  // allow_debt, clamp or reject
+	TierWindowDays         int     `json:"tier_window_days"`         // Rolling window used to qualify for tiers
+	TierGraceDays          int     `json:"tier_grace_days"`          // Days a user keeps a tier after no longer qualifying
+	TransferMinPoints      float64 `json:"transfer_min_points"`
+	TransferMaxPoints      float64 `json:"transfer_max_points"`       // 0 means no limit
+	TransferDailyCap       float64 `json:"transfer_daily_cap"`        // Points a user can send per day, 0 means no cap
+	TransferFeePercentage  float64 `json:"transfer_fee_percentage"`   // Charged to the sender on top of the points
+	TransferMinAccountDays int     `json:"transfer_min_account_days"` // Age an account needs before it can transfer
+	VoucherValidityDays    int     `json:"voucher_validity_days"`     // Days a redemption voucher can be used
 }
@@ -0,0 +1,44 @@
+package dtos
+
+import "time"
+
+type TierResponse struct {
+	ID             uint    `json:"id"`
+	StoreID        uint    `json:"store_id"`
+	Name           string  `json:"name"`
+	Rank           int     `json:"rank"`
+	ThresholdType  string  `json:"threshold_type"`
+	Threshold      float64 `json:"threshold"`
+	EarnMultiplier float64 `json:"earn_multiplier"`
+}
+
+type TierRequest struct {
+	StoreID        uint    `json:"store_id"`
+	Name           string  `json:"name"`
+	Rank           int     `json:"rank"`           // Higher ranks are better levels
+	ThresholdType  string  `json:"threshold_type"` // spend or points
+	Threshold      float64 `json:"threshold"`
+	EarnMultiplier float64 `json:"earn_multiplier"`
+}
+
+type TierHistoryResponse struct {
+	FromTier  string    `json:"from_tier"`
+	ToTier    string    `json:"to_tier"`
+	Reason    string    `json:"reason"`
+	Spend     float64   `json:"spend"`
+	Points    float64   `json:"points"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type TierProgressResponse struct {
+	UserID      uint                  `json:"user_id"`
+	StoreID     uint                  `json:"store_id"`
+	WindowDays  int                   `json:"window_days"`
+	Spend       float64               `json:"spend"`
+	Points      float64               `json:"points"`
+	CurrentTier *TierResponse         `json:"current_tier"`
+	NextTier    *TierResponse         `json:"next_tier"`
+	Remaining   float64               `json:"remaining"` // Spend or points still needed for the next tier
+	DowngradeAt *time.Time            `json:"downgrade_at"`
+	History     []TierHistoryResponse `json:"history"`
+}
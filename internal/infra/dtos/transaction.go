@@ -2,19 +2,91 @@ package dtos
 
 import "time"
 
+type AppliedCampaignResponse struct {
+	CampaignID  uint    `json:"campaign_id"`
+	Campaign    string  `json:"campaign"`
+	BonusPoints float64 `json:"bonus_points"`
+}
+
+// EarningBreakdownResponse explica cómo se calculó la recompensa de una transacción
+type EarningBreakdownResponse struct {
+	BasePoints         float64                   `json:"base_points"`
+	ConversionFactor   float64                   `json:"conversion_factor"`
+	CashbackPercentage float64                   `json:"cashback_percentage"`
+	TierID             *uint                     `json:"tier_id"`
+	TierMultiplier     float64                   `json:"tier_multiplier"`
+	TierBonusPoints    float64                   `json:"tier_bonus_points"`
+	AppliedCampaigns   []AppliedCampaignResponse `json:"applied_campaigns"`
+	BonusPoints        float64                   `json:"bonus_points"`
+	CapAdjustment      float64                   `json:"cap_adjustment"`
+	RoundingAdjustment float64                   `json:"rounding_adjustment"`
+}
+
 type TransactionResponse struct {
-	UserID         uint      `json:"user_id"`
-	User           string    `json:"user"`
-	BranchID       uint      `json:"branch_id"`
-	Branch         string    `json:"branch"`
-	Amount         float64   `json:"amount" `
-	Date           time.Time `json:"date" `
-	RewardType     string    `json:"reward_type" `
-	PointsEarned   float64   `json:"points_earned"`
-	CashbackEarned float64   `json:"cashback_earned"`
+	Id                uint                     `json:"id"`
+	UserID            uint                     `json:"user_id"`
+	User              string                   `json:"user"`
+	BranchID          uint                     `json:"branch_id"`
+	Branch            string                   `json:"branch"`
+	Amount            float64                  `json:"amount" `
+	CashbackApplied   float64                  `json:"cashback_applied"`
+	NetAmount         float64                  `json:"net_amount"`
+	Date              time.Time                `json:"date" `
+	RewardType        string                   `json:"reward_type" `
+	ExternalReceiptID *string                  `json:"external_receipt_id"`
+	PointsEarned      float64                  `json:"points_earned"`
+	CashbackEarned    float64                  `json:"cashback_earned"`
+	RefundedAmount    float64                  `json:"refunded_amount"`
+	CashbackReturned  float64                  `json:"cashback_returned"`
+	Breakdown         EarningBreakdownResponse `json:"breakdown"`
 }
 type TransactionRequest struct {
-	UserID   uint    `json:"user_id"`
-	BranchID uint    `json:"branch_id"`
-	Amount   float64 `json:"amount" `
+	UserID            uint    `json:"user_id"`
+	BranchID          uint    `json:"branch_id"`
+	Amount            float64 `json:"amount" `             // Gross amount of the purchase
+	CashbackToApply   float64 `json:"cashback_to_apply"`   // Part of the amount paid with accumulated cashback
+	RewardType        string  `json:"reward_type"`         // points (default) or cashback
+	ExternalReceiptID string  `json:"external_receipt_id"` // Receipt number of the POS, unique per branch
+}
+
+// TransactionPreviewRequest describe una compra para calcular su recompensa sin registrarla
+type TransactionPreviewRequest struct {
+	UserID          uint       `json:"user_id"`
+	BranchID        uint       `json:"branch_id"`
+	Amount          float64    `json:"amount"`
+	CashbackToApply float64    `json:"cashback_to_apply"`
+	RewardType      string     `json:"reward_type"` // points (default) or cashback
+	Date            *time.Time `json:"date"`        // Omit to preview a purchase made now
+}
+
+// TransactionPreviewResponse es la recompensa que obtendría una compra y su desglose
+type TransactionPreviewResponse struct {
+	UserID          uint                     `json:"user_id"`
+	BranchID        uint                     `json:"branch_id"`
+	Amount          float64                  `json:"amount"`
+	CashbackApplied float64                  `json:"cashback_applied"`
+	NetAmount       float64                  `json:"net_amount"`
+	Date            time.Time                `json:"date"`
+	RewardType      string                   `json:"reward_type"`
+	PointsEarned    float64                  `json:"points_earned"`
+	CashbackEarned  float64                  `json:"cashback_earned"`
+	Breakdown       EarningBreakdownResponse `json:"breakdown"`
+}
+
+type RefundRequest struct {
+	Amount float64 `json:"amount"` // Omit or 0 to refund everything not yet refunded
+	Reason string  `json:"reason"`
+}
+
+type RefundResponse struct {
+	Id               uint      `json:"id"`
+	TransactionID    uint      `json:"transaction_id"`
+	Amount           float64   `json:"amount"`
+	PointsReversed   float64   `json:"points_reversed"`
+	CashbackReversed float64   `json:"cashback_reversed"`
+	PointsWaived     float64   `json:"points_waived"`
+	CashbackWaived   float64   `json:"cashback_waived"`
+	CashbackReturned float64   `json:"cashback_returned"`
+	Reason           string    `json:"reason"`
+	CreatedAt        time.Time `json:"created_at"`
 }
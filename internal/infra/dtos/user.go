@@ -1,10 +1,15 @@
 package dtos
 
+import "time"
+
 type UserResponse struct {
-	Id    uint   `json:"id"`
-	Name  string `json:"name"`
-	Email string `json:"email"`
-	Phone string `json:"phone"`
+	Id        uint   `json:"id"`
+	Name      string `json:"name"`
+	Email     string `json:"email"`
+	Phone     string `json:"phone"`
+	Role      string `json:"role"`
+	StoreIDs  []uint `json:"store_ids"` // Stores a manager or cashier works at
+	Suspended bool   `json:"suspended"`
 }
 
 type UserRequest struct {
@@ -18,3 +23,25 @@ type UserLogin struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
 }
+
+type TokenResponse struct {
+	Token        string    `json:"token"` // Access token, sent as the Authorization header
+	RefreshToken string    `json:"refresh_token"`
+	ExpiresAt    time.Time `json:"expires_at"` // Expiration of the access token
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type UserSuspensionRequest struct {
+	Suspended bool `json:"suspended"`
+}
+
+type UserRoleRequest struct {
+	Role string `json:"role"` // platform_admin, store_manager, cashier or customer
+}
+
+type UserStoresRequest struct {
+	StoreIDs []uint `json:"store_ids"`
+}
@@ -1,21 +1,35 @@
 package repository
 
 import (
-	"fmt"
+	"errors"
 	"leal-technical-test/config"
 	"leal-technical-test/internal/domain/models"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// ErrInsufficientPoints is returned when a debit would leave a balance below zero
+var ErrInsufficientPoints = errors.New("insufficient points")
+
+// ErrInsufficientCashback is returned when a debit would leave the cashback balance below zero
+var ErrInsufficientCashback = errors.New("insufficient cashback")
+
 // AccumulatedRewardRepository interface
 type AccumulatedRewardRepository interface {
 	GetAll() ([]models.AccumulatedReward, error)
 	GetById(id uint) (*models.AccumulatedReward, error)
 	GetByUserAndStore(userID uint, storeID uint) (*models.AccumulatedReward, error)
+	GetByUserId(userID uint) ([]models.AccumulatedReward, error)
+	GetByUserAndStoreForUpdate(userID uint, storeID uint) (*models.AccumulatedReward, error)
+	Accrue(userID uint, storeID uint, points float64, cashback float64) error
+	DeductPoints(userID uint, storeID uint, points float64) error
+	DeductCashback(userID uint, storeID uint, amount float64) error
+	Debit(userID uint, storeID uint, points float64, cashback float64) error
 	Delete(id uint) error
-	UpdateAcumulateReward(userId uint, reward *models.AccumulatedReward) error
 	Create(reward *models.AccumulatedReward) error
+	WithTx(tx config.IDatabaseConnection) AccumulatedRewardRepository
 }
 
 // accumulatedRewardRepository struct
@@ -30,6 +44,11 @@ func NewAccumulatedRewardRepository(db config.IDatabaseConnection) AccumulatedRe
 	}
 }
 
+// WithTx returns a copy of the repository bound to the given transaction
+func (r *accumulatedRewardRepository) WithTx(tx config.IDatabaseConnection) AccumulatedRewardRepository {
+	return &accumulatedRewardRepository{db: tx}
+}
+
 // GetAll retrieves all accumulated rewards
 func (r *accumulatedRewardRepository) GetAll() ([]models.AccumulatedReward, error) {
 	var rewards []models.AccumulatedReward
@@ -54,6 +73,20 @@ func (r *accumulatedRewardRepository) GetById(id uint) (*models.AccumulatedRewar
 	return &reward, nil
 }
 
+// GetByUserId retrieves the accumulated rewards of a user in every store
+func (r *accumulatedRewardRepository) GetByUserId(userID uint) ([]models.AccumulatedReward, error) {
+	var rewards []models.AccumulatedReward
+	if err := r.db.GetDB().
+		Preload("User").
+		Preload("Store").
+		Where("user_id = ?", userID).
+		Order("store_id").
+		Find(&rewards).Error; err != nil {
+		return nil, err
+	}
+	return rewards, nil
+}
+
 // GetByUserAndStore retrieves an accumulated reward by UserID and StoreID
 func (r *accumulatedRewardRepository) GetByUserAndStore(userID uint, storeID uint) (*models.AccumulatedReward, error) {
 	var reward models.AccumulatedReward
@@ -65,23 +98,81 @@ func (r *accumulatedRewardRepository) GetByUserAndStore(userID uint, storeID uin
 	return &reward, nil
 }
 
-// Delete deletes an accumulated reward by its ID
-func (r *accumulatedRewardRepository) Delete(id uint) error {
-	if err := r.db.GetDB().Delete(&models.AccumulatedReward{}, id).Error; err != nil {
-		return err
+// GetByUserAndStoreForUpdate retrieves an accumulated reward and locks the row until the
+// surrounding transaction ends (SELECT ... FOR UPDATE). It must be called inside a unit of work.
+func (r *accumulatedRewardRepository) GetByUserAndStoreForUpdate(userID uint, storeID uint) (*models.AccumulatedReward, error) {
+	var reward models.AccumulatedReward
+	if err := r.db.GetDB().
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("user_id = ? AND store_id = ?", userID, storeID).First(&reward).Error; err != nil {
+		return nil, err
+	}
+	return &reward, nil
+}
+
+// Accrue atomically adds points and cashback to the balance of a user in a store. The row is
+// created on first use with an upsert on (user_id, store_id), so duplicates can never appear.
+func (r *accumulatedRewardRepository) Accrue(userID uint, storeID uint, points float64, cashback float64) error {
+	reward := models.AccumulatedReward{
+		UserID:              userID,
+		StoreID:             storeID,
+		PointsAccumulated:   points,
+		CashbackAccumulated: cashback,
+	}
+	return r.db.GetDB().Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}, {Name: "store_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"points_accumulated":   gorm.Expr("accumulated_rewards.points_accumulated + excluded.points_accumulated"),
+			"cashback_accumulated": gorm.Expr("accumulated_rewards.cashback_accumulated + excluded.cashback_accumulated"),
+			"updated_at":           time.Now(),
+			"deleted_at":           nil,
+		}),
+	}).Create(&reward).Error
+}
+
+// DeductPoints atomically decrements the points of a user in a store. The update only
+// applies while the balance covers the amount, so concurrent debits can never overdraw it.
+func (r *accumulatedRewardRepository) DeductPoints(userID uint, storeID uint, points float64) error {
+	result := r.db.GetDB().Model(&models.AccumulatedReward{}).
+		Where("user_id = ? AND store_id = ? AND points_accumulated >= ?", userID, storeID, points).
+		Update("points_accumulated", gorm.Expr("points_accumulated - ?", points))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrInsufficientPoints
 	}
 	return nil
 }
-func (r *accumulatedRewardRepository) UpdateAcumulateReward(userId uint, reward *models.AccumulatedReward) error {
-	var existingReward models.AccumulatedReward
-	if err := r.db.GetDB().Where("user_id = ?", userId).First(&existingReward).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return fmt.Errorf("no record found for user_id: %d", userId)
-		}
-		return err
+
+// DeductCashback atomically decrements the cashback of a user in a store while the balance covers the amount
+func (r *accumulatedRewardRepository) DeductCashback(userID uint, storeID uint, amount float64) error {
+	result := r.db.GetDB().Model(&models.AccumulatedReward{}).
+		Where("user_id = ? AND store_id = ? AND cashback_accumulated >= ?", userID, storeID, amount).
+		Update("cashback_accumulated", gorm.Expr("cashback_accumulated - ?", amount))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrInsufficientCashback
 	}
+	return nil
+}
 
-	if err := r.db.GetDB().Model(&existingReward).Updates(reward).Error; err != nil {
+// Debit atomically decrements points and cashback without checking the balance, so it can go below zero.
+// Callers must lock the balance and decide beforehand how much can be debited.
+func (r *accumulatedRewardRepository) Debit(userID uint, storeID uint, points float64, cashback float64) error {
+	return r.db.GetDB().Model(&models.AccumulatedReward{}).
+		Where("user_id = ? AND store_id = ?", userID, storeID).
+		Updates(map[string]interface{}{
+			"points_accumulated":   gorm.Expr("points_accumulated - ?", points),
+			"cashback_accumulated": gorm.Expr("cashback_accumulated - ?", cashback),
+		}).Error
+}
+
+// Delete deletes an accumulated reward by its ID
+func (r *accumulatedRewardRepository) Delete(id uint) error {
+	if err := r.db.GetDB().Delete(&models.AccumulatedReward{}, id).Error; err != nil {
 		return err
 	}
 	return nil
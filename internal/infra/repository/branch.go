@@ -25,7 +25,7 @@ type branchRepository struct {
 
 // NewBranchRepository constructor
 func NewBranchRepository(db config.IDatabaseConnection) BranchRepository {
-	return &branchRepository{db: config.NewPostgresConnection()}
+	return &branchRepository{db: db}
 }
 
 // GetAll retrieves all branches
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // CampaignRepository interface
@@ -17,9 +18,17 @@ type CampaignRepository interface {
 	Delete(id uint) error
 	Update(id uint, campaign *models.Campaign) error
 	Create(campaign *models.Campaign) error
-	FindByBranchAndDate(branchID uint, date time.Time) (*models.Campaign, error)
+	FindActiveByBranchAndDate(branchID uint, date time.Time) ([]models.Campaign, error)
+	GetByIdForUpdate(id uint) (*models.Campaign, error)
+	SumBonusByUser(campaignID uint, userID uint) (float64, error)
+	ConsumeBudget(id uint, points float64) error
+	ReleaseBonus(applied models.TransactionCampaign, points float64) error
+	WithTx(tx config.IDatabaseConnection) CampaignRepository
 }
 
+// ErrCampaignBudgetExhausted is returned when a campaign has no budget left for more bonus points
+var ErrCampaignBudgetExhausted = errors.New("campaign budget exhausted")
+
 // campaignRepository struct
 type campaignRepository struct {
 	db config.IDatabaseConnection
@@ -32,6 +41,13 @@ func NewCampaignRepository(db config.IDatabaseConnection) CampaignRepository {
 	}
 }
 
+// WithTx returns a copy of the repository bound to the given transaction
+func (r *campaignRepository) WithTx(tx config.IDatabaseConnection) CampaignRepository {
+	return &campaignRepository{
+		db: tx,
+	}
+}
+
 // GetAll retrieves all campaigns
 func (r *campaignRepository) GetAll() ([]models.Campaign, error) {
 	var campaigns []models.Campaign
@@ -66,7 +82,8 @@ func (r *campaignRepository) Delete(id uint) error {
 	return nil
 }
 
-// Update updates an existing campaign
+// Update replaces the settings of an existing campaign, zero values included, so a flag or a limit can
+// be turned off. The budget already used is only changed by ConsumeBudget and ReleaseBonus.
 func (r *campaignRepository) Update(id uint, campaign *models.Campaign) error {
 	// Verificar si la campaña existe
 	var existingCampaign models.Campaign
@@ -78,7 +95,10 @@ func (r *campaignRepository) Update(id uint, campaign *models.Campaign) error {
 	}
 
 	// Actualizar la campaña
-	if err := r.db.GetDB().Model(&existingCampaign).Updates(campaign).Error; err != nil {
+	if err := r.db.GetDB().Model(&existingCampaign).
+		Select("name", "branch_id", "type", "percentage", "multiplier", "bonus_points", "min_amount", "priority", "exclusive",
+			"budget_points", "per_customer_cap", "per_transaction_cap", "start_date", "end_date").
+		Updates(campaign).Error; err != nil {
 		return err
 	}
 	return nil
@@ -92,24 +112,73 @@ func (r *campaignRepository) Create(campaign *models.Campaign) error {
 	return nil
 }
 
-// FindByBranchAndDate busca una campaña para la sucursal y fecha proporcionada.
-func (r *campaignRepository) FindByBranchAndDate(branchID uint, date time.Time) (*models.Campaign, error) {
-	var campaign models.Campaign
-	err := r.db.GetDB().Where("branch_id = ?", branchID).First(&campaign).Error
+// FindActiveByBranchAndDate devuelve todas las campañas de la sucursal vigentes en la fecha
+// proporcionada y con presupuesto disponible, de mayor a menor prioridad.
+func (r *campaignRepository) FindActiveByBranchAndDate(branchID uint, date time.Time) ([]models.Campaign, error) {
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+
+	var campaigns []models.Campaign
+	err := r.db.GetDB().
+		Where("branch_id = ? AND start_date <= ? AND end_date >= ?", branchID, day, day).
+		Where("budget_points = 0 OR budget_used < budget_points").
+		Order("priority DESC, id ASC").
+		Find(&campaigns).Error
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, fmt.Errorf("no campaign found for branch ID %d", branchID)
-		}
 		return nil, err
 	}
+	return campaigns, nil
+}
 
-	err = r.db.GetDB().Where("branch_id = ? AND start_date <= ? AND end_date >= ?", branchID, date, date).First(&campaign).Error
-	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, fmt.Errorf("no campaign found for branch ID %d on date %s", branchID, date)
-		}
+// GetByIdForUpdate retrieves a campaign and locks it until the surrounding transaction ends, so the
+// purchases that apply it consume its budget one at a time. It must be called inside a unit of work.
+func (r *campaignRepository) GetByIdForUpdate(id uint) (*models.Campaign, error) {
+	var campaign models.Campaign
+	if err := r.db.GetDB().
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&campaign, id).Error; err != nil {
 		return nil, err
 	}
-
 	return &campaign, nil
 }
+
+// SumBonusByUser returns the bonus points a user has received from a campaign
+func (r *campaignRepository) SumBonusByUser(campaignID uint, userID uint) (float64, error) {
+	var total float64
+	err := r.db.GetDB().Model(&models.TransactionCampaign{}).
+		Joins("JOIN transactions ON transactions.id = transaction_campaigns.transaction_id AND transactions.deleted_at IS NULL").
+		Where("transaction_campaigns.campaign_id = ? AND transactions.user_id = ?", campaignID, userID).
+		Select("COALESCE(SUM(transaction_campaigns.bonus_points), 0)").
+		Scan(&total).Error
+	if err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+// ConsumeBudget atomically adds issued bonus points to the budget used by a campaign. The update
+// only applies while the campaign has budget left or has no budget at all.
+func (r *campaignRepository) ConsumeBudget(id uint, points float64) error {
+	result := r.db.GetDB().Model(&models.Campaign{}).
+		Where("id = ? AND (budget_points = 0 OR budget_used < budget_points)", id).
+		Update("budget_used", gorm.Expr("budget_used + ?", points))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrCampaignBudgetExhausted
+	}
+	return nil
+}
+
+// ReleaseBonus gives back bonus points of a purchase that was refunded: they return to the budget of the
+// campaign and stop counting toward the per customer cap. The budget used never goes below zero.
+func (r *campaignRepository) ReleaseBonus(applied models.TransactionCampaign, points float64) error {
+	if err := r.db.GetDB().Model(&models.TransactionCampaign{}).
+		Where("id = ?", applied.ID).
+		Update("bonus_points", gorm.Expr("bonus_points - ?", points)).Error; err != nil {
+		return err
+	}
+	return r.db.GetDB().Model(&models.Campaign{}).
+		Where("id = ?", applied.CampaignID).
+		Update("budget_used", gorm.Expr("CASE WHEN budget_used > ? THEN budget_used - ? ELSE 0 END", points, points)).Error
+}
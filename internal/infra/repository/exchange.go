@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"leal-technical-test/config"
+	"leal-technical-test/internal/domain/models"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+// ExchangeRepository interface
+type ExchangeRepository interface {
+	GetRatesByStoreId(storeID uint) ([]models.ExchangeRate, error)
+	GetRateById(id uint) (*models.ExchangeRate, error)
+	GetRateByPairForUpdate(fromStoreID uint, toStoreID uint) (*models.ExchangeRate, error)
+	CreateRate(rate *models.ExchangeRate) error
+	UpdateRate(id uint, rate *models.ExchangeRate) error
+	DeleteRate(id uint) error
+	CreateExchange(exchange *models.PointsExchange) error
+	GetExchangesByUserId(userID uint) ([]models.PointsExchange, error)
+	SumDebitedSince(fromStoreID uint, toStoreID uint, since time.Time) (float64, error)
+	GetSettlementTotals(storeID uint) ([]SettlementTotal, error)
+	WithTx(tx config.IDatabaseConnection) ExchangeRepository
+}
+
+// SettlementTotal is the value converted from one store into another
+type SettlementTotal struct {
+	FromStoreID uint
+	ToStoreID   uint
+	Value       float64
+}
+
+// exchangeRepository struct
+type exchangeRepository struct {
+	db config.IDatabaseConnection
+}
+
+// NewExchangeRepository constructor
+func NewExchangeRepository(db config.IDatabaseConnection) ExchangeRepository {
+	return &exchangeRepository{
+		db: db,
+	}
+}
+
+// WithTx returns a copy of the repository bound to the given transaction
+func (r *exchangeRepository) WithTx(tx config.IDatabaseConnection) ExchangeRepository {
+	return &exchangeRepository{db: tx}
+}
+
+// GetRatesByStoreId retrieves the rates that convert points from or into a store
+func (r *exchangeRepository) GetRatesByStoreId(storeID uint) ([]models.ExchangeRate, error) {
+	var rates []models.ExchangeRate
+	if err := r.db.GetDB().
+		Preload("FromStore").
+		Preload("ToStore").
+		Where("from_store_id = ? OR to_store_id = ?", storeID, storeID).
+		Order("id ASC").
+		Find(&rates).Error; err != nil {
+		return nil, err
+	}
+	return rates, nil
+}
+
+// GetRateById retrieves a rate by its ID
+func (r *exchangeRepository) GetRateById(id uint) (*models.ExchangeRate, error) {
+	var rate models.ExchangeRate
+	if err := r.db.GetDB().
+		Preload("FromStore").
+		Preload("ToStore").
+		First(&rate, id).Error; err != nil {
+		return nil, err
+	}
+	return &rate, nil
+}
+
+// GetRateByPairForUpdate retrieves the rate of a pair of stores and locks it until the surrounding
+// transaction ends, so conversions of the same pair are checked against the daily cap one at a time
+func (r *exchangeRepository) GetRateByPairForUpdate(fromStoreID uint, toStoreID uint) (*models.ExchangeRate, error) {
+	var rate models.ExchangeRate
+	if err := r.db.GetDB().
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("from_store_id = ? AND to_store_id = ?", fromStoreID, toStoreID).
+		First(&rate).Error; err != nil {
+		return nil, err
+	}
+	return &rate, nil
+}
+
+// CreateRate creates a new rate
+func (r *exchangeRepository) CreateRate(rate *models.ExchangeRate) error {
+	if err := r.db.GetDB().Create(rate).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// UpdateRate replaces the terms of a rate. The stores of a rate don't change.
+func (r *exchangeRepository) UpdateRate(id uint, rate *models.ExchangeRate) error {
+	return r.db.GetDB().Model(&models.ExchangeRate{}).
+		Where("id = ?", id).
+		Select("rate", "max_points_per_exchange", "daily_cap", "active").
+		Updates(rate).Error
+}
+
+// DeleteRate deletes a rate by its ID
+func (r *exchangeRepository) DeleteRate(id uint) error {
+	if err := r.db.GetDB().Delete(&models.ExchangeRate{}, id).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// CreateExchange creates a new conversion
+func (r *exchangeRepository) CreateExchange(exchange *models.PointsExchange) error {
+	if err := r.db.GetDB().Create(exchange).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetExchangesByUserId retrieves the conversions of a user, newest first
+func (r *exchangeRepository) GetExchangesByUserId(userID uint) ([]models.PointsExchange, error) {
+	var exchanges []models.PointsExchange
+	if err := r.db.GetDB().
+		Preload("FromStore").
+		Preload("ToStore").
+		Where("user_id = ?", userID).
+		Order("created_at DESC, id DESC").
+		Find(&exchanges).Error; err != nil {
+		return nil, err
+	}
+	return exchanges, nil
+}
+
+// SumDebitedSince sums the points converted from one store into another since the given time
+func (r *exchangeRepository) SumDebitedSince(fromStoreID uint, toStoreID uint, since time.Time) (float64, error) {
+	var total float64
+	if err := r.db.GetDB().Model(&models.PointsExchange{}).
+		Select("COALESCE(SUM(points_debited), 0)").
+		Where("from_store_id = ? AND to_store_id = ? AND created_at >= ?", fromStoreID, toStoreID, since).
+		Scan(&total).Error; err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+// GetSettlementTotals sums the settlement value of the conversions from or into a store, per pair of stores
+func (r *exchangeRepository) GetSettlementTotals(storeID uint) ([]SettlementTotal, error) {
+	var totals []SettlementTotal
+	if err := r.db.GetDB().Model(&models.PointsExchange{}).
+		Select("from_store_id, to_store_id, COALESCE(SUM(settlement_value), 0) AS value").
+		Where("from_store_id = ? OR to_store_id = ?", storeID, storeID).
+		Group("from_store_id, to_store_id").
+		Scan(&totals).Error; err != nil {
+		return nil, err
+	}
+	return totals, nil
+}
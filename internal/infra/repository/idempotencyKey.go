@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"errors"
+	"leal-technical-test/config"
+	"leal-technical-test/internal/domain/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// IdempotencyKeyRepository interface
+type IdempotencyKeyRepository interface {
+	GetByKey(scope string, key string) (*models.IdempotencyKey, error)
+	Create(key *models.IdempotencyKey) error
+	Delete(id uint) error
+	DeleteExpired(now time.Time) (int64, error)
+	WithTx(tx config.IDatabaseConnection) IdempotencyKeyRepository
+}
+
+// idempotencyKeyRepository struct
+type idempotencyKeyRepository struct {
+	db config.IDatabaseConnection
+}
+
+// NewIdempotencyKeyRepository constructor
+func NewIdempotencyKeyRepository(db config.IDatabaseConnection) IdempotencyKeyRepository {
+	return &idempotencyKeyRepository{
+		db: db,
+	}
+}
+
+// WithTx returns a copy of the repository bound to the given transaction
+func (r *idempotencyKeyRepository) WithTx(tx config.IDatabaseConnection) IdempotencyKeyRepository {
+	return &idempotencyKeyRepository{db: tx}
+}
+
+// GetByKey retrieves a stored key by its scope and value. It returns nil when the key was never stored.
+func (r *idempotencyKeyRepository) GetByKey(scope string, key string) (*models.IdempotencyKey, error) {
+	var idempotencyKey models.IdempotencyKey
+	if err := r.db.GetDB().
+		Where("scope = ? AND key = ?", scope, key).
+		First(&idempotencyKey).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &idempotencyKey, nil
+}
+
+// Create stores a new key. The unique index on (scope, key) rejects concurrent duplicates.
+func (r *idempotencyKeyRepository) Create(key *models.IdempotencyKey) error {
+	if err := r.db.GetDB().Create(key).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// Delete permanently deletes a key so its value can be stored again
+func (r *idempotencyKeyRepository) Delete(id uint) error {
+	if err := r.db.GetDB().Unscoped().Delete(&models.IdempotencyKey{}, id).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// DeleteExpired permanently deletes the keys that expired before now and returns how many were removed
+func (r *idempotencyKeyRepository) DeleteExpired(now time.Time) (int64, error) {
+	result := r.db.GetDB().Unscoped().
+		Where("expires_at <= ?", now).
+		Delete(&models.IdempotencyKey{})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"leal-technical-test/config"
+	"leal-technical-test/internal/domain/models"
+	"time"
+)
+
+// PointTransferRepository interface
+type PointTransferRepository interface {
+	GetByUserAndStore(userID uint, storeID uint) ([]models.PointTransfer, error)
+	SumSentSince(userID uint, storeID uint, since time.Time) (float64, error)
+	Create(transfer *models.PointTransfer) error
+	WithTx(tx config.IDatabaseConnection) PointTransferRepository
+}
+
+// pointTransferRepository struct
+type pointTransferRepository struct {
+	db config.IDatabaseConnection
+}
+
+// NewPointTransferRepository constructor
+func NewPointTransferRepository(db config.IDatabaseConnection) PointTransferRepository {
+	return &pointTransferRepository{
+		db: db,
+	}
+}
+
+// WithTx returns a copy of the repository bound to the given transaction
+func (r *pointTransferRepository) WithTx(tx config.IDatabaseConnection) PointTransferRepository {
+	return &pointTransferRepository{db: tx}
+}
+
+// GetByUserAndStore retrieves the transfers sent or received by a user in a store, newest first
+func (r *pointTransferRepository) GetByUserAndStore(userID uint, storeID uint) ([]models.PointTransfer, error) {
+	var transfers []models.PointTransfer
+	if err := r.db.GetDB().
+		Preload("FromUser").
+		Preload("ToUser").
+		Where("store_id = ? AND (from_user_id = ? OR to_user_id = ?)", storeID, userID, userID).
+		Order("created_at DESC, id DESC").
+		Find(&transfers).Error; err != nil {
+		return nil, err
+	}
+	return transfers, nil
+}
+
+// SumSentSince sums the points a user sent in a store since the given time, without the fees
+func (r *pointTransferRepository) SumSentSince(userID uint, storeID uint, since time.Time) (float64, error) {
+	var total float64
+	if err := r.db.GetDB().Model(&models.PointTransfer{}).
+		Select("COALESCE(SUM(points), 0)").
+		Where("from_user_id = ? AND store_id = ? AND created_at >= ?", userID, storeID, since).
+		Scan(&total).Error; err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+// Create creates a new transfer
+func (r *pointTransferRepository) Create(transfer *models.PointTransfer) error {
+	if err := r.db.GetDB().Create(transfer).Error; err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"leal-technical-test/config"
+	"leal-technical-test/internal/domain/models"
+)
+
+// PointsLedgerRepository interface
+type PointsLedgerRepository interface {
+	GetByUserAndStore(userID uint, storeID uint) ([]models.PointsLedgerEntry, error)
+	Create(entry *models.PointsLedgerEntry) error
+	WithTx(tx config.IDatabaseConnection) PointsLedgerRepository
+}
+
+// pointsLedgerRepository struct
+type pointsLedgerRepository struct {
+	db config.IDatabaseConnection
+}
+
+// NewPointsLedgerRepository constructor
+func NewPointsLedgerRepository(db config.IDatabaseConnection) PointsLedgerRepository {
+	return &pointsLedgerRepository{
+		db: db,
+	}
+}
+
+// WithTx returns a copy of the repository bound to the given transaction
+func (r *pointsLedgerRepository) WithTx(tx config.IDatabaseConnection) PointsLedgerRepository {
+	return &pointsLedgerRepository{db: tx}
+}
+
+// GetByUserAndStore retrieves the ledger entries of a user in a store in the order they were written
+func (r *pointsLedgerRepository) GetByUserAndStore(userID uint, storeID uint) ([]models.PointsLedgerEntry, error) {
+	var entries []models.PointsLedgerEntry
+	if err := r.db.GetDB().
+		Where("user_id = ? AND store_id = ?", userID, storeID).
+		Order("id ASC").
+		Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// Create appends a new entry to the ledger. Entries are never updated or deleted.
+func (r *pointsLedgerRepository) Create(entry *models.PointsLedgerEntry) error {
+	if err := r.db.GetDB().Create(entry).Error; err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"leal-technical-test/config"
+	"leal-technical-test/internal/domain/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PointsLotRepository interface
+type PointsLotRepository interface {
+	Create(lot *models.PointsLot) error
+	GetAvailableForUpdate(userID uint, storeID uint) ([]models.PointsLot, error)
+	GetByIdForUpdate(id uint) (*models.PointsLot, error)
+	GetExpiring(userID uint, storeID uint, until time.Time) ([]models.PointsLot, error)
+	GetExpired(now time.Time) ([]models.PointsLot, error)
+	UpdateRemaining(id uint, remaining float64) error
+	Restore(id uint, points float64, now time.Time) (bool, error)
+	CreateConsumption(consumption *models.PointsLotConsumption) error
+	GetConsumptionsByRedemption(redemptionID uint) ([]models.PointsLotConsumption, error)
+	WithTx(tx config.IDatabaseConnection) PointsLotRepository
+}
+
+// pointsLotRepository struct
+type pointsLotRepository struct {
+	db config.IDatabaseConnection
+}
+
+// NewPointsLotRepository constructor
+func NewPointsLotRepository(db config.IDatabaseConnection) PointsLotRepository {
+	return &pointsLotRepository{
+		db: db,
+	}
+}
+
+// WithTx returns a copy of the repository bound to the given transaction
+func (r *pointsLotRepository) WithTx(tx config.IDatabaseConnection) PointsLotRepository {
+	return &pointsLotRepository{db: tx}
+}
+
+// Create creates a new lot
+func (r *pointsLotRepository) Create(lot *models.PointsLot) error {
+	if err := r.db.GetDB().Create(lot).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetAvailableForUpdate retrieves the lots of a user in a store that still have points, oldest first,
+// and locks them until the surrounding transaction ends. It must be called inside a unit of work.
+func (r *pointsLotRepository) GetAvailableForUpdate(userID uint, storeID uint) ([]models.PointsLot, error) {
+	var lots []models.PointsLot
+	if err := r.db.GetDB().
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("user_id = ? AND store_id = ? AND remaining > 0", userID, storeID).
+		Order("earned_at ASC, id ASC").
+		Find(&lots).Error; err != nil {
+		return nil, err
+	}
+	return lots, nil
+}
+
+// GetByIdForUpdate retrieves a lot and locks it until the surrounding transaction ends
+func (r *pointsLotRepository) GetByIdForUpdate(id uint) (*models.PointsLot, error) {
+	var lot models.PointsLot
+	if err := r.db.GetDB().
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&lot, id).Error; err != nil {
+		return nil, err
+	}
+	return &lot, nil
+}
+
+// GetExpiring retrieves the lots of a user in a store with points that expire before until
+func (r *pointsLotRepository) GetExpiring(userID uint, storeID uint, until time.Time) ([]models.PointsLot, error) {
+	var lots []models.PointsLot
+	if err := r.db.GetDB().
+		Where("user_id = ? AND store_id = ? AND remaining > 0 AND expires_at IS NOT NULL AND expires_at <= ?", userID, storeID, until).
+		Order("expires_at ASC, id ASC").
+		Find(&lots).Error; err != nil {
+		return nil, err
+	}
+	return lots, nil
+}
+
+// GetExpired retrieves every lot whose expiry date has passed and that still has points
+func (r *pointsLotRepository) GetExpired(now time.Time) ([]models.PointsLot, error) {
+	var lots []models.PointsLot
+	if err := r.db.GetDB().
+		Where("remaining > 0 AND expires_at IS NOT NULL AND expires_at <= ?", now).
+		Order("expires_at ASC, id ASC").
+		Find(&lots).Error; err != nil {
+		return nil, err
+	}
+	return lots, nil
+}
+
+// UpdateRemaining sets the points left in a lot
+func (r *pointsLotRepository) UpdateRemaining(id uint, remaining float64) error {
+	return r.db.GetDB().Model(&models.PointsLot{}).
+		Where("id = ?", id).
+		Update("remaining", remaining).Error
+}
+
+// Restore atomically gives points back to a lot that has not expired at the given time. It reports
+// whether the lot took the points, so the caller can credit them elsewhere when it had already expired.
+func (r *pointsLotRepository) Restore(id uint, points float64, now time.Time) (bool, error) {
+	result := r.db.GetDB().Model(&models.PointsLot{}).
+		Where("id = ? AND (expires_at IS NULL OR expires_at > ?)", id, now).
+		Update("remaining", gorm.Expr("remaining + ?", points))
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// CreateConsumption records the points a debit took from a lot
+func (r *pointsLotRepository) CreateConsumption(consumption *models.PointsLotConsumption) error {
+	if err := r.db.GetDB().Create(consumption).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetConsumptionsByRedemption retrieves the lots consumed by a redemption
+func (r *pointsLotRepository) GetConsumptionsByRedemption(redemptionID uint) ([]models.PointsLotConsumption, error) {
+	var consumptions []models.PointsLotConsumption
+	if err := r.db.GetDB().
+		Where("redemption_id = ?", redemptionID).
+		Order("id ASC").
+		Find(&consumptions).Error; err != nil {
+		return nil, err
+	}
+	return consumptions, nil
+}
@@ -0,0 +1,182 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"leal-technical-test/config"
+	"leal-technical-test/internal/domain/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RedemptionRepository interface
+type RedemptionRepository interface {
+	GetById(id uint) (*models.Redemption, error)
+	GetByVoucherCode(code string) (*models.Redemption, error)
+	GetExpiredVouchers(now time.Time) ([]models.Redemption, error)
+	GetByStoreId(storeID uint, status string) ([]models.Redemption, error)
+	GetByUserId(userID uint) ([]models.Redemption, error)
+	CountActiveByUserAndReward(userID uint, rewardID uint, since *time.Time) (int64, error)
+	Create(redemption *models.Redemption) error
+	UpdateStatus(id uint, from string, to string) error
+	UseVoucher(id uint, branchID uint, now time.Time) error
+	WithTx(tx config.IDatabaseConnection) RedemptionRepository
+}
+
+// redemptionRepository struct
+type redemptionRepository struct {
+	db config.IDatabaseConnection
+}
+
+// NewRedemptionRepository constructor
+func NewRedemptionRepository(db config.IDatabaseConnection) RedemptionRepository {
+	return &redemptionRepository{
+		db: db,
+	}
+}
+
+// WithTx returns a copy of the repository bound to the given transaction
+func (r *redemptionRepository) WithTx(tx config.IDatabaseConnection) RedemptionRepository {
+	return &redemptionRepository{db: tx}
+}
+
+// GetById retrieves a redemption by its ID
+func (r *redemptionRepository) GetById(id uint) (*models.Redemption, error) {
+	var redemption models.Redemption
+	if err := r.db.GetDB().
+		Preload("User").
+		Preload("Store").
+		Preload("Reward").
+		First(&redemption, id).Error; err != nil {
+		return nil, err
+	}
+	return &redemption, nil
+}
+
+// GetByVoucherCode retrieves a redemption by its voucher code, or nil if no redemption has it
+func (r *redemptionRepository) GetByVoucherCode(code string) (*models.Redemption, error) {
+	var redemption models.Redemption
+	if err := r.db.GetDB().
+		Preload("User").
+		Preload("Store").
+		Preload("Reward.Branches").
+		Where("voucher_code = ?", code).
+		First(&redemption).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &redemption, nil
+}
+
+// GetExpiredVouchers retrieves the reserved redemptions whose voucher expired without being used
+func (r *redemptionRepository) GetExpiredVouchers(now time.Time) ([]models.Redemption, error) {
+	var redemptions []models.Redemption
+	if err := r.db.GetDB().
+		Where("status = ? AND expires_at IS NOT NULL AND expires_at <= ?", models.RedemptionStatusReserved, now).
+		Order("expires_at ASC, id ASC").
+		Find(&redemptions).Error; err != nil {
+		return nil, err
+	}
+	return redemptions, nil
+}
+
+// GetByStoreId retrieves the redemptions of a store, optionally filtered by status
+func (r *redemptionRepository) GetByStoreId(storeID uint, status string) ([]models.Redemption, error) {
+	var redemptions []models.Redemption
+	query := r.db.GetDB().
+		Preload("User").
+		Preload("Store").
+		Preload("Reward").
+		Where("store_id = ?", storeID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if err := query.Order("id ASC").Find(&redemptions).Error; err != nil {
+		return nil, err
+	}
+	return redemptions, nil
+}
+
+// GetByUserId retrieves the redemptions of a user
+func (r *redemptionRepository) GetByUserId(userID uint) ([]models.Redemption, error) {
+	var redemptions []models.Redemption
+	if err := r.db.GetDB().
+		Preload("User").
+		Preload("Store").
+		Preload("Reward").
+		Where("user_id = ?", userID).
+		Order("id ASC").
+		Find(&redemptions).Error; err != nil {
+		return nil, err
+	}
+	return redemptions, nil
+}
+
+// CountActiveByUserAndReward counts the claims of a reward by a user that were not cancelled or
+// expired, since both return the points, only since the given time when it is not nil
+func (r *redemptionRepository) CountActiveByUserAndReward(userID uint, rewardID uint, since *time.Time) (int64, error) {
+	var count int64
+	query := r.db.GetDB().Model(&models.Redemption{}).
+		Where("user_id = ? AND reward_id = ?", userID, rewardID).
+		Where("status NOT IN ?", []string{models.RedemptionStatusCancelled, models.RedemptionStatusExpired})
+	if since != nil {
+		query = query.Where("created_at >= ?", *since)
+	}
+	if err := query.Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// Create creates a new redemption
+func (r *redemptionRepository) Create(redemption *models.Redemption) error {
+	if err := r.db.GetDB().Create(redemption).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// UpdateStatus moves a redemption from one status to another. The update only applies while the
+// redemption is still in the expected status, so two concurrent requests can't both move it.
+func (r *redemptionRepository) UpdateStatus(id uint, from string, to string) error {
+	updates := map[string]interface{}{"status": to}
+	switch to {
+	case models.RedemptionStatusFulfilled:
+		updates["fulfilled_at"] = time.Now()
+	case models.RedemptionStatusCancelled:
+		updates["cancelled_at"] = time.Now()
+	}
+
+	result := r.db.GetDB().Model(&models.Redemption{}).
+		Where("id = ? AND status = ?", id, from).
+		Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("redemption with ID %d is not %s", id, from)
+	}
+	return nil
+}
+
+// UseVoucher marks the voucher of a reserved redemption as used at a branch. Like UpdateStatus, the update
+// only applies while the redemption is reserved and its voucher has not expired, so a voucher is used once.
+func (r *redemptionRepository) UseVoucher(id uint, branchID uint, now time.Time) error {
+	result := r.db.GetDB().Model(&models.Redemption{}).
+		Where("id = ? AND status = ? AND (expires_at IS NULL OR expires_at > ?)", id, models.RedemptionStatusReserved, now).
+		Updates(map[string]interface{}{
+			"status":            models.RedemptionStatusFulfilled,
+			"fulfilled_at":      now,
+			"used_at_branch_id": branchID,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("voucher of redemption %d can't be used", id)
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"errors"
+	"leal-technical-test/config"
+	"leal-technical-test/internal/domain/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RefreshTokenRepository interface
+type RefreshTokenRepository interface {
+	Create(token *models.RefreshToken) error
+	GetByHashForUpdate(hash string) (*models.RefreshToken, error)
+	GetIssuedSinceByFamily(familyID string, since time.Time) ([]models.RefreshToken, error)
+	GetIssuedSinceByUser(userID uint, since time.Time) ([]models.RefreshToken, error)
+	MarkUsed(id uint, now time.Time) (bool, error)
+	RevokeFamily(familyID string, now time.Time) error
+	RevokeByUser(userID uint, now time.Time) error
+	DeleteExpired(now time.Time) (int64, error)
+	WithTx(tx config.IDatabaseConnection) RefreshTokenRepository
+}
+
+// refreshTokenRepository struct
+type refreshTokenRepository struct {
+	db config.IDatabaseConnection
+}
+
+// NewRefreshTokenRepository constructor
+func NewRefreshTokenRepository(db config.IDatabaseConnection) RefreshTokenRepository {
+	return &refreshTokenRepository{
+		db: db,
+	}
+}
+
+// WithTx returns a copy of the repository bound to the given transaction
+func (r *refreshTokenRepository) WithTx(tx config.IDatabaseConnection) RefreshTokenRepository {
+	return &refreshTokenRepository{db: tx}
+}
+
+// Create stores a new refresh token
+func (r *refreshTokenRepository) Create(token *models.RefreshToken) error {
+	if err := r.db.GetDB().Create(token).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetByHashForUpdate retrieves a refresh token by its hash and locks its row until the end of the
+// transaction. It returns nil when no token has that hash.
+func (r *refreshTokenRepository) GetByHashForUpdate(hash string) (*models.RefreshToken, error) {
+	var token models.RefreshToken
+	if err := r.db.GetDB().
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("token_hash = ?", hash).
+		First(&token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+// GetIssuedSinceByFamily retrieves the refresh tokens of a family issued after since
+func (r *refreshTokenRepository) GetIssuedSinceByFamily(familyID string, since time.Time) ([]models.RefreshToken, error) {
+	var tokens []models.RefreshToken
+	if err := r.db.GetDB().
+		Where("family_id = ? AND created_at > ?", familyID, since).
+		Find(&tokens).Error; err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// GetIssuedSinceByUser retrieves the refresh tokens of a user issued after since
+func (r *refreshTokenRepository) GetIssuedSinceByUser(userID uint, since time.Time) ([]models.RefreshToken, error) {
+	var tokens []models.RefreshToken
+	if err := r.db.GetDB().
+		Where("user_id = ? AND created_at > ?", userID, since).
+		Find(&tokens).Error; err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// MarkUsed records that a token was exchanged. It reports false when the token had already been used,
+// so two concurrent refreshes with the same token can't both succeed.
+func (r *refreshTokenRepository) MarkUsed(id uint, now time.Time) (bool, error) {
+	result := r.db.GetDB().Model(&models.RefreshToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", now)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// RevokeFamily revokes every token of a family that is not revoked yet
+func (r *refreshTokenRepository) RevokeFamily(familyID string, now time.Time) error {
+	if err := r.db.GetDB().Model(&models.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", now).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// RevokeByUser revokes every token of a user that is not revoked yet
+func (r *refreshTokenRepository) RevokeByUser(userID uint, now time.Time) error {
+	if err := r.db.GetDB().Model(&models.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", now).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// DeleteExpired permanently deletes the tokens that expired before now and returns how many were removed
+func (r *refreshTokenRepository) DeleteExpired(now time.Time) (int64, error) {
+	result := r.db.GetDB().Unscoped().
+		Where("expires_at <= ?", now).
+		Delete(&models.RefreshToken{})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
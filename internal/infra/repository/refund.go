@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"leal-technical-test/config"
+	"leal-technical-test/internal/domain/models"
+)
+
+// RefundRepository interface
+type RefundRepository interface {
+	GetByTransactionId(transactionID uint) ([]models.Refund, error)
+	Create(refund *models.Refund) error
+	WithTx(tx config.IDatabaseConnection) RefundRepository
+}
+
+// refundRepository struct
+type refundRepository struct {
+	db config.IDatabaseConnection
+}
+
+// NewRefundRepository constructor
+func NewRefundRepository(db config.IDatabaseConnection) RefundRepository {
+	return &refundRepository{
+		db: db,
+	}
+}
+
+// WithTx returns a copy of the repository bound to the given transaction
+func (r *refundRepository) WithTx(tx config.IDatabaseConnection) RefundRepository {
+	return &refundRepository{db: tx}
+}
+
+// GetByTransactionId retrieves the refunds of a transaction in the order they were made
+func (r *refundRepository) GetByTransactionId(transactionID uint) ([]models.Refund, error) {
+	var refunds []models.Refund
+	if err := r.db.GetDB().
+		Where("transaction_id = ?", transactionID).
+		Order("id ASC").
+		Find(&refunds).Error; err != nil {
+		return nil, err
+	}
+	return refunds, nil
+}
+
+// Create creates a new refund
+func (r *refundRepository) Create(refund *models.Refund) error {
+	if err := r.db.GetDB().Create(refund).Error; err != nil {
+		return err
+	}
+	return nil
+}
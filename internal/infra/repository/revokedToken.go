@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"leal-technical-test/config"
+	"leal-technical-test/internal/domain/models"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+// RevokedTokenRepository interface. It is also the revocation list checked by AuthMiddleware.
+type RevokedTokenRepository interface {
+	Revoke(tokenID string, expiresAt time.Time) error
+	IsRevoked(tokenID string) (bool, error)
+	DeleteExpired(now time.Time) (int64, error)
+	WithTx(tx config.IDatabaseConnection) RevokedTokenRepository
+}
+
+// revokedTokenRepository struct
+type revokedTokenRepository struct {
+	db config.IDatabaseConnection
+}
+
+// NewRevokedTokenRepository constructor
+func NewRevokedTokenRepository(db config.IDatabaseConnection) RevokedTokenRepository {
+	return &revokedTokenRepository{
+		db: db,
+	}
+}
+
+// WithTx returns a copy of the repository bound to the given transaction
+func (r *revokedTokenRepository) WithTx(tx config.IDatabaseConnection) RevokedTokenRepository {
+	return &revokedTokenRepository{db: tx}
+}
+
+// Revoke adds an access token ID to the revocation list. Revoking it twice is not an error.
+func (r *revokedTokenRepository) Revoke(tokenID string, expiresAt time.Time) error {
+	token := models.RevokedToken{TokenID: tokenID, ExpiresAt: expiresAt}
+	if err := r.db.GetDB().
+		Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "token_id"}}, DoNothing: true}).
+		Create(&token).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// IsRevoked reports whether an access token ID is in the revocation list
+func (r *revokedTokenRepository) IsRevoked(tokenID string) (bool, error) {
+	var count int64
+	if err := r.db.GetDB().Model(&models.RevokedToken{}).
+		Where("token_id = ?", tokenID).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// DeleteExpired permanently deletes the entries whose token expired before now and returns how many were removed
+func (r *revokedTokenRepository) DeleteExpired(now time.Time) (int64, error) {
+	result := r.db.GetDB().Unscoped().
+		Where("expires_at <= ?", now).
+		Delete(&models.RevokedToken{})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
@@ -1,20 +1,31 @@
 package repository
 
 import (
+	"errors"
 	"fmt"
 	"leal-technical-test/config"
 	"leal-technical-test/internal/domain/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// ErrRewardOutOfStock is returned when a reward has no units left to claim
+var ErrRewardOutOfStock = errors.New("reward is out of stock")
+
 // RewardRepository interface
 type RewardRepository interface {
 	GetAll() ([]models.Reward, error)
 	GetById(id uint) (*models.Reward, error)
+	GetByIdForUpdate(id uint) (*models.Reward, error)
 	GetByStoreId(storeID uint) ([]models.Reward, error)
 	Delete(id uint) error
 	Put(id uint, reward *models.Reward) error
 	Create(reward *models.Reward) error
 	Validate(description string) bool
+	DecrementStock(id uint) error
+	RestoreStock(id uint) error
+	WithTx(tx config.IDatabaseConnection) RewardRepository
 }
 
 // rewardRepository struct
@@ -32,11 +43,17 @@ func NewRewardRepository(db config.IDatabaseConnection) RewardRepository {
 	}
 }
 
+// WithTx returns a copy of the repository bound to the given transaction
+func (r *rewardRepository) WithTx(tx config.IDatabaseConnection) RewardRepository {
+	return &rewardRepository{db: tx, log: r.log}
+}
+
 // GetAll retrieves all rewards
 func (r *rewardRepository) GetAll() ([]models.Reward, error) {
 	var rewards []models.Reward
 	if err := r.db.GetDB().
 		Preload("Store").
+		Preload("Branches").
 		Find(&rewards).Error; err != nil {
 		return nil, err
 	}
@@ -48,6 +65,21 @@ func (r *rewardRepository) GetById(id uint) (*models.Reward, error) {
 	var reward models.Reward
 	if err := r.db.GetDB().
 		Preload("Store").
+		Preload("Branches").
+		First(&reward, id).Error; err != nil {
+		return nil, err
+	}
+	return &reward, nil
+}
+
+// GetByIdForUpdate retrieves a reward and locks it until the surrounding transaction ends, so the
+// claims of a reward check its stock and limits one at a time. It must be called inside a unit of work.
+func (r *rewardRepository) GetByIdForUpdate(id uint) (*models.Reward, error) {
+	var reward models.Reward
+	if err := r.db.GetDB().
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Preload("Store").
+		Preload("Branches").
 		First(&reward, id).Error; err != nil {
 		return nil, err
 	}
@@ -59,6 +91,7 @@ func (r *rewardRepository) GetByStoreId(storeID uint) ([]models.Reward, error) {
 	var rewards []models.Reward
 	if err := r.db.GetDB().
 		Preload("Store").
+		Preload("Branches").
 		Where("store_id = ?", storeID).Find(&rewards).Error; err != nil {
 		return nil, err
 	}
@@ -77,9 +110,13 @@ func (r *rewardRepository) Delete(id uint) error {
 	return nil
 }
 
-// Update updates an existing reward
+// Put replaces the settings of an existing reward, zero and nil values included, so a limit or the
+// availability window can be removed. The eligible branches are replaced only when reward.Branches is not nil.
 func (r *rewardRepository) Put(id uint, reward *models.Reward) error {
-	result := r.db.GetDB().Model(&models.Reward{}).Where("id = ?", id).Updates(reward)
+	result := r.db.GetDB().Model(&models.Reward{}).
+		Where("id = ?", id).
+		Select("store_id", "description", "points_required", "stock", "per_user_limit", "period_limit", "period_days", "starts_at", "ends_at").
+		Updates(reward)
 	if result.Error != nil {
 		return result.Error
 	}
@@ -87,17 +124,45 @@ func (r *rewardRepository) Put(id uint, reward *models.Reward) error {
 		r.log.Error("store not found")
 		return fmt.Errorf("store not found")
 	}
+	if reward.Branches != nil {
+		existing := &models.Reward{Model: gorm.Model{ID: id}}
+		if err := r.db.GetDB().Model(existing).Omit("Branches.*").Association("Branches").Replace(reward.Branches); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-// Create creates a new reward
+// Create creates a new reward. The eligible branches must already exist.
 func (r *rewardRepository) Create(reward *models.Reward) error {
-	if err := r.db.GetDB().Create(reward).Error; err != nil {
+	if err := r.db.GetDB().Omit("Branches.*").Create(reward).Error; err != nil {
 		return err
 	}
 	return nil
 }
 
+// DecrementStock atomically takes one unit of a reward with limited stock. The update only applies
+// while units are left, so the stock can never go below zero.
+func (r *rewardRepository) DecrementStock(id uint) error {
+	result := r.db.GetDB().Model(&models.Reward{}).
+		Where("id = ? AND stock IS NOT NULL AND stock > 0", id).
+		Update("stock", gorm.Expr("stock - 1"))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrRewardOutOfStock
+	}
+	return nil
+}
+
+// RestoreStock gives back one unit to a reward with limited stock. Rewards with unlimited stock are left untouched.
+func (r *rewardRepository) RestoreStock(id uint) error {
+	return r.db.GetDB().Model(&models.Reward{}).
+		Where("id = ? AND stock IS NOT NULL", id).
+		Update("stock", gorm.Expr("stock + 1")).Error
+}
+
 // Validate exist description
 func (r *rewardRepository) Validate(description string) bool {
 	var reward models.Reward
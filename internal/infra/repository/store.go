@@ -13,6 +13,7 @@ type StoreRepository interface {
 	Delete(id uint) error
 	Put(id uint, store *models.Store) error
 	Post(store *models.Store) error
+	WithTx(tx config.IDatabaseConnection) StoreRepository
 }
 
 // storeRepository struct
@@ -29,6 +30,11 @@ func NewStoreRepository(db config.IDatabaseConnection) StoreRepository {
 	}
 }
 
+// WithTx returns a copy of the repository bound to the given transaction
+func (r *storeRepository) WithTx(tx config.IDatabaseConnection) StoreRepository {
+	return &storeRepository{db: tx, log: r.log}
+}
+
 // GetAll retrieves all stores
 func (r *storeRepository) GetAll() ([]models.Store, error) {
 	var stores []models.Store
@@ -65,7 +71,9 @@ func (r *storeRepository) Put(id uint, store *models.Store) error {
 		r.log.Error("store not found")
 		return fmt.Errorf("store not found")
 	}
-	return nil
+	// Updates ignora los campos en cero, así que el indicador se guarda aparte para poder desactivarlo
+	return r.db.GetDB().Model(&models.Store{}).Where("id = ?", id).
+		Update("cashback_payment_earns", store.CashbackPaymentEarns).Error
 }
 
 // Post creates a new store
@@ -0,0 +1,303 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"leal-technical-test/config"
+	"leal-technical-test/internal/domain/models"
+	"leal-technical-test/internal/infra/repository"
+	"leal-technical-test/internal/services"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// setupConcurrentTestDB configura una base SQLite en archivo que admite varias conexiones a la vez.
+// _txlock=immediate hace que cada transacción tome el bloqueo de escritura de toda la base al empezar,
+// así que las transacciones concurrentes se ejecutan una tras otra y SQLite ignora los FOR UPDATE.
+// Las pruebas que la usan son pruebas de humo de la serialización: comprueban que nada se pierde ni
+// se duplica con muchas peticiones en paralelo, pero no ejercitan los bloqueos de fila de Postgres;
+// para eso está setupPostgresTestDB.
+func setupConcurrentTestDB(t *testing.T) *gorm.DB {
+	dsn := fmt.Sprintf("file:%s?_busy_timeout=10000&_txlock=immediate", filepath.Join(t.TempDir(), "stress.db"))
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	err = db.AutoMigrate(&models.User{}, &models.Store{}, &models.AccumulatedReward{}, &models.PointsLedgerEntry{}, &models.PointsLot{}, &models.PointsLotConsumption{})
+	if err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+	return db
+}
+
+// setupPostgresTestDB abre la base Postgres de TEST_POSTGRES_DSN en un esquema propio que se elimina al
+// terminar la prueba. Sin la variable la prueba se omite, así que go test sigue funcionando sin Postgres.
+func setupPostgresTestDB(t *testing.T) *gorm.DB {
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN is not set")
+	}
+	admin, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	name := fmt.Sprintf("test_%d", time.Now().UnixNano())
+	if err := admin.Exec("CREATE SCHEMA " + name).Error; err != nil {
+		t.Fatalf("Failed to create test schema: %v", err)
+	}
+	t.Cleanup(func() {
+		admin.Exec("DROP SCHEMA " + name + " CASCADE")
+	})
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+		NamingStrategy:                           schema.NamingStrategy{TablePrefix: name + "."},
+		DisableForeignKeyConstraintWhenMigrating: true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.AccumulatedReward{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+	return db
+}
+
+func newAccumulatedRewardService(mockDB *MockDBConnection) services.AccumulatedRewardService {
+	return services.NewAccumulatedRewardService(
+		config.NewUnitOfWork(mockDB),
+		repository.NewAccumulatedRewardRepository(mockDB),
+		repository.NewPointsLedgerRepository(mockDB),
+		repository.NewPointsLotRepository(mockDB),
+		repository.NewStoreRepository(mockDB),
+	)
+}
+
+// Prueba de humo: ninguna acumulación se pierde cuando muchas compras llegan en paralelo
+func TestConcurrentAccrualLosesNoUpdates(t *testing.T) {
+	mockDB := &MockDBConnection{DB: setupConcurrentTestDB(t)}
+	service := newAccumulatedRewardService(mockDB)
+	mockDB.DB.Create(&models.Store{Name: "Store"})
+
+	// Sin fila previa: la primera acumulación la crea con un upsert
+	const requests = 50
+	var wg sync.WaitGroup
+	errs := make(chan error, requests)
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			transaction := &models.Transaction{UserID: 1, PointsEarned: 10, CashbackEarned: 1}
+			transaction.ID = uint(i + 1)
+			errs <- service.CreateReward(1, transaction)
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Unexpected accrual error: %v", err)
+		}
+	}
+
+	balance, err := service.GetRewardByUserAndStore(1, 1)
+	if err != nil {
+		t.Fatalf("Failed to read balance: %v", err)
+	}
+	if balance.PointsAccumulated != requests*10 {
+		t.Errorf("Expected %d points, got %f", requests*10, balance.PointsAccumulated)
+	}
+	if balance.CashbackAccumulated != requests {
+		t.Errorf("Expected %d cashback, got %f", requests, balance.CashbackAccumulated)
+	}
+
+	entries, err := service.GetLedger(1, 1)
+	if err != nil {
+		t.Fatalf("Failed to read ledger: %v", err)
+	}
+	if len(entries) != requests {
+		t.Errorf("Expected %d ledger entries, got %d", requests, len(entries))
+	}
+}
+
+// Prueba de humo: un saldo no puede gastarse dos veces con canjes simultáneos
+func TestConcurrentClaimsCannotDoubleSpend(t *testing.T) {
+	mockDB := &MockDBConnection{DB: setupConcurrentTestDB(t)}
+	service := newAccumulatedRewardService(mockDB)
+
+	if err := mockDB.DB.Create(&models.AccumulatedReward{UserID: 1, StoreID: 1, PointsAccumulated: 100}).Error; err != nil {
+		t.Fatalf("Failed to create balance: %v", err)
+	}
+
+	const requests = 20
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded := 0
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := service.RedeemPoints(1, 1, 100, 1, "reward")
+			if err == nil {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			} else if err != repository.ErrInsufficientPoints {
+				t.Errorf("Unexpected claim error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if succeeded != 1 {
+		t.Errorf("Expected exactly one successful claim, got %d", succeeded)
+	}
+	balance, err := service.GetRewardByUserAndStore(1, 1)
+	if err != nil {
+		t.Fatalf("Failed to read balance: %v", err)
+	}
+	if balance.PointsAccumulated != 0 {
+		t.Errorf("Expected balance to be 0, got %f", balance.PointsAccumulated)
+	}
+}
+
+// Prueba que los canjes consumen primero los lotes más antiguos y que el job expira solo el saldo restante
+func TestRedeemConsumesOldestLotsAndExpiresRest(t *testing.T) {
+	mockDB := &MockDBConnection{DB: setupConcurrentTestDB(t)}
+	service := newAccumulatedRewardService(mockDB)
+	mockDB.DB.Create(&models.Store{Name: "Store", PointsExpirationMonths: 12})
+
+	for i, points := range []float64{100, 50} {
+		transaction := &models.Transaction{UserID: 1, PointsEarned: points}
+		transaction.ID = uint(i + 1)
+		if err := service.CreateReward(1, transaction); err != nil {
+			t.Fatalf("Failed to accrue points: %v", err)
+		}
+	}
+	// El primer lote vence antes que el segundo
+	mockDB.DB.Model(&models.PointsLot{}).Where("transaction_id = ?", 1).Update("expires_at", time.Now().Add(-time.Hour))
+
+	if err := service.RedeemPoints(1, 1, 80, 1, "reward"); err != nil {
+		t.Fatalf("Failed to redeem points: %v", err)
+	}
+
+	expired, err := service.ExpirePoints(time.Now())
+	if err != nil {
+		t.Fatalf("Failed to expire points: %v", err)
+	}
+	if expired != 1 {
+		t.Errorf("Expected 1 lot to expire, got %d", expired)
+	}
+
+	// Del primer lote quedaban 20 puntos; el segundo lote sigue intacto
+	balance, err := service.GetRewardByUserAndStore(1, 1)
+	if err != nil {
+		t.Fatalf("Failed to read balance: %v", err)
+	}
+	if balance.PointsAccumulated != 50 {
+		t.Errorf("Expected 50 points after expiry, got %f", balance.PointsAccumulated)
+	}
+
+	expiring, err := service.GetExpiringPoints(1, 1, 400)
+	if err != nil {
+		t.Fatalf("Failed to read expiring points: %v", err)
+	}
+	if len(expiring) != 1 || expiring[0].Remaining != 50 {
+		t.Errorf("Expected the second lot with 50 points to be expiring, got %+v", expiring)
+	}
+}
+
+// Prueba que la rutina de reparación fusiona los saldos duplicados de un mismo usuario y tienda
+func TestMergeDuplicateAccumulatedRewards(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to set up test database: %v", err)
+	}
+	mockDB := &MockDBConnection{DB: db}
+
+	// Simular una base anterior al índice único
+	if err := db.Migrator().DropIndex(&models.AccumulatedReward{}, "idx_accumulated_rewards_user_store"); err != nil {
+		t.Fatalf("Failed to drop unique index: %v", err)
+	}
+	db.Create(&models.AccumulatedReward{UserID: 1, StoreID: 1, PointsAccumulated: 100, CashbackAccumulated: 5})
+	db.Create(&models.AccumulatedReward{UserID: 1, StoreID: 1, PointsAccumulated: 50, CashbackAccumulated: 1})
+	db.Create(&models.AccumulatedReward{UserID: 1, StoreID: 2, PointsAccumulated: 70})
+
+	migrator, err := config.NewMigrator(mockDB)
+	if err != nil {
+		t.Fatalf("Failed to create migrator: %v", err)
+	}
+	removed, err := migrator.MergeDuplicateAccumulatedRewards()
+	if err != nil {
+		t.Fatalf("Failed to merge duplicates: %v", err)
+	}
+	if removed != 1 {
+		t.Errorf("Expected 1 duplicate row to be removed, got %d", removed)
+	}
+
+	repo := repository.NewAccumulatedRewardRepository(mockDB)
+	balance, err := repo.GetByUserAndStore(1, 1)
+	if err != nil {
+		t.Fatalf("Failed to read merged balance: %v", err)
+	}
+	if balance.PointsAccumulated != 150 || balance.CashbackAccumulated != 6 {
+		t.Errorf("Expected 150 points and 6 cashback, got %f and %f", balance.PointsAccumulated, balance.CashbackAccumulated)
+	}
+
+	// El índice único se puede volver a crear sobre los datos reparados
+	if err := db.Migrator().CreateIndex(&models.AccumulatedReward{}, "idx_accumulated_rewards_user_store"); err != nil {
+		t.Errorf("Expected unique index to be created after repair: %v", err)
+	}
+}
+
+// Prueba con Postgres que las acumulaciones y los débitos simultáneos sobre la misma fila no pierden
+// actualizaciones ni dejan el saldo en negativo. Se omite cuando no hay TEST_POSTGRES_DSN.
+func TestPostgresConcurrentAccrueAndDeduct(t *testing.T) {
+	repo := repository.NewAccumulatedRewardRepository(&MockDBConnection{DB: setupPostgresTestDB(t)})
+	if err := repo.Accrue(1, 1, 500, 0); err != nil {
+		t.Fatalf("Failed to create balance: %v", err)
+	}
+
+	// 50 acumulaciones de 10 y 60 débitos de 20: los débitos piden más de lo que llega a haber
+	const accruals, debits = 50, 60
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	deducted := 0
+	for i := 0; i < accruals+debits; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if i < accruals {
+				if err := repo.Accrue(1, 1, 10, 0); err != nil {
+					t.Errorf("Unexpected accrual error: %v", err)
+				}
+				return
+			}
+			err := repo.DeductPoints(1, 1, 20)
+			if err == nil {
+				mu.Lock()
+				deducted++
+				mu.Unlock()
+			} else if !errors.Is(err, repository.ErrInsufficientPoints) {
+				t.Errorf("Unexpected debit error: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	balance, err := repo.GetByUserAndStore(1, 1)
+	if err != nil {
+		t.Fatalf("Failed to read balance: %v", err)
+	}
+	expected := float64(500 + accruals*10 - deducted*20)
+	if balance.PointsAccumulated != expected || balance.PointsAccumulated < 0 {
+		t.Errorf("Expected %f points after %d debits, got %f", expected, deducted, balance.PointsAccumulated)
+	}
+}
@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"errors"
+	"leal-technical-test/config"
+	"leal-technical-test/internal/domain/models"
+	"leal-technical-test/internal/infra/repository"
+	"leal-technical-test/internal/services"
+	"testing"
+)
+
+// Prueba que una conversión entre tiendas aplica la tasa, respeta el tope diario del par y deja la liquidación
+func TestExchangePointsAppliesRateCapAndSettlement(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to set up test database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.PointsLot{}, &models.PointsLotConsumption{}, &models.ExchangeRate{}, &models.PointsExchange{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+	mockDB := &MockDBConnection{DB: db}
+
+	db.Create(&models.User{Name: "Customer", Email: "customer@example.com", Password: "secret"})
+	db.Create(&models.Store{Name: "Store A", ConversionFactor: 2})
+	db.Create(&models.Store{Name: "Store B", ConversionFactor: 1})
+
+	accumulated := newAccumulatedRewardService(mockDB)
+	if err := accumulated.AdjustPoints(1, 1, 400, "opening"); err != nil {
+		t.Fatalf("Failed to credit points: %v", err)
+	}
+	service := services.NewExchangeService(
+		config.NewUnitOfWork(mockDB),
+		repository.NewExchangeRepository(mockDB),
+		repository.NewUserRepository(mockDB),
+		repository.NewStoreRepository(mockDB),
+		accumulated,
+	)
+	if err := service.CreateRate(&models.ExchangeRate{FromStoreID: 1, ToStoreID: 2, Rate: 0.5, DailyCap: 300, Active: true}); err != nil {
+		t.Fatalf("Failed to create rate: %v", err)
+	}
+
+	exchange, err := service.ExchangePoints(1, 1, 2, 200)
+	if err != nil {
+		t.Fatalf("Failed to exchange points: %v", err)
+	}
+	if exchange.PointsCredited != 100 || exchange.SettlementValue != 100 {
+		t.Errorf("Expected 100 points credited and a settlement of 100, got %f and %f", exchange.PointsCredited, exchange.SettlementValue)
+	}
+
+	balances := repository.NewAccumulatedRewardRepository(mockDB)
+	storeA, _ := balances.GetByUserAndStore(1, 1)
+	storeB, _ := balances.GetByUserAndStore(1, 2)
+	if storeA.PointsAccumulated != 200 || storeB.PointsAccumulated != 100 {
+		t.Errorf("Expected 200 and 100 points, got %f and %f", storeA.PointsAccumulated, storeB.PointsAccumulated)
+	}
+
+	// 200 + 150 supera el tope diario de 300 del par
+	if _, err := service.ExchangePoints(1, 1, 2, 150); !errors.Is(err, services.ErrExchangeDailyCap) {
+		t.Errorf("Expected the daily cap to be exceeded, got %v", err)
+	}
+	if _, err := service.ExchangePoints(1, 2, 1, 50); !errors.Is(err, services.ErrExchangeRateNotFound) {
+		t.Errorf("Expected no rate for the reverse pair, got %v", err)
+	}
+
+	settlements, err := service.GetSettlements(2)
+	if err != nil {
+		t.Fatalf("Failed to read settlements: %v", err)
+	}
+	if len(settlements) != 1 || settlements[0].CounterpartyID != 1 || settlements[0].Balance != 100 {
+		t.Errorf("Expected store A to owe 100 to store B, got %+v", settlements)
+	}
+}
+
+// Prueba que un par con la tasa eliminada se puede volver a publicar, pero no tener dos tasas vigentes
+func TestDeletedExchangeRateCanBeRepublished(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to set up test database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.ExchangeRate{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+	mockDB := &MockDBConnection{DB: db}
+
+	db.Create(&models.Store{Name: "Store A", ConversionFactor: 1})
+	db.Create(&models.Store{Name: "Store B", ConversionFactor: 1})
+
+	service := services.NewExchangeService(
+		config.NewUnitOfWork(mockDB),
+		repository.NewExchangeRepository(mockDB),
+		repository.NewUserRepository(mockDB),
+		repository.NewStoreRepository(mockDB),
+		newAccumulatedRewardService(mockDB),
+	)
+	rate := &models.ExchangeRate{FromStoreID: 1, ToStoreID: 2, Rate: 0.5, Active: true}
+	if err := service.CreateRate(rate); err != nil {
+		t.Fatalf("Failed to create rate: %v", err)
+	}
+	if err := service.DeleteRate(rate.ID); err != nil {
+		t.Fatalf("Failed to delete rate: %v", err)
+	}
+
+	if err := service.CreateRate(&models.ExchangeRate{FromStoreID: 1, ToStoreID: 2, Rate: 0.8, Active: true}); err != nil {
+		t.Fatalf("Expected the pair to be published again, got %v", err)
+	}
+	if err := service.CreateRate(&models.ExchangeRate{FromStoreID: 1, ToStoreID: 2, Rate: 0.9, Active: true}); err == nil {
+		t.Errorf("Expected a second live rate for the pair to be rejected")
+	}
+}
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"leal-technical-test/config"
+	"testing"
+)
+
+// privateKeyPEM codifica una clave privada en PEM PKCS#8, como en el archivo de llavero
+func privateKeyPEM(t *testing.T, key interface{}) string {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("Failed to encode private key: %v", err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+}
+
+// Prueba que rotar la clave activa no invalida los tokens de la anterior hasta que se retira, y que el
+// JWKS publica solo las claves públicas que no están retiradas
+func TestKeyringRotationAndJWKS(t *testing.T) {
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate Ed25519 key: %v", err)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	hsKey := config.KeyConfig{ID: "hs-2026-01", Algorithm: config.AlgorithmHS256, Status: config.KeyStatusActive, Secret: "old-secret"}
+	edConfig := config.KeyConfig{ID: "ed-2026-10", Algorithm: config.AlgorithmEdDSA, Status: config.KeyStatusActive, PrivateKey: privateKeyPEM(t, edKey)}
+	rsaConfig := config.KeyConfig{ID: "rsa-2026-10", Algorithm: config.AlgorithmRS256, Status: config.KeyStatusVerify, PrivateKey: privateKeyPEM(t, rsaKey)}
+
+	manager := func(keys ...config.KeyConfig) *config.TokenManager {
+		keyring, err := config.NewKeyring(keys)
+		if err != nil {
+			t.Fatalf("Failed to build keyring: %v", err)
+		}
+		return config.NewTokenManagerWithKeyring(keyring)
+	}
+
+	// Token firmado con la clave HS256 antes de la rotación
+	before := manager(hsKey)
+	oldToken, _, err := before.GenerateToken(1, "John Doe", "customer", nil)
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+
+	// Tras rotar a EdDSA, la clave HS256 solo verifica y los tokens nuevos llevan el kid nuevo
+	hsKey.Status = config.KeyStatusVerify
+	after := manager(edConfig, rsaConfig, hsKey)
+	if _, err := after.ValidateToken(oldToken); err != nil {
+		t.Errorf("Expected the token of the previous key to stay valid, got %v", err)
+	}
+	newToken, _, err := after.GenerateToken(1, "John Doe", "customer", nil)
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+	if _, err := after.ValidateToken(newToken); err != nil {
+		t.Errorf("Expected the EdDSA token to be valid, got %v", err)
+	}
+	if _, err := before.ValidateToken(newToken); !errors.Is(err, config.ErrUnknownKey) {
+		t.Errorf("Expected a keyring without the new kid to reject the token, got %v", err)
+	}
+
+	// Al retirar la clave HS256 sus tokens se rechazan
+	hsKey.Status = config.KeyStatusRetired
+	if _, err := manager(edConfig, rsaConfig, hsKey).ValidateToken(oldToken); !errors.Is(err, config.ErrUnknownKey) {
+		t.Errorf("Expected the retired key to be rejected, got %v", err)
+	}
+
+	// El JWKS tiene las claves EdDSA y RSA, nunca el secreto HS256
+	jwks := after.PublicKeys()
+	if len(jwks.Keys) != 2 || jwks.Keys[0].KeyID != edConfig.ID || jwks.Keys[0].KeyType != "OKP" || jwks.Keys[1].KeyType != "RSA" || jwks.Keys[1].Exponent != "AQAB" {
+		t.Errorf("Expected the Ed25519 and RSA public keys, got %+v", jwks.Keys)
+	}
+
+	// Debe haber exactamente una clave activa
+	if _, err := config.NewKeyring([]config.KeyConfig{rsaConfig}); err == nil {
+		t.Errorf("Expected a keyring without an active key to be rejected")
+	}
+	rsaConfig.Status = config.KeyStatusActive
+	if _, err := config.NewKeyring([]config.KeyConfig{edConfig, rsaConfig}); err == nil {
+		t.Errorf("Expected a keyring with two active keys to be rejected")
+	}
+}
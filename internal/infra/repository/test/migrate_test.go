@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"leal-technical-test/config"
+	"leal-technical-test/internal/domain/models"
+	"leal-technical-test/internal/domain/rules"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// legacyCampaign es la tabla de campañas anterior a los parámetros, cuando 'additional' daba un 30%
+// fijo sobre las compras mayores a 20000
+type legacyCampaign struct {
+	gorm.Model
+	Name       string
+	BranchID   uint
+	Type       string
+	Percentage float64
+	StartDate  time.Time
+	EndDate    time.Time
+}
+
+func (legacyCampaign) TableName() string {
+	return "campaigns"
+}
+
+// Prueba que las campañas 'additional' migradas conservan el límite anterior: una compra de
+// exactamente 20000 no recibe el bono
+func TestLegacyAdditionalCampaignsKeepExclusiveMinimum(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to set up test database: %v", err)
+	}
+	if err := db.AutoMigrate(&legacyCampaign{}); err != nil {
+		t.Fatalf("Failed to create legacy campaigns: %v", err)
+	}
+	db.Create(&legacyCampaign{Name: "Additional", BranchID: 1, Type: rules.TypeAdditional, StartDate: time.Now(), EndDate: time.Now().AddDate(0, 1, 0)})
+
+	migrator, err := config.NewMigrator(&MockDBConnection{DB: db})
+	if err != nil {
+		t.Fatalf("Failed to create migrator: %v", err)
+	}
+	if err := migrator.Migrate(); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+
+	var campaign models.Campaign
+	if err := db.First(&campaign).Error; err != nil {
+		t.Fatalf("Failed to read campaign: %v", err)
+	}
+	cases := []struct {
+		amount   float64
+		expected float64
+	}{
+		{19999.99, 0},
+		{20000, 0},
+		{20000.01, 30},
+	}
+	for _, c := range cases {
+		bonus, err := rules.Evaluate(rules.Context{Amount: c.amount, BasePoints: 100}, campaign)
+		if err != nil {
+			t.Fatalf("Failed to evaluate campaign: %v", err)
+		}
+		if bonus != c.expected {
+			t.Errorf("Expected a bonus of %f for a purchase of %.2f, got %f", c.expected, c.amount, bonus)
+		}
+	}
+}
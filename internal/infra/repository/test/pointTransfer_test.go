@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"errors"
+	"leal-technical-test/config"
+	"leal-technical-test/internal/domain/models"
+	"leal-technical-test/internal/infra/repository"
+	"leal-technical-test/internal/services"
+	"testing"
+	"time"
+)
+
+// Prueba que un traspaso mueve los puntos con su vencimiento, cobra la comisión y respeta los límites de la tienda
+func TestTransferPointsMovesLotsAndEnforcesLimits(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to set up test database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.PointsLot{}, &models.PointsLotConsumption{}, &models.PointTransfer{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+	mockDB := &MockDBConnection{DB: db}
+
+	old := time.Now().AddDate(0, -1, 0)
+	db.Create(&models.User{Name: "Sender", Email: "sender@example.com", Password: "secret"})
+	db.Create(&models.User{Name: "Receiver", Email: "receiver@example.com", Password: "secret"})
+	db.Create(&models.User{Name: "Newcomer", Email: "newcomer@example.com", Password: "secret"})
+	db.Model(&models.User{}).Where("id IN ?", []uint{1, 2}).Update("created_at", old)
+	db.Create(&models.Store{Name: "Store", ConversionFactor: 1, PointsExpirationMonths: 12, TransferMinPoints: 10, TransferDailyCap: 150, TransferFeePercentage: 10, TransferMinAccountDays: 7})
+
+	accumulated := newAccumulatedRewardService(mockDB)
+	if err := accumulated.AdjustPoints(1, 1, 300, "opening"); err != nil {
+		t.Fatalf("Failed to credit points: %v", err)
+	}
+	service := services.NewPointTransferService(
+		config.NewUnitOfWork(mockDB),
+		repository.NewPointTransferRepository(mockDB),
+		repository.NewUserRepository(mockDB),
+		repository.NewStoreRepository(mockDB),
+		accumulated,
+	)
+
+	transfer := &models.PointTransfer{FromUserID: 1, ToUserID: 2, StoreID: 1, Points: 100}
+	if err := service.TransferPoints(transfer); err != nil {
+		t.Fatalf("Failed to transfer points: %v", err)
+	}
+	if transfer.Fee != 10 {
+		t.Errorf("Expected a 10 point fee, got %f", transfer.Fee)
+	}
+
+	balances := repository.NewAccumulatedRewardRepository(mockDB)
+	sender, _ := balances.GetByUserAndStore(1, 1)
+	receiver, _ := balances.GetByUserAndStore(2, 1)
+	if sender.PointsAccumulated != 190 || receiver.PointsAccumulated != 100 {
+		t.Errorf("Expected 190 and 100 points, got %f and %f", sender.PointsAccumulated, receiver.PointsAccumulated)
+	}
+
+	// Los puntos recibidos vencen cuando vencían en el emisor
+	var senderLot, receiverLot models.PointsLot
+	db.Where("user_id = ?", 1).First(&senderLot)
+	db.Where("user_id = ?", 2).First(&receiverLot)
+	if receiverLot.Remaining != 100 || receiverLot.ExpiresAt == nil || !receiverLot.ExpiresAt.Equal(*senderLot.ExpiresAt) {
+		t.Errorf("Expected a 100 point lot expiring with the sender's lot, got %+v", receiverLot)
+	}
+
+	// 100 + 60 supera el tope diario de 150 y no debe mover nada
+	err = service.TransferPoints(&models.PointTransfer{FromUserID: 1, ToUserID: 2, StoreID: 1, Points: 60})
+	if !errors.Is(err, services.ErrTransferDailyCap) {
+		t.Errorf("Expected the daily cap to be exceeded, got %v", err)
+	}
+	sender, _ = balances.GetByUserAndStore(1, 1)
+	if sender.PointsAccumulated != 190 {
+		t.Errorf("Expected the rejected transfer to be rolled back, got %f points", sender.PointsAccumulated)
+	}
+
+	err = service.TransferPoints(&models.PointTransfer{FromUserID: 1, ToUserID: 3, StoreID: 1, Points: 20})
+	if !errors.Is(err, services.ErrAccountTooNew) {
+		t.Errorf("Expected the new account to be rejected, got %v", err)
+	}
+
+	if err := repository.NewUserRepository(mockDB).SetSuspended(2, true); err != nil {
+		t.Fatalf("Failed to suspend user: %v", err)
+	}
+	err = service.TransferPoints(&models.PointTransfer{FromUserID: 1, ToUserID: 2, StoreID: 1, Points: 20})
+	if !errors.Is(err, services.ErrUserSuspended) {
+		t.Errorf("Expected the suspended user to be rejected, got %v", err)
+	}
+
+	ledger, _ := accumulated.GetLedger(1, 1)
+	if len(ledger) != 3 || ledger[1].EntryType != models.LedgerEntryTransferOut || ledger[2].EntryType != models.LedgerEntryTransferFee {
+		t.Errorf("Expected opening, transfer_out and transfer_fee entries, got %+v", ledger)
+	}
+}
@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"leal-technical-test/config"
+	"leal-technical-test/internal/domain/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Prueba que la matriz de permisos deja pasar solo a los roles autorizados y responde 403 al resto
+func TestRequirePermissionEnforcesRoleMatrix(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cases := []struct {
+		role       string
+		permission config.Permission
+		expected   int
+	}{
+		{models.RolePlatformAdmin, config.PermStoresWrite, http.StatusOK},
+		{models.RoleStoreManager, config.PermStoresWrite, http.StatusForbidden},
+		{models.RoleStoreManager, config.PermCampaignsWrite, http.StatusOK},
+		{models.RoleCashier, config.PermTransactionsWrite, http.StatusOK},
+		{models.RoleCashier, config.PermTransactionsRefund, http.StatusForbidden},
+		{models.RoleCustomer, config.PermUsersRead, http.StatusForbidden},
+		{models.RoleCustomer, config.PermTransfersWrite, http.StatusOK},
+		{"", config.PermStoresRead, http.StatusForbidden},
+	}
+	for _, c := range cases {
+		engine := gin.New()
+		engine.GET("/", func(ctx *gin.Context) { ctx.Set("role", c.role) }, config.RequirePermission(c.permission), func(ctx *gin.Context) {
+			ctx.Status(http.StatusOK)
+		})
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+		if recorder.Code != c.expected {
+			t.Errorf("%q with %s: expected %d, got %d", c.role, c.permission, c.expected, recorder.Code)
+		}
+	}
+}
+
+// Prueba que un cliente solo puede pedir los datos de su propio usuario y que el personal no tiene esa restricción
+func TestRequireOwnUserLimitsCustomersToTheirOwnData(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cases := []struct {
+		role     string
+		userID   uint
+		path     string
+		expected int
+	}{
+		{models.RoleCustomer, 7, "/users/7", http.StatusOK},
+		{models.RoleCustomer, 7, "/users/8", http.StatusForbidden},
+		{models.RoleCustomer, 0, "/users/0", http.StatusForbidden},
+		{models.RoleCashier, 7, "/users/8", http.StatusOK},
+		{models.RolePlatformAdmin, 1, "/users/8", http.StatusOK},
+	}
+	for _, c := range cases {
+		engine := gin.New()
+		engine.GET("/users/:user_id", func(ctx *gin.Context) {
+			ctx.Set("role", c.role)
+			ctx.Set("user_id", c.userID)
+		}, config.RequireOwnUser("user_id"), func(ctx *gin.Context) {
+			ctx.Status(http.StatusOK)
+		})
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, c.path, nil))
+		if recorder.Code != c.expected {
+			t.Errorf("%q as user %d on %s: expected %d, got %d", c.role, c.userID, c.path, c.expected, recorder.Code)
+		}
+	}
+}
@@ -0,0 +1,290 @@
+package repository
+
+import (
+	"errors"
+	"leal-technical-test/config"
+	"leal-technical-test/internal/domain/models"
+	"leal-technical-test/internal/infra/repository"
+	"leal-technical-test/internal/services"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Prueba de humo (ver setupConcurrentTestDB): los canjes simultáneos nunca dejan el stock por debajo de
+// cero y cancelar devuelve la unidad
+func TestConcurrentClaimsRespectStockAndLimits(t *testing.T) {
+	db := setupConcurrentTestDB(t)
+	if err := db.AutoMigrate(&models.Branch{}, &models.Reward{}, &models.Redemption{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+	mockDB := &MockDBConnection{DB: db}
+
+	const users = 10
+	for userID := uint(1); userID <= users; userID++ {
+		db.Create(&models.AccumulatedReward{UserID: userID, StoreID: 1, PointsAccumulated: 100})
+	}
+	db.Create(&models.Store{Name: "Store", ConversionFactor: 1})
+	db.Create(&models.Branch{Name: "Downtown", StoreID: 1})
+	db.Create(&models.Branch{Name: "Airport", StoreID: 1})
+
+	stock := 3
+	reward := &models.Reward{StoreID: 1, Description: "Coffee", PointsRequired: 10, Stock: &stock, PerUserLimit: 1}
+	reward.Branches = []models.Branch{{}}
+	reward.Branches[0].ID = 1
+	rewards := services.NewRewardService(repository.NewRewardRepository(mockDB), repository.NewBranchRepository(mockDB))
+	if err := rewards.CreateReward(reward); err != nil {
+		t.Fatalf("Failed to create reward: %v", err)
+	}
+
+	service := services.NewRedemptionService(
+		config.NewUnitOfWork(mockDB),
+		repository.NewRedemptionRepository(mockDB),
+		repository.NewRewardRepository(mockDB),
+		repository.NewBranchRepository(mockDB),
+		newAccumulatedRewardService(mockDB),
+	)
+
+	downtown, airport := uint(1), uint(2)
+	if _, err := service.ClaimReward(1, reward.ID, &airport); !errors.Is(err, services.ErrRewardUnavailable) {
+		t.Errorf("Expected the claim at a non eligible branch to fail, got %v", err)
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var claimed []*models.Redemption
+	for userID := uint(1); userID <= users; userID++ {
+		wg.Add(1)
+		go func(userID uint) {
+			defer wg.Done()
+			redemption, err := service.ClaimReward(userID, reward.ID, &downtown)
+			if err == nil {
+				mu.Lock()
+				claimed = append(claimed, redemption)
+				mu.Unlock()
+			} else if !errors.Is(err, repository.ErrRewardOutOfStock) {
+				t.Errorf("Unexpected claim error: %v", err)
+			}
+		}(userID)
+	}
+	wg.Wait()
+
+	if len(claimed) != stock {
+		t.Fatalf("Expected exactly %d successful claims, got %d", stock, len(claimed))
+	}
+	current, _ := rewards.GetRewardById(reward.ID)
+	if *current.Stock != 0 {
+		t.Errorf("Expected no stock left, got %d", *current.Stock)
+	}
+
+	// Cancelar devuelve la unidad, pero el mismo usuario no puede volver a canjear si ya llegó a su límite
+	if err := service.CancelRedemption(claimed[0].ID); err != nil {
+		t.Fatalf("Failed to cancel redemption: %v", err)
+	}
+	if _, err := service.ClaimReward(claimed[1].UserID, reward.ID, &downtown); !errors.Is(err, services.ErrRewardLimitReached) {
+		t.Errorf("Expected the per user limit to be reached, got %v", err)
+	}
+	if _, err := service.ClaimReward(claimed[0].UserID, reward.ID, &downtown); err != nil {
+		t.Errorf("Expected the restocked unit to be claimable again, got %v", err)
+	}
+}
+
+// Prueba que un voucher solo se usa una vez en una sucursal de su tienda y que el vencido devuelve los puntos
+func TestVoucherIsUsedOnceAndExpiredVouchersReturnPoints(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to set up test database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.Reward{}, &models.Redemption{}, &models.PointsLot{}, &models.PointsLotConsumption{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+	mockDB := &MockDBConnection{DB: db}
+
+	db.Create(&models.Store{Name: "Store", ConversionFactor: 1})
+	db.Create(&models.Store{Name: "Other Store", ConversionFactor: 1})
+	db.Create(&models.Branch{Name: "Branch", StoreID: 1})
+	db.Create(&models.Branch{Name: "Other Branch", StoreID: 2})
+	stock := 5
+	db.Create(&models.Reward{StoreID: 1, Description: "Coffee", PointsRequired: 40, Stock: &stock})
+	accumulated := newAccumulatedRewardService(mockDB)
+	if err := accumulated.AdjustPoints(1, 1, 100, "opening"); err != nil {
+		t.Fatalf("Failed to credit points: %v", err)
+	}
+
+	service := services.NewRedemptionService(
+		config.NewUnitOfWork(mockDB),
+		repository.NewRedemptionRepository(mockDB),
+		repository.NewRewardRepository(mockDB),
+		repository.NewBranchRepository(mockDB),
+		accumulated,
+	)
+
+	redemption, err := service.ClaimReward(1, 1, nil)
+	if err != nil {
+		t.Fatalf("Failed to claim reward: %v", err)
+	}
+	if redemption.VoucherCode == nil || len(*redemption.VoucherCode) != 12 || redemption.ExpiresAt == nil {
+		t.Fatalf("Expected a 12 character voucher with an expiry, got %+v", redemption)
+	}
+	code := *redemption.VoucherCode
+
+	if _, err := service.UseVoucher(2, code); !errors.Is(err, services.ErrVoucherWrongStore) {
+		t.Errorf("Expected the voucher to be rejected at another store, got %v", err)
+	}
+	// El cajero puede escribirlo en minúsculas y con guiones
+	used, err := service.UseVoucher(1, strings.ToLower(code[:6]+"-"+code[6:]))
+	if err != nil {
+		t.Fatalf("Failed to use voucher: %v", err)
+	}
+	if used.Status != models.RedemptionStatusFulfilled || used.UsedAtBranchID == nil || *used.UsedAtBranchID != 1 {
+		t.Errorf("Expected the redemption to be fulfilled at branch 1, got %+v", used)
+	}
+	if _, err := service.UseVoucher(1, code); !errors.Is(err, services.ErrVoucherNotUsable) {
+		t.Errorf("Expected the voucher to be used only once, got %v", err)
+	}
+
+	expiring, err := service.ClaimReward(1, 1, nil)
+	if err != nil {
+		t.Fatalf("Failed to claim reward: %v", err)
+	}
+	db.Model(&models.Redemption{}).Where("id = ?", expiring.ID).Update("expires_at", time.Now().Add(-time.Minute))
+	expired, err := service.ExpireVouchers(time.Now())
+	if err != nil || expired != 1 {
+		t.Fatalf("Expected one voucher to expire, got %d and %v", expired, err)
+	}
+
+	balance, _ := accumulated.GetRewardByUserAndStore(1, 1)
+	if balance.PointsAccumulated != 60 {
+		t.Errorf("Expected the expired voucher to return its points, got %f", balance.PointsAccumulated)
+	}
+	reward, _ := repository.NewRewardRepository(mockDB).GetById(1)
+	if *reward.Stock != 4 {
+		t.Errorf("Expected the expired voucher to return its unit, got %d", *reward.Stock)
+	}
+}
+
+// Prueba que actualizar una recompensa puede quitar el stock, los límites y la ventana, que se valida
+// la recompensa resultante y que los canjes vencidos no cuentan para el límite por usuario
+func TestRewardUpdateClearsLimitsAndExpiredClaimsDontCount(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to set up test database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.Reward{}, &models.Redemption{}, &models.PointsLot{}, &models.PointsLotConsumption{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+	mockDB := &MockDBConnection{DB: db}
+
+	db.Create(&models.Store{Name: "Store", ConversionFactor: 1})
+	accumulated := newAccumulatedRewardService(mockDB)
+	if err := accumulated.AdjustPoints(1, 1, 100, "opening"); err != nil {
+		t.Fatalf("Failed to credit points: %v", err)
+	}
+	rewards := services.NewRewardService(repository.NewRewardRepository(mockDB), repository.NewBranchRepository(mockDB))
+	stock := 5
+	endsAt := time.Now().AddDate(0, 1, 0)
+	if err := rewards.CreateReward(&models.Reward{StoreID: 1, Description: "Coffee", PointsRequired: 10, Stock: &stock, PerUserLimit: 1, EndsAt: &endsAt}); err != nil {
+		t.Fatalf("Failed to create reward: %v", err)
+	}
+	service := services.NewRedemptionService(
+		config.NewUnitOfWork(mockDB),
+		repository.NewRedemptionRepository(mockDB),
+		repository.NewRewardRepository(mockDB),
+		repository.NewBranchRepository(mockDB),
+		accumulated,
+	)
+
+	// Un voucher vencido devuelve los puntos y tampoco ocupa el límite por usuario
+	expiring, err := service.ClaimReward(1, 1, nil)
+	if err != nil {
+		t.Fatalf("Failed to claim reward: %v", err)
+	}
+	db.Model(&models.Redemption{}).Where("id = ?", expiring.ID).Update("expires_at", time.Now().Add(-time.Minute))
+	if expired, err := service.ExpireVouchers(time.Now()); err != nil || expired != 1 {
+		t.Fatalf("Expected one voucher to expire, got %d and %v", expired, err)
+	}
+	if _, err := service.ClaimReward(1, 1, nil); err != nil {
+		t.Fatalf("Expected the expired claim not to count for the limit, got %v", err)
+	}
+	if _, err := service.ClaimReward(1, 1, nil); !errors.Is(err, services.ErrRewardLimitReached) {
+		t.Errorf("Expected the active claim to count for the limit, got %v", err)
+	}
+
+	// La recompensa resultante se valida antes de guardarla
+	if err := rewards.UpdateReward(1, &models.Reward{PeriodLimit: 2}); err == nil {
+		t.Errorf("Expected a period limit without period days to be rejected")
+	}
+
+	// Sin stock, límites ni fin, la recompensa queda ilimitada; la descripción y los puntos se conservan
+	if err := rewards.UpdateReward(1, &models.Reward{}); err != nil {
+		t.Fatalf("Failed to update reward: %v", err)
+	}
+	reward, err := repository.NewRewardRepository(mockDB).GetById(1)
+	if err != nil {
+		t.Fatalf("Failed to get reward: %v", err)
+	}
+	if reward.Stock != nil || reward.PerUserLimit != 0 || reward.EndsAt != nil {
+		t.Errorf("Expected the stock, limit and end to be cleared, got %v, %d and %v", reward.Stock, reward.PerUserLimit, reward.EndsAt)
+	}
+	if reward.Description != "Coffee" || reward.PointsRequired != 10 {
+		t.Errorf("Expected the description and points to be kept, got %q and %f", reward.Description, reward.PointsRequired)
+	}
+	if _, err := service.ClaimReward(1, 1, nil); err != nil {
+		t.Errorf("Expected the claim to succeed without a limit, got %v", err)
+	}
+}
+
+// Prueba que cancelar un canje cuyo lote ya venció devuelve los puntos en un lote nuevo que el job de
+// vencimiento no vuelve a expirar
+func TestCancelledRedemptionOfExpiredLotKeepsPoints(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to set up test database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.Reward{}, &models.Redemption{}, &models.PointsLot{}, &models.PointsLotConsumption{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+	mockDB := &MockDBConnection{DB: db}
+
+	db.Create(&models.Store{Name: "Store", ConversionFactor: 1, PointsExpirationMonths: 6})
+	stock := 5
+	db.Create(&models.Reward{StoreID: 1, Description: "Coffee", PointsRequired: 40, Stock: &stock})
+	accumulated := newAccumulatedRewardService(mockDB)
+	if err := accumulated.AdjustPoints(1, 1, 100, "opening"); err != nil {
+		t.Fatalf("Failed to credit points: %v", err)
+	}
+	service := services.NewRedemptionService(
+		config.NewUnitOfWork(mockDB),
+		repository.NewRedemptionRepository(mockDB),
+		repository.NewRewardRepository(mockDB),
+		repository.NewBranchRepository(mockDB),
+		accumulated,
+	)
+
+	redemption, err := service.ClaimReward(1, 1, nil)
+	if err != nil {
+		t.Fatalf("Failed to claim reward: %v", err)
+	}
+	db.Model(&models.PointsLot{}).Where("id = ?", 1).Update("expires_at", time.Now().Add(-time.Minute))
+	if _, err := accumulated.ExpirePoints(time.Now()); err != nil {
+		t.Fatalf("Failed to expire points: %v", err)
+	}
+
+	if err := service.CancelRedemption(redemption.ID); err != nil {
+		t.Fatalf("Failed to cancel redemption: %v", err)
+	}
+	if _, err := accumulated.ExpirePoints(time.Now()); err != nil {
+		t.Fatalf("Failed to expire points: %v", err)
+	}
+
+	balance, _ := accumulated.GetRewardByUserAndStore(1, 1)
+	if balance.PointsAccumulated != 40 {
+		t.Errorf("Expected the 40 returned points to survive the expiry job, got %f", balance.PointsAccumulated)
+	}
+	var lot models.PointsLot
+	db.Where("id <> ?", 1).First(&lot)
+	if lot.Remaining != 40 || lot.ExpiresAt == nil || !lot.ExpiresAt.After(time.Now()) {
+		t.Errorf("Expected a new lot of 40 points with a fresh expiry, got %+v", lot)
+	}
+}
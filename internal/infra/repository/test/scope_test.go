@@ -0,0 +1,173 @@
+package repository
+
+import (
+	"errors"
+	"leal-technical-test/config"
+	"leal-technical-test/internal/domain/models"
+	"leal-technical-test/internal/infra/repository"
+	"leal-technical-test/internal/services"
+	"testing"
+)
+
+// Prueba que el personal de una tienda no puede leer ni modificar los recursos de otra
+func TestStoreScopeIsEnforcedByServices(t *testing.T) {
+	mockDB := setupTransactionTestDB(t)
+	if err := mockDB.DB.AutoMigrate(&models.Reward{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+	mockDB.DB.Create(&models.Store{Name: "Other store", ConversionFactor: 1})
+	mockDB.DB.Create(&models.Branch{Name: "Other branch", StoreID: 2})
+	scope := config.ScopeFor(models.RoleStoreManager, []uint{1})
+
+	branches := services.NewBranchService(repository.NewBranchRepository(mockDB)).WithScope(scope)
+	visible, err := branches.GetAllBranches()
+	if err != nil {
+		t.Fatalf("Failed to list branches: %v", err)
+	}
+	if len(visible) != 1 || visible[0].StoreID != 1 {
+		t.Errorf("Expected only the branch of store 1, got %+v", visible)
+	}
+
+	_, _, transactionErr := newTransactionService(mockDB).WithScope(scope).CreateTransaction(&models.Transaction{UserID: 1, BranchID: 2, Amount: 100}, "")
+	outOfScope := []struct {
+		name string
+		err  error
+	}{
+		{"update branch", branches.UpdateBranch(&models.Branch{Model: visible[0].Model, Name: "Branch", StoreID: 2})},
+		{"delete branch", branches.DeleteBranch(2)},
+		{"create store", services.NewStoreService(repository.NewStoreRepository(mockDB)).WithScope(scope).CreateStore(&models.Store{Name: "New"})},
+		{"create campaign", services.NewCampaignService(repository.NewCampaignRepository(mockDB), repository.NewBranchRepository(mockDB)).WithScope(scope).
+			CreateCampaign(&models.Campaign{Name: "Double", BranchID: 2, Type: "double"})},
+		{"create reward", services.NewRewardService(repository.NewRewardRepository(mockDB), repository.NewBranchRepository(mockDB)).WithScope(scope).
+			CreateReward(&models.Reward{Description: "Coffee", StoreID: 2, PointsRequired: 10})},
+		{"create transaction", transactionErr},
+	}
+
+	for _, c := range outOfScope {
+		if !errors.Is(c.err, services.ErrStoreOutOfScope) {
+			t.Errorf("%s: expected the store to be out of scope, got %v", c.name, c.err)
+		}
+	}
+
+	// El administrador de la plataforma alcanza todas las tiendas
+	all, err := services.NewBranchService(repository.NewBranchRepository(mockDB)).WithScope(config.ScopeFor(models.RolePlatformAdmin, nil)).GetAllBranches()
+	if err != nil || len(all) != 2 {
+		t.Errorf("Expected the admin to see 2 branches, got %d (err %v)", len(all), err)
+	}
+}
+
+// Prueba que el personal de una tienda no puede operar los canjes, saldos, niveles, conversiones,
+// traspasos ni clientes de otra tienda
+func TestStoreScopeCoversBalancesAndRedemptions(t *testing.T) {
+	mockDB := setupTransactionTestDB(t)
+	if err := mockDB.DB.AutoMigrate(&models.Reward{}, &models.Redemption{}, &models.ExchangeRate{}, &models.PointsExchange{}, &models.PointTransfer{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+	mockDB.DB.Create(&models.Store{Name: "Other store", ConversionFactor: 1})
+	mockDB.DB.Create(&models.Branch{Name: "Other branch", StoreID: 2})
+	mockDB.DB.Create(&models.User{Name: "Other customer", Email: "other@example.com", Password: "secret"})
+	uow := config.NewUnitOfWork(mockDB)
+
+	// Saldos, un canje, un nivel y una tasa de la tienda 2, creados sin restricción
+	accumulated := newAccumulatedRewardService(mockDB)
+	for _, userID := range []uint{1, 2} {
+		if err := accumulated.AdjustPoints(userID, 2, 100, "opening"); err != nil {
+			t.Fatalf("Failed to credit points: %v", err)
+		}
+	}
+	if err := accumulated.AdjustPoints(1, 1, 100, "opening"); err != nil {
+		t.Fatalf("Failed to credit points: %v", err)
+	}
+	reward := &models.Reward{StoreID: 2, Description: "Coffee", PointsRequired: 10}
+	if err := services.NewRewardService(repository.NewRewardRepository(mockDB), repository.NewBranchRepository(mockDB)).CreateReward(reward); err != nil {
+		t.Fatalf("Failed to create reward: %v", err)
+	}
+	redemptions := services.NewRedemptionService(uow, repository.NewRedemptionRepository(mockDB), repository.NewRewardRepository(mockDB), repository.NewBranchRepository(mockDB), accumulated)
+	redemption, err := redemptions.ClaimReward(1, reward.ID, nil)
+	if err != nil {
+		t.Fatalf("Failed to claim reward: %v", err)
+	}
+	tiers := services.NewTierService(uow, repository.NewTierRepository(mockDB), repository.NewStoreRepository(mockDB), repository.NewTransactionRepository(mockDB))
+	tier := &models.Tier{StoreID: 2, Name: "Silver", Rank: 1, ThresholdType: models.TierThresholdSpend, Threshold: 1000}
+	if err := tiers.CreateTier(tier); err != nil {
+		t.Fatalf("Failed to create tier: %v", err)
+	}
+	exchanges := services.NewExchangeService(uow, repository.NewExchangeRepository(mockDB), repository.NewUserRepository(mockDB), repository.NewStoreRepository(mockDB), accumulated)
+	rate := &models.ExchangeRate{FromStoreID: 2, ToStoreID: 1, Rate: 1, Active: true}
+	if err := exchanges.CreateRate(rate); err != nil {
+		t.Fatalf("Failed to create rate: %v", err)
+	}
+	transfers := services.NewPointTransferService(uow, repository.NewPointTransferRepository(mockDB), repository.NewUserRepository(mockDB), repository.NewStoreRepository(mockDB), accumulated)
+	users := services.NewUserService(repository.NewUserRepository(mockDB), repository.NewStoreRepository(mockDB), nil)
+
+	for _, role := range []string{models.RoleCashier, models.RoleStoreManager} {
+		scope := config.ScopeFor(role, []uint{1})
+		scopedRedemptions := redemptions.WithScope(scope)
+		scopedBalances := accumulated.WithScope(scope)
+		scopedTiers := tiers.WithScope(scope)
+		scopedExchanges := exchanges.WithScope(scope)
+
+		_, getRedemptionErr := scopedRedemptions.GetRedemptionById(redemption.ID)
+		_, listRedemptionsErr := scopedRedemptions.GetRedemptionsByStoreId(2, "")
+		_, useVoucherErr := scopedRedemptions.UseVoucher(2, *redemption.VoucherCode)
+		_, claimErr := scopedRedemptions.ClaimReward(1, reward.ID, nil)
+		_, getBalanceErr := scopedBalances.GetRewardByUserAndStore(1, 2)
+		_, getLedgerErr := scopedBalances.GetLedger(1, 2)
+		_, settlementsErr := scopedExchanges.GetSettlements(2)
+		_, exchangeErr := scopedExchanges.ExchangePoints(1, 2, 1, 10)
+		_, getUserErr := users.WithScope(scope).GetUserById(2)
+		outOfScope := []struct {
+			name string
+			err  error
+		}{
+			{"get redemption", getRedemptionErr},
+			{"list redemptions", listRedemptionsErr},
+			{"fulfill redemption", scopedRedemptions.FulfillRedemption(redemption.ID)},
+			{"cancel redemption", scopedRedemptions.CancelRedemption(redemption.ID)},
+			{"use voucher", useVoucherErr},
+			{"claim reward", claimErr},
+			{"get balance", getBalanceErr},
+			{"get ledger", getLedgerErr},
+			{"adjust points", scopedBalances.AdjustPoints(1, 2, 50, "gift")},
+			{"redeem cashback", scopedBalances.RedeemCashback(1, 2, 1, "refund")},
+			{"create tier", scopedTiers.CreateTier(&models.Tier{StoreID: 2, Name: "Gold", Rank: 2, ThresholdType: models.TierThresholdSpend, Threshold: 5000})},
+			{"update tier", scopedTiers.UpdateTier(tier.ID, &models.Tier{Threshold: 1})},
+			{"delete tier", scopedTiers.DeleteTier(tier.ID)},
+			{"create rate", scopedExchanges.CreateRate(&models.ExchangeRate{FromStoreID: 1, ToStoreID: 2, Rate: 1, Active: true})},
+			{"update rate", scopedExchanges.UpdateRate(rate.ID, &models.ExchangeRate{Rate: 10, Active: true})},
+			{"delete rate", scopedExchanges.DeleteRate(rate.ID)},
+			{"get settlements", settlementsErr},
+			{"exchange points", exchangeErr},
+			{"transfer points", transfers.WithScope(scope).TransferPoints(&models.PointTransfer{FromUserID: 1, ToUserID: 2, StoreID: 2, Points: 10})},
+			{"get user", getUserErr},
+		}
+		for _, c := range outOfScope {
+			if !errors.Is(c.err, services.ErrStoreOutOfScope) {
+				t.Errorf("%s as %s: expected the store to be out of scope, got %v", c.name, role, c.err)
+			}
+		}
+
+		// Los listados solo muestran lo de la tienda 1
+		balances, err := scopedBalances.GetAllRewards()
+		if err != nil || len(balances) != 1 || balances[0].StoreID != 1 {
+			t.Errorf("Expected %s to see only the balance in store 1, got %+v (err %v)", role, balances, err)
+		}
+		visible, err := users.WithScope(scope).GetAllUsers()
+		if err != nil || len(visible) != 1 || visible[0].ID != 1 {
+			t.Errorf("Expected %s to see only the customer of store 1, got %+v (err %v)", role, visible, err)
+		}
+	}
+
+	// Nada cambió en la tienda 2
+	stored, err := redemptions.GetRedemptionById(redemption.ID)
+	if err != nil || stored.Status != models.RedemptionStatusReserved {
+		t.Errorf("Expected the redemption to stay reserved, got %+v (err %v)", stored, err)
+	}
+	balance, err := accumulated.GetRewardByUserAndStore(1, 2)
+	if err != nil || balance.PointsAccumulated != 90 {
+		t.Errorf("Expected 90 points left in store 2, got %+v (err %v)", balance, err)
+	}
+	if all, err := users.GetAllUsers(); err != nil || len(all) != 2 {
+		t.Errorf("Expected the admin to see 2 users, got %d (err %v)", len(all), err)
+	}
+}
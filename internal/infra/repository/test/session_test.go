@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"errors"
+	"leal-technical-test/config"
+	"leal-technical-test/internal/domain/models"
+	"leal-technical-test/internal/infra/repository"
+	"leal-technical-test/internal/services"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Prueba que los tokens de refresco se rotan, que reutilizar uno revoca toda la sesión y que cambiar
+// la contraseña revoca los tokens de acceso ya emitidos
+func TestRefreshTokensRotateAndReuseRevokesSession(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to set up test database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.RefreshToken{}, &models.RevokedToken{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+	mockDB := &MockDBConnection{DB: db}
+	repoUser := repository.NewUserRepository(mockDB)
+	repoRevoked := repository.NewRevokedTokenRepository(mockDB)
+	keyring, err := config.NewKeyring([]config.KeyConfig{{ID: "test", Algorithm: config.AlgorithmHS256, Status: config.KeyStatusActive, Secret: "test-key"}})
+	if err != nil {
+		t.Fatalf("Failed to build keyring: %v", err)
+	}
+	tokens := config.NewTokenManagerWithKeyring(keyring).WithRevocationList(repoRevoked)
+	sessions := services.NewSessionService(config.NewUnitOfWork(mockDB), repository.NewRefreshTokenRepository(mockDB), repoRevoked, repoUser, tokens)
+	users := services.NewUserService(repoUser, repository.NewStoreRepository(mockDB), sessions)
+
+	user := models.User{Name: "John Doe", Email: "john@example.com", Role: models.RoleCustomer}
+	mockDB.DB.Create(&user)
+
+	engine := gin.New()
+	engine.GET("/", tokens.AuthMiddleware(), func(ctx *gin.Context) { ctx.Status(http.StatusOK) })
+	status := func(accessToken string) int {
+		request := httptest.NewRequest(http.MethodGet, "/", nil)
+		request.Header.Set("Authorization", "Bearer "+accessToken)
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, request)
+		return recorder.Code
+	}
+
+	// El token de acceso lleva el ID del usuario como subject
+	first, err := sessions.Start(&user)
+	if err != nil {
+		t.Fatalf("Failed to start session: %v", err)
+	}
+	claims, err := tokens.ValidateToken(first.AccessToken)
+	if err != nil {
+		t.Fatalf("Failed to validate access token: %v", err)
+	}
+	if userID, _ := claims.UserID(); userID != user.ID || claims.ID == "" {
+		t.Errorf("Expected subject %d and a token ID, got %q and %q", user.ID, claims.Subject, claims.ID)
+	}
+
+	// Rotación: el token de refresco se cambia por un par nuevo
+	second, err := sessions.Refresh(first.RefreshToken)
+	if err != nil {
+		t.Fatalf("Failed to refresh: %v", err)
+	}
+	if second.RefreshToken == first.RefreshToken || status(second.AccessToken) != http.StatusOK {
+		t.Errorf("Expected a new working pair after refreshing")
+	}
+
+	// Reutilizar el token ya usado revoca la familia completa, incluido el par nuevo
+	if _, err := sessions.Refresh(first.RefreshToken); !errors.Is(err, services.ErrRefreshTokenReused) {
+		t.Fatalf("Expected ErrRefreshTokenReused, got %v", err)
+	}
+	if _, err := sessions.Refresh(second.RefreshToken); !errors.Is(err, services.ErrInvalidRefreshToken) {
+		t.Errorf("Expected the rotated token to be revoked, got %v", err)
+	}
+	if code := status(second.AccessToken); code != http.StatusUnauthorized {
+		t.Errorf("Expected the access token of the revoked session to be rejected, got %d", code)
+	}
+
+	// Cambiar la contraseña cierra las demás sesiones del usuario
+	third, err := sessions.Start(&user)
+	if err != nil {
+		t.Fatalf("Failed to start session: %v", err)
+	}
+	if err := users.UpdateUser(user.ID, &models.User{Password: "new-password"}); err != nil {
+		t.Fatalf("Failed to change password: %v", err)
+	}
+	if code := status(third.AccessToken); code != http.StatusUnauthorized {
+		t.Errorf("Expected the access token to be revoked after a password change, got %d", code)
+	}
+	if _, err := sessions.Refresh(third.RefreshToken); !errors.Is(err, services.ErrInvalidRefreshToken) {
+		t.Errorf("Expected the refresh token to be revoked after a password change, got %v", err)
+	}
+}
@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"errors"
+	"leal-technical-test/config"
+	"leal-technical-test/internal/domain/models"
+	"leal-technical-test/internal/infra/repository"
+	"leal-technical-test/internal/services"
+	"testing"
+	"time"
+)
+
+// Prueba que un cliente sube de nivel, gana con el multiplicador y solo baja al terminar el periodo de gracia
+func TestTierUpgradeMultiplierAndGracePeriod(t *testing.T) {
+	mockDB := setupTransactionTestDB(t)
+	transactions := newTransactionService(mockDB)
+	tiers := services.NewTierService(
+		config.NewUnitOfWork(mockDB),
+		repository.NewTierRepository(mockDB),
+		repository.NewStoreRepository(mockDB),
+		repository.NewTransactionRepository(mockDB),
+	)
+
+	for _, tier := range []models.Tier{
+		{StoreID: 1, Name: "Silver", Rank: 1, ThresholdType: models.TierThresholdSpend, Threshold: 1000, EarnMultiplier: 1.5},
+		{StoreID: 1, Name: "Gold", Rank: 2, ThresholdType: models.TierThresholdSpend, Threshold: 5000, EarnMultiplier: 2},
+	} {
+		if err := tiers.CreateTier(&tier); err != nil {
+			t.Fatalf("Failed to create tier: %v", err)
+		}
+	}
+
+	first, _, err := transactions.CreateTransaction(&models.Transaction{UserID: 1, BranchID: 1, Amount: 1200}, "")
+	if err != nil {
+		t.Fatalf("Failed to create transaction: %v", err)
+	}
+	now := time.Now()
+	if changed, err := tiers.RecalculateUserTier(1, 1, now); err != nil || !changed {
+		t.Fatalf("Expected an upgrade, got changed=%v err=%v", changed, err)
+	}
+
+	second, _, err := transactions.CreateTransaction(&models.Transaction{UserID: 1, BranchID: 1, Amount: 100}, "")
+	if err != nil {
+		t.Fatalf("Failed to create transaction: %v", err)
+	}
+	if second.PointsEarned != 150 || second.TierBonusPoints != 50 {
+		t.Errorf("Expected 150 points with a 50 point tier bonus, got %f and %f", second.PointsEarned, second.TierBonusPoints)
+	}
+
+	// Sin el gasto de la primera compra ya no califica, pero conserva el nivel durante la gracia
+	if _, err := transactions.RefundTransaction(first.ID, 0, "returned"); err != nil {
+		t.Fatalf("Failed to refund: %v", err)
+	}
+	if changed, err := tiers.RecalculateUserTier(1, 1, now); err != nil || changed {
+		t.Fatalf("Expected the tier to be kept during the grace period, got changed=%v err=%v", changed, err)
+	}
+	progress, err := tiers.GetProgress(1, 1)
+	if err != nil {
+		t.Fatalf("Failed to read progress: %v", err)
+	}
+	if progress.Current == nil || progress.Current.Name != "Silver" || progress.DowngradeAt == nil {
+		t.Errorf("Expected Silver with a pending downgrade, got %+v", progress)
+	}
+	if progress.Next == nil || progress.Next.Name != "Gold" || progress.Remaining != 4900 {
+		t.Errorf("Expected 4900 left for Gold, got %+v", progress)
+	}
+
+	if changed, err := tiers.RecalculateUserTier(1, 1, now.AddDate(0, 0, 31)); err != nil || !changed {
+		t.Fatalf("Expected a downgrade after the grace period, got changed=%v err=%v", changed, err)
+	}
+	progress, err = tiers.GetProgress(1, 1)
+	if err != nil {
+		t.Fatalf("Failed to read progress: %v", err)
+	}
+	if progress.Current != nil || len(progress.History) != 2 || progress.History[0].Reason != models.TierChangeDowngrade {
+		t.Errorf("Expected no tier and two history entries, got %+v", progress)
+	}
+}
+
+// Prueba que no se puede eliminar un nivel mientras sea el nivel actual de algún usuario y que el
+// rango de un nivel eliminado se puede volver a usar
+func TestTierWithMembersCannotBeDeleted(t *testing.T) {
+	mockDB := setupTransactionTestDB(t)
+	tiers := services.NewTierService(
+		config.NewUnitOfWork(mockDB),
+		repository.NewTierRepository(mockDB),
+		repository.NewStoreRepository(mockDB),
+		repository.NewTransactionRepository(mockDB),
+	)
+	silver := &models.Tier{StoreID: 1, Name: "Silver", Rank: 1, ThresholdType: models.TierThresholdSpend, Threshold: 1000}
+	gold := &models.Tier{StoreID: 1, Name: "Gold", Rank: 2, ThresholdType: models.TierThresholdSpend, Threshold: 5000}
+	for _, tier := range []*models.Tier{silver, gold} {
+		if err := tiers.CreateTier(tier); err != nil {
+			t.Fatalf("Failed to create tier: %v", err)
+		}
+	}
+	if _, _, err := newTransactionService(mockDB).CreateTransaction(&models.Transaction{UserID: 1, BranchID: 1, Amount: 1200}, ""); err != nil {
+		t.Fatalf("Failed to create transaction: %v", err)
+	}
+	if changed, err := tiers.RecalculateUserTier(1, 1, time.Now()); err != nil || !changed {
+		t.Fatalf("Expected an upgrade, got changed=%v err=%v", changed, err)
+	}
+
+	if err := tiers.DeleteTier(silver.ID); !errors.Is(err, services.ErrTierHasMembers) {
+		t.Errorf("Expected the tier with members not to be deleted, got %v", err)
+	}
+	if err := tiers.DeleteTier(gold.ID); err != nil {
+		t.Errorf("Failed to delete the tier without members: %v", err)
+	}
+	remaining, err := tiers.GetTiersByStoreId(1)
+	if err != nil || len(remaining) != 1 || remaining[0].ID != silver.ID {
+		t.Errorf("Expected only Silver to remain, got %+v (err %v)", remaining, err)
+	}
+
+	// El rango del nivel eliminado queda libre, pero no el de un nivel vigente
+	if err := tiers.CreateTier(&models.Tier{StoreID: 1, Name: "Platinum", Rank: 2, ThresholdType: models.TierThresholdSpend, Threshold: 8000}); err != nil {
+		t.Errorf("Failed to re-create the rank of the deleted tier: %v", err)
+	}
+	if err := tiers.CreateTier(&models.Tier{StoreID: 1, Name: "Bronze", Rank: 1, ThresholdType: models.TierThresholdSpend, Threshold: 500}); err == nil {
+		t.Errorf("Expected the rank of an existing tier to be rejected")
+	}
+}
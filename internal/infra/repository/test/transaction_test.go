@@ -0,0 +1,358 @@
+package repository
+
+import (
+	"errors"
+	"leal-technical-test/config"
+	"leal-technical-test/internal/domain/models"
+	"leal-technical-test/internal/infra/repository"
+	"leal-technical-test/internal/services"
+	"testing"
+	"time"
+)
+
+// setupTransactionTestDB prepara una tienda con una sucursal y un usuario para registrar compras
+func setupTransactionTestDB(t *testing.T) *MockDBConnection {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to set up test database: %v", err)
+	}
+	err = db.AutoMigrate(&models.Campaign{}, &models.TransactionCampaign{}, &models.PointsLot{}, &models.PointsLotConsumption{}, &models.IdempotencyKey{}, &models.Refund{}, &models.Tier{}, &models.UserTier{}, &models.TierHistory{})
+	if err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+
+	db.Create(&models.User{Name: "Customer", Email: "customer@example.com", Password: "secret"})
+	db.Create(&models.Store{Name: "Store", ConversionFactor: 1})
+	db.Create(&models.Branch{Name: "Branch", StoreID: 1})
+	return &MockDBConnection{DB: db}
+}
+
+func newTransactionService(mockDB *MockDBConnection) services.TransactionService {
+	uow := config.NewUnitOfWork(mockDB)
+	return services.NewTransactionService(
+		uow,
+		repository.NewTransactionRepository(mockDB),
+		repository.NewBranchRepository(mockDB),
+		repository.NewCampaignRepository(mockDB),
+		repository.NewIdempotencyKeyRepository(mockDB),
+		repository.NewRefundRepository(mockDB),
+		repository.NewTierRepository(mockDB),
+		newAccumulatedRewardService(mockDB),
+	)
+}
+
+// Prueba que los reintentos con la misma clave o el mismo recibo no acumulan puntos otra vez
+func TestCreateTransactionReplaysRetries(t *testing.T) {
+	mockDB := setupTransactionTestDB(t)
+	service := newTransactionService(mockDB)
+
+	first, replayed, err := service.CreateTransaction(&models.Transaction{UserID: 1, BranchID: 1, Amount: 1000}, "pos-1-0001")
+	if err != nil || replayed {
+		t.Fatalf("Expected the first request to be recorded, got replayed=%v err=%v", replayed, err)
+	}
+	retry, replayed, err := service.CreateTransaction(&models.Transaction{UserID: 1, BranchID: 1, Amount: 1000}, "pos-1-0001")
+	if err != nil || !replayed || retry.ID != first.ID {
+		t.Fatalf("Expected the retry to replay transaction %d, got %+v replayed=%v err=%v", first.ID, retry, replayed, err)
+	}
+
+	receiptID := "R-77"
+	first, _, err = service.CreateTransaction(&models.Transaction{UserID: 1, BranchID: 1, Amount: 500, ExternalReceiptID: &receiptID}, "")
+	if err != nil {
+		t.Fatalf("Failed to record the receipt: %v", err)
+	}
+	retry, replayed, err = service.CreateTransaction(&models.Transaction{UserID: 1, BranchID: 1, Amount: 500, ExternalReceiptID: &receiptID}, "")
+	if err != nil || !replayed || retry.ID != first.ID {
+		t.Fatalf("Expected the receipt to replay transaction %d, got replayed=%v err=%v", first.ID, replayed, err)
+	}
+
+	// La misma clave con otra compra es un error del cliente
+	_, _, err = service.CreateTransaction(&models.Transaction{UserID: 1, BranchID: 1, Amount: 2000}, "pos-1-0001")
+	if !errors.Is(err, services.ErrIdempotencyConflict) {
+		t.Errorf("Expected an idempotency conflict, got %v", err)
+	}
+
+	balance, err := repository.NewAccumulatedRewardRepository(mockDB).GetByUserAndStore(1, 1)
+	if err != nil {
+		t.Fatalf("Failed to read balance: %v", err)
+	}
+	if balance.PointsAccumulated != 1500 {
+		t.Errorf("Expected 1500 points, got %f", balance.PointsAccumulated)
+	}
+}
+
+// Prueba que las devoluciones retiran los puntos proporcionales y respetan la política de saldo negativo
+func TestRefundTransactionClawsBackPoints(t *testing.T) {
+	mockDB := setupTransactionTestDB(t)
+	service := newTransactionService(mockDB)
+	balances := repository.NewAccumulatedRewardRepository(mockDB)
+
+	transaction, _, err := service.CreateTransaction(&models.Transaction{UserID: 1, BranchID: 1, Amount: 1000}, "")
+	if err != nil {
+		t.Fatalf("Failed to create transaction: %v", err)
+	}
+
+	refund, err := service.RefundTransaction(transaction.ID, 250, "damaged item")
+	if err != nil {
+		t.Fatalf("Failed to refund: %v", err)
+	}
+	if refund.PointsReversed != 250 {
+		t.Errorf("Expected 250 points reversed, got %f", refund.PointsReversed)
+	}
+
+	// Con la política por defecto (reject) no se puede devolver más de lo que cubre el saldo
+	if err := newAccumulatedRewardService(mockDB).AdjustPoints(1, 1, -700, "spent elsewhere"); err != nil {
+		t.Fatalf("Failed to adjust points: %v", err)
+	}
+	if _, err := service.RefundTransaction(transaction.ID, 0, "full refund"); !errors.Is(err, repository.ErrInsufficientPoints) {
+		t.Fatalf("Expected insufficient points, got %v", err)
+	}
+
+	// Con clamp el saldo queda en cero y el resto se condona
+	mockDB.DB.Model(&models.Store{}).Where("id = ?", 1).Update("negative_balance_policy", models.NegativeBalanceClamp)
+	refund, err = service.RefundTransaction(transaction.ID, 0, "full refund")
+	if err != nil {
+		t.Fatalf("Failed to refund the rest: %v", err)
+	}
+	if refund.Amount != 750 || refund.PointsReversed != 50 || refund.PointsWaived != 700 {
+		t.Errorf("Expected 750 refunded with 50 points reversed and 700 waived, got %+v", refund)
+	}
+	balance, err := balances.GetByUserAndStore(1, 1)
+	if err != nil {
+		t.Fatalf("Failed to read balance: %v", err)
+	}
+	if balance.PointsAccumulated != 0 {
+		t.Errorf("Expected balance to be 0, got %f", balance.PointsAccumulated)
+	}
+
+	if _, err := service.RefundTransaction(transaction.ID, 1, "again"); !errors.Is(err, services.ErrRefundExceedsAmount) {
+		t.Errorf("Expected the refund to exceed the amount, got %v", err)
+	}
+}
+
+// Prueba que el cashback usado como pago se descuenta, no acumula puntos y se devuelve con la compra
+func TestCashbackPaymentReducesEarningAmountAndIsReturnedOnRefund(t *testing.T) {
+	mockDB := setupTransactionTestDB(t)
+	service := newTransactionService(mockDB)
+	mockDB.DB.Create(&models.AccumulatedReward{UserID: 1, StoreID: 1, CashbackAccumulated: 300})
+
+	_, _, err := service.CreateTransaction(&models.Transaction{UserID: 1, BranchID: 1, Amount: 1000, CashbackApplied: 500}, "")
+	if !errors.Is(err, repository.ErrInsufficientCashback) {
+		t.Fatalf("Expected insufficient cashback, got %v", err)
+	}
+
+	transaction, _, err := service.CreateTransaction(&models.Transaction{UserID: 1, BranchID: 1, Amount: 1000, CashbackApplied: 200}, "")
+	if err != nil {
+		t.Fatalf("Failed to record the purchase: %v", err)
+	}
+	if transaction.NetAmount != 800 || transaction.PointsEarned != 800 {
+		t.Errorf("Expected 800 net amount and 800 points, got %f and %f", transaction.NetAmount, transaction.PointsEarned)
+	}
+
+	refund, err := service.RefundTransaction(transaction.ID, 500, "partial return")
+	if err != nil {
+		t.Fatalf("Failed to refund the purchase: %v", err)
+	}
+	if refund.CashbackReturned != 100 || refund.PointsReversed != 400 {
+		t.Errorf("Expected 100 cashback returned and 400 points reversed, got %f and %f", refund.CashbackReturned, refund.PointsReversed)
+	}
+
+	balance, err := repository.NewAccumulatedRewardRepository(mockDB).GetByUserAndStore(1, 1)
+	if err != nil {
+		t.Fatalf("Failed to read balance: %v", err)
+	}
+	if balance.CashbackAccumulated != 200 || balance.PointsAccumulated != 400 {
+		t.Errorf("Expected 200 cashback and 400 points, got %f and %f", balance.CashbackAccumulated, balance.PointsAccumulated)
+	}
+}
+
+// Prueba que una campaña respeta sus topes por compra y por cliente y deja de aplicar al agotar el presupuesto
+func TestCampaignBudgetAndCaps(t *testing.T) {
+	mockDB := setupTransactionTestDB(t)
+	service := newTransactionService(mockDB)
+	mockDB.DB.Create(&models.User{Name: "Other", Email: "other@example.com", Password: "secret"})
+	campaign := models.Campaign{
+		Name: "Double", BranchID: 1, Type: "double",
+		BudgetPoints: 150, PerCustomerCap: 100, PerTransactionCap: 80,
+		StartDate: time.Now().AddDate(0, 0, -1), EndDate: time.Now().AddDate(0, 0, 1),
+	}
+	mockDB.DB.Create(&campaign)
+
+	// Cada compra de 100 otorga 100 puntos base y la campaña doble otros 100
+	cases := []struct {
+		userID uint
+		points float64
+	}{
+		{1, 180}, // tope por compra
+		{1, 120}, // resto del tope por cliente
+		{2, 150}, // resto del presupuesto
+		{2, 100}, // presupuesto agotado
+	}
+	for i, c := range cases {
+		transaction, _, err := service.CreateTransaction(&models.Transaction{UserID: c.userID, BranchID: 1, Amount: 100}, "")
+		if err != nil {
+			t.Fatalf("Purchase %d: failed to record: %v", i+1, err)
+		}
+		if transaction.PointsEarned != c.points {
+			t.Errorf("Purchase %d: expected %f points, got %f", i+1, c.points, transaction.PointsEarned)
+		}
+	}
+
+	stored, err := repository.NewCampaignRepository(mockDB).GetById(campaign.ID)
+	if err != nil {
+		t.Fatalf("Failed to read campaign: %v", err)
+	}
+	if remaining := stored.RemainingBudget(); remaining == nil || *remaining != 0 {
+		t.Errorf("Expected no budget left, got %v", remaining)
+	}
+}
+
+// Prueba que devolver una compra devuelve su bono al presupuesto de la campaña y deja de contarlo
+// para el tope por cliente
+func TestRefundReleasesCampaignBonus(t *testing.T) {
+	mockDB := setupTransactionTestDB(t)
+	service := newTransactionService(mockDB)
+	campaign := models.Campaign{
+		Name: "Double", BranchID: 1, Type: "double", BudgetPoints: 150, PerCustomerCap: 100,
+		StartDate: time.Now().AddDate(0, 0, -1), EndDate: time.Now().AddDate(0, 0, 1),
+	}
+	mockDB.DB.Create(&campaign)
+	campaigns := repository.NewCampaignRepository(mockDB)
+
+	transaction, _, err := service.CreateTransaction(&models.Transaction{UserID: 1, BranchID: 1, Amount: 100}, "")
+	if err != nil {
+		t.Fatalf("Failed to record the purchase: %v", err)
+	}
+
+	// Cada devolución libera la parte del bono que corresponde al monto devuelto
+	cases := []struct {
+		amount     float64
+		budgetUsed float64
+		received   float64
+	}{
+		{40, 60, 60},
+		{0, 0, 0},
+	}
+	for i, c := range cases {
+		if _, err := service.RefundTransaction(transaction.ID, c.amount, "returned"); err != nil {
+			t.Fatalf("Refund %d: failed: %v", i+1, err)
+		}
+		stored, _ := campaigns.GetById(campaign.ID)
+		received, _ := campaigns.SumBonusByUser(campaign.ID, 1)
+		if stored.BudgetUsed != c.budgetUsed || received != c.received {
+			t.Errorf("Refund %d: expected %f budget used and %f received, got %f and %f", i+1, c.budgetUsed, c.received, stored.BudgetUsed, received)
+		}
+	}
+
+	// Con el bono devuelto, el cliente puede volver a recibir el tope completo
+	again, _, err := service.CreateTransaction(&models.Transaction{UserID: 1, BranchID: 1, Amount: 100}, "")
+	if err != nil {
+		t.Fatalf("Failed to record the second purchase: %v", err)
+	}
+	if again.PointsEarned != 200 {
+		t.Errorf("Expected 200 points after the refund, got %f", again.PointsEarned)
+	}
+}
+
+// Prueba que la vista previa coincide con la compra real y no guarda nada ni consume presupuesto
+func TestPreviewTransactionMatchesCreateWithoutSaving(t *testing.T) {
+	mockDB := setupTransactionTestDB(t)
+	service := newTransactionService(mockDB)
+	campaign := models.Campaign{
+		Name: "Double", BranchID: 1, Type: "double", BudgetPoints: 500, PerTransactionCap: 60,
+		StartDate: time.Now().AddDate(0, 0, -1), EndDate: time.Now().AddDate(0, 0, 1),
+	}
+	mockDB.DB.Create(&campaign)
+
+	preview, err := service.PreviewTransaction(&models.Transaction{UserID: 1, BranchID: 1, Amount: 100}, time.Now())
+	if err != nil {
+		t.Fatalf("Failed to preview the purchase: %v", err)
+	}
+	var transactions, applied int64
+	mockDB.DB.Model(&models.Transaction{}).Count(&transactions)
+	mockDB.DB.Model(&models.TransactionCampaign{}).Count(&applied)
+	stored, _ := repository.NewCampaignRepository(mockDB).GetById(campaign.ID)
+	if transactions != 0 || applied != 0 || stored.BudgetUsed != 0 {
+		t.Fatalf("Expected the preview to save nothing, got %d transactions, %d applied campaigns and %f budget used", transactions, applied, stored.BudgetUsed)
+	}
+
+	created, _, err := service.CreateTransaction(&models.Transaction{UserID: 1, BranchID: 1, Amount: 100}, "")
+	if err != nil {
+		t.Fatalf("Failed to record the purchase: %v", err)
+	}
+	if preview.PointsEarned != 160 || preview.PointsEarned != created.PointsEarned || preview.CapAdjustment != created.CapAdjustment {
+		t.Errorf("Expected preview and purchase to earn 160 points, got %f and %f", preview.PointsEarned, created.PointsEarned)
+	}
+
+	// Una fecha fuera de la vigencia de la campaña solo otorga los puntos base
+	later, err := service.PreviewTransaction(&models.Transaction{UserID: 1, BranchID: 1, Amount: 100}, time.Now().AddDate(0, 0, 5))
+	if err != nil {
+		t.Fatalf("Failed to preview the purchase: %v", err)
+	}
+	if later.PointsEarned != 100 {
+		t.Errorf("Expected 100 points after the campaign ends, got %f", later.PointsEarned)
+	}
+}
+
+// Prueba que actualizar una campaña puede quitarle la exclusividad y la prioridad y conserva lo que no se envía
+func TestCampaignUpdateTurnsOffExclusive(t *testing.T) {
+	mockDB := setupTransactionTestDB(t)
+	campaigns := services.NewCampaignService(repository.NewCampaignRepository(mockDB), repository.NewBranchRepository(mockDB))
+	campaign := &models.Campaign{
+		Name: "Double", BranchID: 1, Type: "double", Priority: 5, Exclusive: true,
+		StartDate: time.Now().AddDate(0, 0, -1), EndDate: time.Now().AddDate(0, 0, 1),
+	}
+	if err := campaigns.CreateCampaign(campaign); err != nil {
+		t.Fatalf("Failed to create campaign: %v", err)
+	}
+
+	if err := campaigns.UpdateCampaign(campaign.ID, &models.Campaign{Exclusive: false, Priority: 0}); err != nil {
+		t.Fatalf("Failed to update campaign: %v", err)
+	}
+	stored, err := repository.NewCampaignRepository(mockDB).GetById(campaign.ID)
+	if err != nil {
+		t.Fatalf("Failed to read campaign: %v", err)
+	}
+	if stored.Exclusive || stored.Priority != 0 {
+		t.Errorf("Expected the campaign not to be exclusive and to have no priority, got %v and %d", stored.Exclusive, stored.Priority)
+	}
+	if stored.Name != "Double" || stored.Type != "double" || stored.BranchID != 1 {
+		t.Errorf("Expected the name, type and branch to be kept, got %q, %q and %d", stored.Name, stored.Type, stored.BranchID)
+	}
+}
+
+// Prueba que actualizar una campaña con topes en 0 los quita y vuelve a otorgar el bono completo
+func TestCampaignUpdateClearsCaps(t *testing.T) {
+	mockDB := setupTransactionTestDB(t)
+	service := newTransactionService(mockDB)
+	campaigns := services.NewCampaignService(repository.NewCampaignRepository(mockDB), repository.NewBranchRepository(mockDB))
+	campaign := &models.Campaign{
+		Name: "Double", BranchID: 1, Type: "double", MinAmount: 50,
+		BudgetPoints: 1000, PerCustomerCap: 500, PerTransactionCap: 20,
+		StartDate: time.Now().AddDate(0, 0, -1), EndDate: time.Now().AddDate(0, 0, 1),
+	}
+	if err := campaigns.CreateCampaign(campaign); err != nil {
+		t.Fatalf("Failed to create campaign: %v", err)
+	}
+	capped, _, err := service.CreateTransaction(&models.Transaction{UserID: 1, BranchID: 1, Amount: 100}, "")
+	if err != nil || capped.PointsEarned != 120 {
+		t.Fatalf("Expected the bonus to be capped at 20 points, got %+v (err %v)", capped, err)
+	}
+
+	if err := campaigns.UpdateCampaign(campaign.ID, &models.Campaign{}); err != nil {
+		t.Fatalf("Failed to update campaign: %v", err)
+	}
+	stored, err := repository.NewCampaignRepository(mockDB).GetById(campaign.ID)
+	if err != nil {
+		t.Fatalf("Failed to read campaign: %v", err)
+	}
+	if stored.BudgetPoints != 0 || stored.PerCustomerCap != 0 || stored.PerTransactionCap != 0 || stored.MinAmount != 0 {
+		t.Errorf("Expected the limits to be removed, got %+v", stored)
+	}
+	if stored.RemainingBudget() != nil || stored.BudgetUsed != 20 {
+		t.Errorf("Expected no budget and the used budget to be kept, got %v and %f", stored.RemainingBudget(), stored.BudgetUsed)
+	}
+	uncapped, _, err := service.CreateTransaction(&models.Transaction{UserID: 1, BranchID: 1, Amount: 40}, "")
+	if err != nil || uncapped.PointsEarned != 80 {
+		t.Errorf("Expected the full bonus without limits or minimum amount, got %+v (err %v)", uncapped, err)
+	}
+}
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"fmt"
+	"leal-technical-test/config"
+	"leal-technical-test/internal/domain/models"
+	"leal-technical-test/internal/infra/repository"
+	"testing"
+)
+
+// Prueba que una escritura dentro de la unidad de trabajo se revierte si la función falla
+func TestUnitOfWorkRollback(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to set up test database: %v", err)
+	}
+	mockDB := &MockDBConnection{DB: db}
+	uow := config.NewUnitOfWork(mockDB)
+	transactionRepo := repository.NewTransactionRepository(mockDB)
+
+	err = uow.Do(func(tx config.IDatabaseConnection) error {
+		transaction := models.Transaction{UserID: 1, BranchID: 1, Amount: 1000, RewardType: "points"}
+		if err := transactionRepo.WithTx(tx).Create(&transaction); err != nil {
+			return err
+		}
+		return fmt.Errorf("accrual failed")
+	})
+	if err == nil {
+		t.Fatalf("Expected the unit of work to return the inner error")
+	}
+
+	transactions, err := transactionRepo.GetAll()
+	if err != nil {
+		t.Fatalf("Failed to list transactions: %v", err)
+	}
+	if len(transactions) != 0 {
+		t.Errorf("Expected the transaction to be rolled back, found %d", len(transactions))
+	}
+}
+
+// Prueba que la transacción y la acumulación se confirman juntas
+func TestUnitOfWorkCommit(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to set up test database: %v", err)
+	}
+	mockDB := &MockDBConnection{DB: db}
+	uow := config.NewUnitOfWork(mockDB)
+	transactionRepo := repository.NewTransactionRepository(mockDB)
+	accumulatedRepo := repository.NewAccumulatedRewardRepository(mockDB)
+
+	err = uow.Do(func(tx config.IDatabaseConnection) error {
+		transaction := models.Transaction{UserID: 1, BranchID: 1, Amount: 1000, RewardType: "points", PointsEarned: 1000}
+		if err := transactionRepo.WithTx(tx).Create(&transaction); err != nil {
+			return err
+		}
+		return accumulatedRepo.WithTx(tx).Create(&models.AccumulatedReward{UserID: 1, StoreID: 1, PointsAccumulated: 1000})
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	reward, err := accumulatedRepo.GetByUserAndStore(1, 1)
+	if err != nil {
+		t.Fatalf("Expected accumulated reward to be committed: %v", err)
+	}
+	if reward.PointsAccumulated != 1000 {
+		t.Errorf("Expected 1000 points, got %f", reward.PointsAccumulated)
+	}
+}
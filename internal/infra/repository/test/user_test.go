@@ -48,8 +48,15 @@ func setupTestDB() (*gorm.DB, error) {
 		return nil, err
 	}
 
+	// Una única conexión para que las transacciones vean la misma base en memoria
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	sqlDB.SetMaxOpenConns(1)
+
 	// Migrar los modelos necesarios
-	err = db.AutoMigrate(&models.User{})
+	err = db.AutoMigrate(&models.User{}, &models.Store{}, &models.Branch{}, &models.Transaction{}, &models.AccumulatedReward{}, &models.PointsLedgerEntry{})
 	if err != nil {
 		return nil, err
 	}
@@ -83,3 +90,35 @@ func TestCreateUser(t *testing.T) {
 		t.Errorf("Expected user ID to be set after creation")
 	}
 }
+
+// Prueba que crear un usuario no guarda las tiendas ni los saldos que vengan con él
+func TestCreateUserIgnoresAssociations(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to set up test database: %v", err)
+	}
+	mockDB := &MockDBConnection{DB: db}
+	userRepo := repository.NewUserRepository(mockDB)
+	db.Create(&models.Store{Name: "Store", ConversionFactor: 1})
+
+	user := models.User{Name: "John Doe", Email: "john@example.com"}
+	user.Stores = []models.Store{{}}
+	user.Stores[0].ID = 1
+	user.Rewards = []models.AccumulatedReward{{StoreID: 1, PointsAccumulated: 1000000}}
+	if err := userRepo.Create(&user); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	stored, err := userRepo.GetById(user.ID)
+	if err != nil {
+		t.Fatalf("Failed to get user: %v", err)
+	}
+	if len(stored.Stores) != 0 {
+		t.Errorf("Expected the user to be linked to no store, got %v", stored.StoreIDs())
+	}
+	var balances int64
+	db.Model(&models.AccumulatedReward{}).Where("user_id = ?", user.ID).Count(&balances)
+	if balances != 0 {
+		t.Errorf("Expected no balance for the new user, got %d", balances)
+	}
+}
@@ -0,0 +1,174 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"leal-technical-test/config"
+	"leal-technical-test/internal/domain/models"
+
+	"gorm.io/gorm"
+)
+
+// TierRepository interface
+type TierRepository interface {
+	GetByStoreId(storeID uint) ([]models.Tier, error)
+	GetById(id uint) (*models.Tier, error)
+	Create(tier *models.Tier) error
+	Update(id uint, tier *models.Tier) error
+	Delete(id uint) error
+	GetStoreIdsWithTiers() ([]uint, error)
+	GetMemberIds(storeID uint) ([]uint, error)
+	CountMembers(tierID uint) (int64, error)
+	GetUserTier(userID uint, storeID uint) (*models.UserTier, error)
+	SaveUserTier(userTier *models.UserTier) error
+	CreateHistory(history *models.TierHistory) error
+	GetHistory(userID uint, storeID uint) ([]models.TierHistory, error)
+	WithTx(tx config.IDatabaseConnection) TierRepository
+}
+
+// tierRepository struct
+type tierRepository struct {
+	db config.IDatabaseConnection
+}
+
+// NewTierRepository constructor
+func NewTierRepository(db config.IDatabaseConnection) TierRepository {
+	return &tierRepository{
+		db: db,
+	}
+}
+
+// WithTx returns a copy of the repository bound to the given transaction
+func (r *tierRepository) WithTx(tx config.IDatabaseConnection) TierRepository {
+	return &tierRepository{db: tx}
+}
+
+// GetByStoreId retrieves the tiers of a store from the lowest to the highest rank
+func (r *tierRepository) GetByStoreId(storeID uint) ([]models.Tier, error) {
+	var tiers []models.Tier
+	if err := r.db.GetDB().
+		Where("store_id = ?", storeID).
+		Order("rank ASC").
+		Find(&tiers).Error; err != nil {
+		return nil, err
+	}
+	return tiers, nil
+}
+
+// GetById retrieves a tier by its ID
+func (r *tierRepository) GetById(id uint) (*models.Tier, error) {
+	var tier models.Tier
+	if err := r.db.GetDB().First(&tier, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("tier with ID %d not found", id)
+		}
+		return nil, err
+	}
+	return &tier, nil
+}
+
+// Create creates a new tier
+func (r *tierRepository) Create(tier *models.Tier) error {
+	if err := r.db.GetDB().Create(tier).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// Update updates an existing tier
+func (r *tierRepository) Update(id uint, tier *models.Tier) error {
+	result := r.db.GetDB().Model(&models.Tier{}).Where("id = ?", id).Updates(tier)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("tier with ID %d not found", id)
+	}
+	return nil
+}
+
+// Delete deletes a tier by its ID
+func (r *tierRepository) Delete(id uint) error {
+	if err := r.db.GetDB().Delete(&models.Tier{}, id).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetStoreIdsWithTiers retrieves the stores that define at least one tier
+func (r *tierRepository) GetStoreIdsWithTiers() ([]uint, error) {
+	var storeIDs []uint
+	if err := r.db.GetDB().Model(&models.Tier{}).
+		Distinct("store_id").
+		Pluck("store_id", &storeIDs).Error; err != nil {
+		return nil, err
+	}
+	return storeIDs, nil
+}
+
+// GetMemberIds retrieves the users with a balance or a tier in a store
+func (r *tierRepository) GetMemberIds(storeID uint) ([]uint, error) {
+	var userIDs []uint
+	if err := r.db.GetDB().Raw(
+		"SELECT user_id FROM accumulated_rewards WHERE store_id = ? AND deleted_at IS NULL "+
+			"UNION SELECT user_id FROM user_tiers WHERE store_id = ? AND deleted_at IS NULL",
+		storeID, storeID,
+	).Scan(&userIDs).Error; err != nil {
+		return nil, err
+	}
+	return userIDs, nil
+}
+
+// CountMembers counts the users whose current tier is the given one
+func (r *tierRepository) CountMembers(tierID uint) (int64, error) {
+	var count int64
+	if err := r.db.GetDB().Model(&models.UserTier{}).Where("tier_id = ?", tierID).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// GetUserTier retrieves the current tier of a user in a store. It returns nil when the user was never evaluated.
+func (r *tierRepository) GetUserTier(userID uint, storeID uint) (*models.UserTier, error) {
+	var userTier models.UserTier
+	if err := r.db.GetDB().
+		Preload("Tier").
+		Where("user_id = ? AND store_id = ?", userID, storeID).
+		First(&userTier).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &userTier, nil
+}
+
+// SaveUserTier creates or updates the tier of a user in a store
+func (r *tierRepository) SaveUserTier(userTier *models.UserTier) error {
+	if err := r.db.GetDB().Omit("Tier").Save(userTier).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// CreateHistory records a tier change
+func (r *tierRepository) CreateHistory(history *models.TierHistory) error {
+	if err := r.db.GetDB().Omit("FromTier", "ToTier").Create(history).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetHistory retrieves the tier changes of a user in a store, newest first
+func (r *tierRepository) GetHistory(userID uint, storeID uint) ([]models.TierHistory, error) {
+	var history []models.TierHistory
+	if err := r.db.GetDB().
+		Preload("FromTier").
+		Preload("ToTier").
+		Where("user_id = ? AND store_id = ?", userID, storeID).
+		Order("id DESC").
+		Find(&history).Error; err != nil {
+		return nil, err
+	}
+	return history, nil
+}
@@ -1,9 +1,14 @@
 package repository
 
 import (
+	"errors"
 	"fmt"
 	"leal-technical-test/config"
 	"leal-technical-test/internal/domain/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // TransactionRepository interface
@@ -11,7 +16,13 @@ type TransactionRepository interface {
 	GetAll() ([]models.Transaction, error)
 	GetById(id uint) (*models.Transaction, error)
 	GetByUserId(userID uint) ([]models.Transaction, error)
+	GetByIdForUpdate(id uint) (*models.Transaction, error)
+	AddRefund(id uint, amount float64, points float64, cashback float64, cashbackReturned float64) error
+	GetByBranchAndReceipt(branchID uint, receiptID string) (*models.Transaction, error)
+	CountByUserAndStore(userID uint, storeID uint) (int64, error)
+	SumByUserAndStoreSince(userID uint, storeID uint, since time.Time) (float64, float64, error)
 	Create(transaction *models.Transaction) error
+	WithTx(tx config.IDatabaseConnection) TransactionRepository
 }
 
 // transactionRepository struct
@@ -26,12 +37,18 @@ func NewTransactionRepository(db config.IDatabaseConnection) TransactionReposito
 	}
 }
 
+// WithTx returns a copy of the repository bound to the given transaction
+func (r *transactionRepository) WithTx(tx config.IDatabaseConnection) TransactionRepository {
+	return &transactionRepository{db: tx}
+}
+
 // GetAll retrieves all transactions
 func (r *transactionRepository) GetAll() ([]models.Transaction, error) {
 	var transactions []models.Transaction
 	if err := r.db.GetDB().
 		Preload("User").
 		Preload("Branch").
+		Preload("AppliedCampaigns.Campaign").
 		Find(&transactions).Error; err != nil {
 		return nil, err
 	}
@@ -44,24 +61,99 @@ func (r *transactionRepository) GetById(id uint) (*models.Transaction, error) {
 	if err := r.db.GetDB().
 		Preload("User").
 		Preload("Branch").
+		Preload("AppliedCampaigns.Campaign").
 		First(&transaction, id).Error; err != nil {
 		return nil, err
 	}
 	return &transaction, nil
 }
 
+// GetByIdForUpdate retrieves a transaction with its applied campaigns and locks the row until the
+// surrounding transaction ends, so refunds of the same purchase are applied one at a time
+func (r *transactionRepository) GetByIdForUpdate(id uint) (*models.Transaction, error) {
+	var transaction models.Transaction
+	if err := r.db.GetDB().
+		Preload("AppliedCampaigns").
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&transaction, id).Error; err != nil {
+		return nil, err
+	}
+	return &transaction, nil
+}
+
+// AddRefund atomically adds a refund to the refunded totals of a transaction
+func (r *transactionRepository) AddRefund(id uint, amount float64, points float64, cashback float64, cashbackReturned float64) error {
+	return r.db.GetDB().Model(&models.Transaction{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"refunded_amount":   gorm.Expr("refunded_amount + ?", amount),
+			"points_reversed":   gorm.Expr("points_reversed + ?", points),
+			"cashback_reversed": gorm.Expr("cashback_reversed + ?", cashback),
+			"cashback_returned": gorm.Expr("cashback_returned + ?", cashbackReturned),
+		}).Error
+}
+
 // GetByUserId retrieves transactions by UserID
 func (r *transactionRepository) GetByUserId(userID uint) ([]models.Transaction, error) {
 	var transactions []models.Transaction
 	if err := r.db.GetDB().
 		Preload("User").
 		Preload("Branch").
+		Preload("AppliedCampaigns.Campaign").
 		Where("user_id = ?", userID).Find(&transactions).Error; err != nil {
 		return nil, err
 	}
 	return transactions, nil
 }
 
+// GetByBranchAndReceipt retrieves the transaction recorded for a POS receipt of a branch.
+// It returns nil when the receipt was never recorded.
+func (r *transactionRepository) GetByBranchAndReceipt(branchID uint, receiptID string) (*models.Transaction, error) {
+	var transaction models.Transaction
+	if err := r.db.GetDB().
+		Preload("User").
+		Preload("Branch").
+		Preload("AppliedCampaigns.Campaign").
+		Where("branch_id = ? AND external_receipt_id = ?", branchID, receiptID).
+		First(&transaction).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &transaction, nil
+}
+
+// CountByUserAndStore counts the transactions of a user in any branch of a store
+func (r *transactionRepository) CountByUserAndStore(userID uint, storeID uint) (int64, error) {
+	var count int64
+	if err := r.db.GetDB().Model(&models.Transaction{}).
+		Joins("JOIN branches ON branches.id = transactions.branch_id").
+		Where("transactions.user_id = ? AND branches.store_id = ?", userID, storeID).
+		Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// SumByUserAndStoreSince adds up the spend and the points of a user in a store since the given date,
+// net of refunds
+func (r *transactionRepository) SumByUserAndStoreSince(userID uint, storeID uint, since time.Time) (float64, float64, error) {
+	var totals struct {
+		Spend  float64
+		Points float64
+	}
+	if err := r.db.GetDB().Model(&models.Transaction{}).
+		Select("COALESCE(SUM(transactions.amount - transactions.refunded_amount), 0) AS spend, "+
+			"COALESCE(SUM(transactions.points_earned - transactions.points_reversed), 0) AS points").
+		Joins("JOIN branches ON branches.id = transactions.branch_id").
+		Where("transactions.user_id = ? AND branches.store_id = ? AND transactions.date >= ?", userID, storeID, since).
+		Scan(&totals).Error; err != nil {
+		return 0, 0, err
+	}
+	return totals.Spend, totals.Points, nil
+}
+
 // Create creates a new transaction
 func (r *transactionRepository) Create(transaction *models.Transaction) error {
 	fmt.Println("transactionRepository.Create", transaction)
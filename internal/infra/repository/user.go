@@ -7,17 +7,24 @@ import (
 	"leal-technical-test/internal/domain/models"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // UserRepository interface
 type UserRepository interface {
 	GetAll() ([]models.User, error)
 	GetById(id uint) (*models.User, error)
+	GetByStoreIds(storeIDs []uint) ([]models.User, error)
+	BelongsToStores(id uint, storeIDs []uint) (bool, error)
 	Delete(id uint) error
 	Update(id uint, user *models.User) error
+	SetSuspended(id uint, suspended bool) error
+	SetRole(id uint, role string) error
+	SetStores(id uint, storeIDs []uint) error
 	Create(user *models.User) error
 	GetByEmail(email string) bool
 	GetIdByEmail(email string) (uint, error)
+	WithTx(tx config.IDatabaseConnection) UserRepository
 }
 
 // userRepository struct
@@ -30,19 +37,55 @@ func NewUserRepository(db config.IDatabaseConnection) UserRepository {
 	return &userRepository{db: db}
 }
 
+// WithTx returns a copy of the repository bound to the given transaction
+func (r *userRepository) WithTx(tx config.IDatabaseConnection) UserRepository {
+	return &userRepository{db: tx}
+}
+
 // GetAll retrieves all users
 func (r *userRepository) GetAll() ([]models.User, error) {
 	var users []models.User
-	if err := r.db.GetDB().Find(&users).Error; err != nil {
+	if err := r.db.GetDB().Preload("Stores").Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// storeMembers is the condition on users.id that matches the users who work at or hold a balance in the stores
+const storeMembers = "users.id IN (SELECT user_id FROM user_stores WHERE store_id IN ?) OR users.id IN (SELECT user_id FROM accumulated_rewards WHERE store_id IN ? AND deleted_at IS NULL)"
+
+// GetByStoreIds retrieves the users who work at or hold a balance in any of the stores
+func (r *userRepository) GetByStoreIds(storeIDs []uint) ([]models.User, error) {
+	users := []models.User{}
+	if len(storeIDs) == 0 {
+		return users, nil
+	}
+	if err := r.db.GetDB().Preload("Stores").Where(storeMembers, storeIDs, storeIDs).Find(&users).Error; err != nil {
 		return nil, err
 	}
 	return users, nil
 }
 
+// BelongsToStores reports whether a user works at or holds a balance in any of the stores
+func (r *userRepository) BelongsToStores(id uint, storeIDs []uint) (bool, error) {
+	if len(storeIDs) == 0 {
+		return false, nil
+	}
+	var count int64
+	err := r.db.GetDB().Model(&models.User{}).
+		Where("users.id = ?", id).
+		Where(storeMembers, storeIDs, storeIDs).
+		Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
 // GetById retrieves a user by its ID
 func (r *userRepository) GetById(id uint) (*models.User, error) {
 	var user models.User
-	if err := r.db.GetDB().First(&user, id).Error; err != nil {
+	if err := r.db.GetDB().Preload("Stores").First(&user, id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, fmt.Errorf("user not found")
 		}
@@ -62,18 +105,54 @@ func (r *userRepository) Delete(id uint) error {
 	return nil
 }
 
-// Update updates an existing user
+// Update updates an existing user. Stores and balances are never written through the user:
+// they change with SetStores and the accumulated reward service.
 func (r *userRepository) Update(id uint, user *models.User) error {
-	if err := r.db.GetDB().Model(&models.User{}).Where("id = ?", id).Updates(user).Error; err != nil {
+	if err := r.db.GetDB().Model(&models.User{}).Where("id = ?", id).Omit(clause.Associations).Updates(user).Error; err != nil {
 		return err
 	}
 	return nil
 }
 
-// Create creates a new user
+// SetSuspended suspends or reactivates a user. It is separate from Update because Updates skips false values.
+func (r *userRepository) SetSuspended(id uint, suspended bool) error {
+	result := r.db.GetDB().Model(&models.User{}).Where("id = ?", id).Update("suspended", suspended)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("user with ID %d not found", id)
+	}
+	return nil
+}
+
+// SetRole changes the role of a user
+func (r *userRepository) SetRole(id uint, role string) error {
+	result := r.db.GetDB().Model(&models.User{}).Where("id = ?", id).Update("role", role)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("user with ID %d not found", id)
+	}
+	return nil
+}
+
+// SetStores replaces the stores a user is linked to
+func (r *userRepository) SetStores(id uint, storeIDs []uint) error {
+	user := models.User{}
+	user.ID = id
+	stores := make([]models.Store, len(storeIDs))
+	for i, storeID := range storeIDs {
+		stores[i].ID = storeID
+	}
+	return r.db.GetDB().Model(&user).Association("Stores").Replace(stores)
+}
 
+// Create creates a new user without its associations, so a sign up can't link itself to stores or
+// bring its own balances
 func (r *userRepository) Create(user *models.User) error {
-	if err := r.db.GetDB().Create(user).Error; err != nil {
+	if err := r.db.GetDB().Omit(clause.Associations).Create(user).Error; err != nil {
 		return err
 	}
 	return nil
@@ -0,0 +1,43 @@
+package jobs
+
+import (
+	"context"
+	"leal-technical-test/config"
+	"leal-technical-test/internal/infra/repository"
+	"leal-technical-test/internal/services"
+	"time"
+)
+
+// idempotencyKeyPurgeInterval es cada cuánto se eliminan las claves de idempotencia vencidas
+const idempotencyKeyPurgeInterval = time.Hour
+
+// NewIdempotencyKeyPurgeJob crea el job que elimina las claves de idempotencia que ya no se pueden repetir
+func NewIdempotencyKeyPurgeJob() Job {
+	db := config.NewPostgresConnection()
+	uow := config.NewUnitOfWork(db)
+	repo := repository.NewTransactionRepository(db)
+	repoBranch := repository.NewBranchRepository(db)
+	repoCampaign := repository.NewCampaignRepository(db)
+	repoKey := repository.NewIdempotencyKeyRepository(db)
+	repoRefund := repository.NewRefundRepository(db)
+	repoTier := repository.NewTierRepository(db)
+	repoAcumulate := repository.NewAccumulatedRewardRepository(db)
+	repoLedger := repository.NewPointsLedgerRepository(db)
+	repoLot := repository.NewPointsLotRepository(db)
+	repoStore := repository.NewStoreRepository(db)
+	serviceAcumulate := services.NewAccumulatedRewardService(uow, repoAcumulate, repoLedger, repoLot, repoStore)
+	service := services.NewTransactionService(uow, repo, repoBranch, repoCampaign, repoKey, repoRefund, repoTier, serviceAcumulate)
+	logger := config.NewLogger()
+
+	return Job{
+		Name:     "idempotency-key-purge",
+		Interval: idempotencyKeyPurgeInterval,
+		Run: func(ctx context.Context) error {
+			purged, err := service.PurgeExpiredIdempotencyKeys(time.Now())
+			if purged > 0 {
+				logger.Info("Claves de idempotencia eliminadas: %d", purged)
+			}
+			return err
+		},
+	}
+}
@@ -0,0 +1,36 @@
+package jobs
+
+import (
+	"context"
+	"leal-technical-test/config"
+	"leal-technical-test/internal/infra/repository"
+	"leal-technical-test/internal/services"
+	"time"
+)
+
+// pointsExpiryInterval es cada cuánto se buscan lotes de puntos vencidos
+const pointsExpiryInterval = time.Hour
+
+// NewPointsExpiryJob crea el job que expira los lotes de puntos vencidos y lo registra en el libro de puntos
+func NewPointsExpiryJob() Job {
+	db := config.NewPostgresConnection()
+	uow := config.NewUnitOfWork(db)
+	repo := repository.NewAccumulatedRewardRepository(db)
+	ledgerRepo := repository.NewPointsLedgerRepository(db)
+	lotRepo := repository.NewPointsLotRepository(db)
+	storeRepo := repository.NewStoreRepository(db)
+	service := services.NewAccumulatedRewardService(uow, repo, ledgerRepo, lotRepo, storeRepo)
+	logger := config.NewLogger()
+
+	return Job{
+		Name:     "points-expiry",
+		Interval: pointsExpiryInterval,
+		Run: func(ctx context.Context) error {
+			expired, err := service.ExpirePoints(time.Now())
+			if expired > 0 {
+				logger.Info("Lotes de puntos expirados: %d", expired)
+			}
+			return err
+		},
+	}
+}
@@ -0,0 +1,105 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"leal-technical-test/config"
+	"sync"
+	"time"
+)
+
+// Job es una tarea que el servidor ejecuta periódicamente dentro del mismo proceso. Con Schedule
+// el job corre en las fechas que devuelve; sin él, corre al arrancar y después cada Interval.
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Schedule func(now time.Time) time.Time
+	Run      func(ctx context.Context) error
+}
+
+// Daily devuelve una planificación que ejecuta un job todos los días a la hora local indicada
+func Daily(hour int, minute int) func(now time.Time) time.Time {
+	return func(now time.Time) time.Time {
+		next := time.Date(now.Year(), now.Month(), now.Day(), hour, minute, 0, 0, now.Location())
+		if !next.After(now) {
+			next = next.AddDate(0, 0, 1)
+		}
+		return next
+	}
+}
+
+// Scheduler ejecuta los jobs registrados, cada uno en su propia goroutine
+type Scheduler struct {
+	jobs   []Job
+	logger config.ILogger
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewScheduler es el constructor del planificador de jobs
+func NewScheduler() *Scheduler {
+	return &Scheduler{
+		logger: config.NewLogger(),
+	}
+}
+
+// Register añade un job. Debe llamarse antes de Start.
+func (s *Scheduler) Register(job Job) {
+	s.jobs = append(s.jobs, job)
+}
+
+// Start lanza cada job en su propia goroutine según su planificación
+func (s *Scheduler) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+
+	for _, job := range s.jobs {
+		s.wg.Add(1)
+		go func(job Job) {
+			defer s.wg.Done()
+
+			next := time.Now()
+			if job.Schedule != nil {
+				next = job.Schedule(next)
+			}
+			for {
+				timer := time.NewTimer(time.Until(next))
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return
+				case <-timer.C:
+				}
+
+				s.runOnce(ctx, job)
+				if job.Schedule != nil {
+					next = job.Schedule(time.Now())
+				} else {
+					next = time.Now().Add(job.Interval)
+				}
+			}
+		}(job)
+	}
+	s.logger.Info("Jobs programados: %d", len(s.jobs))
+}
+
+// Stop detiene los jobs y espera a que termine la ejecución en curso
+func (s *Scheduler) Stop() {
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.wg.Wait()
+}
+
+// runOnce ejecuta un job sin dejar que un error o un panic detenga el planificador
+func (s *Scheduler) runOnce(ctx context.Context, job Job) {
+	defer func() {
+		if r := recover(); r != nil {
+			s.logger.Error(fmt.Sprintf("Job %s terminó con panic: %v", job.Name, r))
+		}
+	}()
+
+	if err := job.Run(ctx); err != nil {
+		s.logger.Error(fmt.Sprintf("Error en el job %s: %v", job.Name, err))
+	}
+}
@@ -0,0 +1,30 @@
+package jobs
+
+import (
+	"context"
+	"leal-technical-test/config"
+	"leal-technical-test/internal/infra/repository"
+	"leal-technical-test/internal/services"
+	"time"
+)
+
+// NewTierRecalculationJob crea el job nocturno que recalcula el nivel de los clientes de cada tienda
+func NewTierRecalculationJob() Job {
+	db := config.NewPostgresConnection()
+	uow := config.NewUnitOfWork(db)
+	repo := repository.NewTierRepository(db)
+	repoStore := repository.NewStoreRepository(db)
+	repoTransaction := repository.NewTransactionRepository(db)
+	service := services.NewTierService(uow, repo, repoStore, repoTransaction)
+	logger := config.NewLogger()
+
+	return Job{
+		Name:     "tier-recalculation",
+		Schedule: Daily(2, 0),
+		Run: func(ctx context.Context) error {
+			changed, err := service.RecalculateTiers(time.Now())
+			logger.Info("Cambios de nivel aplicados: %d", changed)
+			return err
+		},
+	}
+}
@@ -0,0 +1,36 @@
+package jobs
+
+import (
+	"context"
+	"leal-technical-test/config"
+	"leal-technical-test/internal/infra/repository"
+	"leal-technical-test/internal/services"
+	"time"
+)
+
+// tokenPurgeInterval es cada cuánto se eliminan los tokens de refresco y las revocaciones vencidas
+const tokenPurgeInterval = time.Hour
+
+// NewTokenPurgeJob crea el job que elimina los tokens de refresco vencidos y las entradas de la lista
+// de revocados cuyo token ya venció
+func NewTokenPurgeJob() Job {
+	db := config.NewPostgresConnection()
+	uow := config.NewUnitOfWork(db)
+	repo := repository.NewRefreshTokenRepository(db)
+	repoRevoked := repository.NewRevokedTokenRepository(db)
+	repoUser := repository.NewUserRepository(db)
+	service := services.NewSessionService(uow, repo, repoRevoked, repoUser, config.NewTokenManager())
+	logger := config.NewLogger()
+
+	return Job{
+		Name:     "token-purge",
+		Interval: tokenPurgeInterval,
+		Run: func(ctx context.Context) error {
+			purged, err := service.PurgeExpiredTokens(time.Now())
+			if purged > 0 {
+				logger.Info("Tokens vencidos eliminados: %d", purged)
+			}
+			return err
+		},
+	}
+}
@@ -0,0 +1,40 @@
+package jobs
+
+import (
+	"context"
+	"leal-technical-test/config"
+	"leal-technical-test/internal/infra/repository"
+	"leal-technical-test/internal/services"
+	"time"
+)
+
+// voucherExpiryInterval es cada cuánto se buscan vouchers vencidos sin usar
+const voucherExpiryInterval = 15 * time.Minute
+
+// NewVoucherExpiryJob crea el job que vence los vouchers sin usar y devuelve sus puntos y su stock
+func NewVoucherExpiryJob() Job {
+	db := config.NewPostgresConnection()
+	uow := config.NewUnitOfWork(db)
+	repo := repository.NewRedemptionRepository(db)
+	repoReward := repository.NewRewardRepository(db)
+	repoBranch := repository.NewBranchRepository(db)
+	repoAcumulate := repository.NewAccumulatedRewardRepository(db)
+	repoLedger := repository.NewPointsLedgerRepository(db)
+	repoLot := repository.NewPointsLotRepository(db)
+	repoStore := repository.NewStoreRepository(db)
+	serviceAcumulate := services.NewAccumulatedRewardService(uow, repoAcumulate, repoLedger, repoLot, repoStore)
+	service := services.NewRedemptionService(uow, repo, repoReward, repoBranch, serviceAcumulate)
+	logger := config.NewLogger()
+
+	return Job{
+		Name:     "voucher-expiry",
+		Interval: voucherExpiryInterval,
+		Run: func(ctx context.Context) error {
+			expired, err := service.ExpireVouchers(time.Now())
+			if expired > 0 {
+				logger.Info("Vouchers vencidos: %d", expired)
+			}
+			return err
+		},
+	}
+}
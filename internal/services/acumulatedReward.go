@@ -2,11 +2,12 @@ package services
 
 import (
 	"fmt"
+	"leal-technical-test/config"
 	"leal-technical-test/internal/domain/models"
-	"leal-technical-test/internal/infra/dtos"
 	"leal-technical-test/internal/infra/repository"
-
-	"gorm.io/gorm"
+	"math"
+	"sort"
+	"time"
 )
 
 // AccumulatedRewardService interface
@@ -14,19 +15,74 @@ type AccumulatedRewardService interface {
 	GetAllRewards() ([]models.AccumulatedReward, error)
 	GetRewardById(id uint) (*models.AccumulatedReward, error)
 	GetRewardByUserAndStore(userID uint, storeID uint) (*models.AccumulatedReward, error)
+	GetRewardsByUserId(userID uint) ([]models.AccumulatedReward, error)
 	CreateReward(id uint, transaction *models.Transaction) error
-	ClaimReward(claim dtos.ClaimRewardRequest) (string, error)
+	RedeemPoints(userID uint, storeID uint, points float64, redemptionID uint, description string) error
+	ReturnRedeemedPoints(userID uint, storeID uint, points float64, redemptionID uint, description string) error
+	AdjustPoints(userID uint, storeID uint, points float64, description string) error
+	RedeemCashback(userID uint, storeID uint, amount float64, description string) error
+	PayWithCashback(userID uint, storeID uint, transactionID uint, amount float64) error
+	ReturnCashbackPayment(userID uint, storeID uint, transactionID uint, amount float64) error
+	ReversePurchase(userID uint, storeID uint, transactionID uint, points float64, cashback float64, policy string) (float64, float64, error)
+	TransferPoints(transfer *models.PointTransfer) error
+	ExchangePoints(exchange *models.PointsExchange) error
+	GetLedger(userID uint, storeID uint) ([]models.PointsLedgerEntry, error)
+	GetExpiringPoints(userID uint, storeID uint, days int) ([]models.PointsLot, error)
+	ExpirePoints(now time.Time) (int, error)
+	WithTx(tx config.IDatabaseConnection) AccumulatedRewardService
+	WithScope(scope config.Scope) AccumulatedRewardService
 }
 
 // accumulatedRewardService struct
 type accumulatedRewardService struct {
-	repo repository.AccumulatedRewardRepository
+	uow        config.IUnitOfWork
+	repo       repository.AccumulatedRewardRepository
+	ledgerRepo repository.PointsLedgerRepository
+	lotRepo    repository.PointsLotRepository
+	storeRepo  repository.StoreRepository
+	scope      config.Scope
 }
 
 // NewAccumulatedRewardService constructor
-func NewAccumulatedRewardService(repo repository.AccumulatedRewardRepository) AccumulatedRewardService {
+func NewAccumulatedRewardService(
+	uow config.IUnitOfWork,
+	repo repository.AccumulatedRewardRepository,
+	ledgerRepo repository.PointsLedgerRepository,
+	lotRepo repository.PointsLotRepository,
+	storeRepo repository.StoreRepository,
+) AccumulatedRewardService {
 	return &accumulatedRewardService{
-		repo: repo,
+		uow:        uow,
+		repo:       repo,
+		ledgerRepo: ledgerRepo,
+		lotRepo:    lotRepo,
+		storeRepo:  storeRepo,
+		scope:      config.Unrestricted(),
+	}
+}
+
+// WithTx returns a copy of the service whose repositories join the given transaction
+func (s *accumulatedRewardService) WithTx(tx config.IDatabaseConnection) AccumulatedRewardService {
+	return &accumulatedRewardService{
+		uow:        config.NewUnitOfWork(tx),
+		repo:       s.repo.WithTx(tx),
+		ledgerRepo: s.ledgerRepo.WithTx(tx),
+		lotRepo:    s.lotRepo.WithTx(tx),
+		storeRepo:  s.storeRepo.WithTx(tx),
+		scope:      s.scope,
+	}
+}
+
+// WithScope returns a copy of the service limited to the stores of the scope. Only the balances read and
+// changed directly by store staff are checked; the other services check the scope before calling this one.
+func (s *accumulatedRewardService) WithScope(scope config.Scope) AccumulatedRewardService {
+	return &accumulatedRewardService{
+		uow:        s.uow,
+		repo:       s.repo,
+		ledgerRepo: s.ledgerRepo,
+		lotRepo:    s.lotRepo,
+		storeRepo:  s.storeRepo,
+		scope:      scope,
 	}
 }
 
@@ -36,7 +92,7 @@ func (s *accumulatedRewardService) GetAllRewards() ([]models.AccumulatedReward,
 	if err != nil {
 		return nil, err
 	}
-	return rewards, nil
+	return s.filterScope(rewards), nil
 }
 
 // GetRewardById retrieves an accumulated reward by its ID
@@ -45,11 +101,17 @@ func (s *accumulatedRewardService) GetRewardById(id uint) (*models.AccumulatedRe
 	if err != nil {
 		return nil, err
 	}
+	if err := checkScope(s.scope, reward.StoreID); err != nil {
+		return nil, err
+	}
 	return reward, nil
 }
 
 // GetRewardByUserAndStore retrieves an accumulated reward by UserID and StoreID
 func (s *accumulatedRewardService) GetRewardByUserAndStore(userID uint, storeID uint) (*models.AccumulatedReward, error) {
+	if err := checkScope(s.scope, storeID); err != nil {
+		return nil, err
+	}
 	reward, err := s.repo.GetByUserAndStore(userID, storeID)
 	if err != nil {
 		return nil, err
@@ -57,47 +119,575 @@ func (s *accumulatedRewardService) GetRewardByUserAndStore(userID uint, storeID
 	return reward, nil
 }
 
-// CreateReward creates a new accumulated reward
+// GetRewardsByUserId retrieves the accumulated rewards of a user in every store
+func (s *accumulatedRewardService) GetRewardsByUserId(userID uint) ([]models.AccumulatedReward, error) {
+	rewards, err := s.repo.GetByUserId(userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.filterScope(rewards), nil
+}
+
+// filterScope keeps the balances of the stores in the scope
+func (s *accumulatedRewardService) filterScope(rewards []models.AccumulatedReward) []models.AccumulatedReward {
+	inScope := make([]models.AccumulatedReward, 0, len(rewards))
+	for _, reward := range rewards {
+		if s.scope.Allows(reward.StoreID) {
+			inScope = append(inScope, reward)
+		}
+	}
+	return inScope
+}
+
+// CreateReward credits the points of a transaction as a new lot and records them in the ledger
 func (s *accumulatedRewardService) CreateReward(storeId uint, transaction *models.Transaction) error {
-	acumulatedReward := models.AccumulatedReward{
-		UserID:              transaction.UserID,
-		StoreID:             storeId,
-		PointsAccumulated:   transaction.PointsEarned,
-		CashbackAccumulated: transaction.CashbackEarned,
+	return s.uow.Do(func(tx config.IDatabaseConnection) error {
+		if err := s.repo.WithTx(tx).Accrue(transaction.UserID, storeId, transaction.PointsEarned, transaction.CashbackEarned); err != nil {
+			return err
+		}
+
+		transactionID := transaction.ID
+		if err := s.creditLot(tx, transaction.UserID, storeId, transaction.PointsEarned, &transactionID); err != nil {
+			return err
+		}
+		return s.ledgerRepo.WithTx(tx).Create(&models.PointsLedgerEntry{
+			UserID:        transaction.UserID,
+			StoreID:       storeId,
+			EntryType:     models.LedgerEntryEarn,
+			Points:        transaction.PointsEarned,
+			Cashback:      transaction.CashbackEarned,
+			TransactionID: &transactionID,
+			Description:   "purchase",
+		})
+	})
+}
+
+// RedeemPoints debits the points spent on a redemption, consuming the oldest lots first, and records
+// them in the ledger. The debit is a guarded atomic update, so a balance can't be double-spent.
+func (s *accumulatedRewardService) RedeemPoints(userID uint, storeID uint, points float64, redemptionID uint, description string) error {
+	return s.uow.Do(func(tx config.IDatabaseConnection) error {
+		if err := s.repo.WithTx(tx).DeductPoints(userID, storeID, points); err != nil {
+			return err
+		}
+		if _, err := s.consumeLots(tx, userID, storeID, points, &redemptionID, nil); err != nil {
+			return err
+		}
+		return s.ledgerRepo.WithTx(tx).Create(&models.PointsLedgerEntry{
+			UserID:       userID,
+			StoreID:      storeID,
+			EntryType:    models.LedgerEntryRedeem,
+			Points:       -points,
+			RedemptionID: &redemptionID,
+			Description:  description,
+		})
+	})
+}
+
+// ReturnRedeemedPoints gives back the points of a cancelled redemption to the lots they were taken from
+// and records them in the ledger. Points taken from a lot that already expired come back as a new lot.
+func (s *accumulatedRewardService) ReturnRedeemedPoints(userID uint, storeID uint, points float64, redemptionID uint, description string) error {
+	return s.uow.Do(func(tx config.IDatabaseConnection) error {
+		if err := s.repo.WithTx(tx).Accrue(userID, storeID, points, 0); err != nil {
+			return err
+		}
+		if err := s.restoreLots(tx, userID, storeID, points, redemptionID); err != nil {
+			return err
+		}
+		return s.ledgerRepo.WithTx(tx).Create(&models.PointsLedgerEntry{
+			UserID:       userID,
+			StoreID:      storeID,
+			EntryType:    models.LedgerEntryRedeemReversal,
+			Points:       points,
+			RedemptionID: &redemptionID,
+			Description:  description,
+		})
+	})
+}
+
+// AdjustPoints applies a manual correction to a balance and records it in the ledger
+func (s *accumulatedRewardService) AdjustPoints(userID uint, storeID uint, points float64, description string) error {
+	if points == 0 {
+		return fmt.Errorf("adjustment points must not be zero")
 	}
-	points, err := s.repo.GetByUserAndStore(transaction.UserID, storeId)
-	if err != nil {
-		if err == gorm.ErrRecordNotFound {
-			// No record found, create a new one
-			err := s.repo.Create(&acumulatedReward)
-			if err != nil {
+	if err := checkScope(s.scope, storeID); err != nil {
+		return err
+	}
+	return s.uow.Do(func(tx config.IDatabaseConnection) error {
+		if points > 0 {
+			if err := s.repo.WithTx(tx).Accrue(userID, storeID, points, 0); err != nil {
+				return err
+			}
+			if err := s.creditLot(tx, userID, storeID, points, nil); err != nil {
 				return err
 			}
 		} else {
+			if err := s.repo.WithTx(tx).DeductPoints(userID, storeID, -points); err != nil {
+				return err
+			}
+			if _, err := s.consumeLots(tx, userID, storeID, -points, nil, nil); err != nil {
+				return err
+			}
+		}
+		return s.ledgerRepo.WithTx(tx).Create(&models.PointsLedgerEntry{
+			UserID:      userID,
+			StoreID:     storeID,
+			EntryType:   models.LedgerEntryAdjust,
+			Points:      points,
+			Description: description,
+		})
+	})
+}
+
+// RedeemCashback spends part of the cashback balance. It is a separate path from points redemptions.
+func (s *accumulatedRewardService) RedeemCashback(userID uint, storeID uint, amount float64, description string) error {
+	if amount <= 0 {
+		return fmt.Errorf("cashback amount must be greater than zero")
+	}
+	if err := checkScope(s.scope, storeID); err != nil {
+		return err
+	}
+	return s.uow.Do(func(tx config.IDatabaseConnection) error {
+		if err := s.repo.WithTx(tx).DeductCashback(userID, storeID, amount); err != nil {
+			return err
+		}
+		return s.ledgerRepo.WithTx(tx).Create(&models.PointsLedgerEntry{
+			UserID:      userID,
+			StoreID:     storeID,
+			EntryType:   models.LedgerEntryCashbackRedeem,
+			Cashback:    -amount,
+			Description: description,
+		})
+	})
+}
+
+// PayWithCashback debits the cashback a customer used to pay part of a purchase and records it in the ledger
+func (s *accumulatedRewardService) PayWithCashback(userID uint, storeID uint, transactionID uint, amount float64) error {
+	if amount <= 0 {
+		return fmt.Errorf("cashback amount must be greater than zero")
+	}
+	return s.uow.Do(func(tx config.IDatabaseConnection) error {
+		if err := s.repo.WithTx(tx).DeductCashback(userID, storeID, amount); err != nil {
+			return err
+		}
+		return s.ledgerRepo.WithTx(tx).Create(&models.PointsLedgerEntry{
+			UserID:        userID,
+			StoreID:       storeID,
+			EntryType:     models.LedgerEntryCashbackPay,
+			Cashback:      -amount,
+			TransactionID: &transactionID,
+			Description:   "cashback used as payment",
+		})
+	})
+}
+
+// ReturnCashbackPayment gives back the cashback used to pay a refunded purchase and records it in the ledger
+func (s *accumulatedRewardService) ReturnCashbackPayment(userID uint, storeID uint, transactionID uint, amount float64) error {
+	if amount <= 0 {
+		return fmt.Errorf("cashback amount must be greater than zero")
+	}
+	return s.uow.Do(func(tx config.IDatabaseConnection) error {
+		if err := s.repo.WithTx(tx).Accrue(userID, storeID, 0, amount); err != nil {
+			return err
+		}
+		return s.ledgerRepo.WithTx(tx).Create(&models.PointsLedgerEntry{
+			UserID:        userID,
+			StoreID:       storeID,
+			EntryType:     models.LedgerEntryCashbackReturn,
+			Cashback:      amount,
+			TransactionID: &transactionID,
+			Description:   "cashback payment refunded",
+		})
+	})
+}
+
+// ReversePurchase claws back the points and cashback of a refunded purchase and records them in the ledger.
+// When the balance can't cover them, the negative balance policy of the store decides: allow_debt debits
+// everything, clamp debits only what is available and reject fails the refund. It returns the points and
+// cashback actually debited.
+func (s *accumulatedRewardService) ReversePurchase(userID uint, storeID uint, transactionID uint, points float64, cashback float64, policy string) (float64, float64, error) {
+	var pointsDebited, cashbackDebited float64
+	err := s.uow.Do(func(tx config.IDatabaseConnection) error {
+		balance, err := s.repo.WithTx(tx).GetByUserAndStoreForUpdate(userID, storeID)
+		if err != nil {
+			return err
+		}
+
+		pointsDebited, cashbackDebited = points, cashback
+		switch policy {
+		case models.NegativeBalanceAllowDebt:
+		case models.NegativeBalanceClamp:
+			pointsDebited = math.Max(0, math.Min(points, balance.PointsAccumulated))
+			cashbackDebited = math.Max(0, math.Min(cashback, balance.CashbackAccumulated))
+		case "", models.NegativeBalanceReject:
+			if balance.PointsAccumulated < points {
+				return repository.ErrInsufficientPoints
+			}
+			if balance.CashbackAccumulated < cashback {
+				return repository.ErrInsufficientCashback
+			}
+		default:
+			return fmt.Errorf("unknown negative balance policy %q", policy)
+		}
+
+		if pointsDebited == 0 && cashbackDebited == 0 {
+			return nil
+		}
+		if err := s.repo.WithTx(tx).Debit(userID, storeID, pointsDebited, cashbackDebited); err != nil {
+			return err
+		}
+		if _, err := s.consumeLots(tx, userID, storeID, pointsDebited, nil, &transactionID); err != nil {
+			return err
+		}
+		return s.ledgerRepo.WithTx(tx).Create(&models.PointsLedgerEntry{
+			UserID:        userID,
+			StoreID:       storeID,
+			EntryType:     models.LedgerEntryRefund,
+			Points:        -pointsDebited,
+			Cashback:      -cashbackDebited,
+			TransactionID: &transactionID,
+			Description:   "purchase refunded",
+		})
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	return pointsDebited, cashbackDebited, nil
+}
+
+// TransferPoints moves points from one balance to another of the same store and records both sides in the ledger.
+// The sender pays the points plus the fee; the receiver gets lots with the same expiry as the ones taken from the
+// sender, so a transfer can't extend the life of points. The transfer must already be created.
+func (s *accumulatedRewardService) TransferPoints(transfer *models.PointTransfer) error {
+	return s.uow.Do(func(tx config.IDatabaseConnection) error {
+		// Bloquear ambos saldos en orden de usuario para que dos traspasos cruzados no se bloqueen entre sí.
+		// El upsert crea el saldo del receptor si todavía no existe.
+		first, second := transfer.FromUserID, transfer.ToUserID
+		if second < first {
+			first, second = second, first
+		}
+		for _, userID := range []uint{first, second} {
+			if err := s.repo.WithTx(tx).Accrue(userID, transfer.StoreID, 0, 0); err != nil {
+				return err
+			}
+		}
+
+		if err := s.repo.WithTx(tx).DeductPoints(transfer.FromUserID, transfer.StoreID, transfer.Points+transfer.Fee); err != nil {
+			return err
+		}
+		taken, err := s.consumeLots(tx, transfer.FromUserID, transfer.StoreID, transfer.Points, nil, nil)
+		if err != nil {
+			return err
+		}
+		if transfer.Fee > 0 {
+			if _, err := s.consumeLots(tx, transfer.FromUserID, transfer.StoreID, transfer.Fee, nil, nil); err != nil {
+				return err
+			}
+		}
+
+		if err := s.repo.WithTx(tx).Accrue(transfer.ToUserID, transfer.StoreID, transfer.Points, 0); err != nil {
 			return err
 		}
-	} else {
-		// Record found, update it
-		acumulatedReward.PointsAccumulated += points.PointsAccumulated
-		acumulatedReward.CashbackAccumulated += points.CashbackAccumulated
-		err = s.repo.UpdateAcumulateReward(transaction.UserID, &acumulatedReward)
+		if err := s.creditTransferredLots(tx, transfer.ToUserID, transfer.StoreID, transfer.Points, taken); err != nil {
+			return err
+		}
+
+		transferID := transfer.ID
+		entries := []models.PointsLedgerEntry{
+			{
+				UserID:      transfer.FromUserID,
+				StoreID:     transfer.StoreID,
+				EntryType:   models.LedgerEntryTransferOut,
+				Points:      -transfer.Points,
+				TransferID:  &transferID,
+				Description: fmt.Sprintf("transfer to user %d", transfer.ToUserID),
+			},
+			{
+				UserID:      transfer.ToUserID,
+				StoreID:     transfer.StoreID,
+				EntryType:   models.LedgerEntryTransferIn,
+				Points:      transfer.Points,
+				TransferID:  &transferID,
+				Description: fmt.Sprintf("transfer from user %d", transfer.FromUserID),
+			},
+		}
+		if transfer.Fee > 0 {
+			entries = append(entries, models.PointsLedgerEntry{
+				UserID:      transfer.FromUserID,
+				StoreID:     transfer.StoreID,
+				EntryType:   models.LedgerEntryTransferFee,
+				Points:      -transfer.Fee,
+				TransferID:  &transferID,
+				Description: "transfer fee",
+			})
+		}
+		for i := range entries {
+			if err := s.ledgerRepo.WithTx(tx).Create(&entries[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ExchangePoints converts points of a user from one store into another and records both sides in the ledger.
+// The credited points start a new lot that follows the expiry policy of the destination store. The
+// conversion must already be created.
+func (s *accumulatedRewardService) ExchangePoints(exchange *models.PointsExchange) error {
+	return s.uow.Do(func(tx config.IDatabaseConnection) error {
+		// Bloquear ambos saldos en orden de tienda, igual que los traspasos bloquean en orden de usuario
+		first, second := exchange.FromStoreID, exchange.ToStoreID
+		if second < first {
+			first, second = second, first
+		}
+		for _, storeID := range []uint{first, second} {
+			if err := s.repo.WithTx(tx).Accrue(exchange.UserID, storeID, 0, 0); err != nil {
+				return err
+			}
+		}
+
+		if err := s.repo.WithTx(tx).DeductPoints(exchange.UserID, exchange.FromStoreID, exchange.PointsDebited); err != nil {
+			return err
+		}
+		if _, err := s.consumeLots(tx, exchange.UserID, exchange.FromStoreID, exchange.PointsDebited, nil, nil); err != nil {
+			return err
+		}
+		if err := s.repo.WithTx(tx).Accrue(exchange.UserID, exchange.ToStoreID, exchange.PointsCredited, 0); err != nil {
+			return err
+		}
+		if err := s.creditLot(tx, exchange.UserID, exchange.ToStoreID, exchange.PointsCredited, nil); err != nil {
+			return err
+		}
+
+		exchangeID := exchange.ID
+		if err := s.ledgerRepo.WithTx(tx).Create(&models.PointsLedgerEntry{
+			UserID:      exchange.UserID,
+			StoreID:     exchange.FromStoreID,
+			EntryType:   models.LedgerEntryExchangeOut,
+			Points:      -exchange.PointsDebited,
+			ExchangeID:  &exchangeID,
+			Description: fmt.Sprintf("exchanged into store %d", exchange.ToStoreID),
+		}); err != nil {
+			return err
+		}
+		return s.ledgerRepo.WithTx(tx).Create(&models.PointsLedgerEntry{
+			UserID:      exchange.UserID,
+			StoreID:     exchange.ToStoreID,
+			EntryType:   models.LedgerEntryExchangeIn,
+			Points:      exchange.PointsCredited,
+			ExchangeID:  &exchangeID,
+			Description: fmt.Sprintf("exchanged from store %d", exchange.FromStoreID),
+		})
+	})
+}
+
+// GetLedger retrieves the ledger entries of a user in a store
+func (s *accumulatedRewardService) GetLedger(userID uint, storeID uint) ([]models.PointsLedgerEntry, error) {
+	if err := checkScope(s.scope, storeID); err != nil {
+		return nil, err
+	}
+	entries, err := s.ledgerRepo.GetByUserAndStore(userID, storeID)
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// GetExpiringPoints retrieves the lots of a user in a store with points that expire in the next days
+func (s *accumulatedRewardService) GetExpiringPoints(userID uint, storeID uint, days int) ([]models.PointsLot, error) {
+	if days <= 0 {
+		return nil, fmt.Errorf("days must be greater than zero")
+	}
+	if err := checkScope(s.scope, storeID); err != nil {
+		return nil, err
+	}
+	lots, err := s.lotRepo.GetExpiring(userID, storeID, time.Now().AddDate(0, 0, days))
+	if err != nil {
+		return nil, err
+	}
+	return lots, nil
+}
+
+// ExpirePoints expires every lot whose expiry date has passed. Each lot is expired in its own
+// transaction: the remaining points are debited from the balance and recorded in the ledger.
+// It returns the number of lots expired.
+func (s *accumulatedRewardService) ExpirePoints(now time.Time) (int, error) {
+	lots, err := s.lotRepo.GetExpired(now)
+	if err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for _, lot := range lots {
+		debited := false
+		err := s.uow.Do(func(tx config.IDatabaseConnection) error {
+			// Bloquear primero el saldo, en el mismo orden que los canjes, y después el lote
+			balance, err := s.repo.WithTx(tx).GetByUserAndStoreForUpdate(lot.UserID, lot.StoreID)
+			if err != nil {
+				return err
+			}
+			current, err := s.lotRepo.WithTx(tx).GetByIdForUpdate(lot.ID)
+			if err != nil {
+				return err
+			}
+			if current.Remaining <= 0 || current.ExpiresAt == nil || current.ExpiresAt.After(now) {
+				return nil
+			}
+
+			points := math.Min(current.Remaining, balance.PointsAccumulated)
+			if err := s.lotRepo.WithTx(tx).UpdateRemaining(current.ID, 0); err != nil {
+				return err
+			}
+			if points <= 0 {
+				return nil
+			}
+			if err := s.repo.WithTx(tx).DeductPoints(lot.UserID, lot.StoreID, points); err != nil {
+				return err
+			}
+			debited = true
+			return s.ledgerRepo.WithTx(tx).Create(&models.PointsLedgerEntry{
+				UserID:        lot.UserID,
+				StoreID:       lot.StoreID,
+				EntryType:     models.LedgerEntryExpire,
+				Points:        -points,
+				TransactionID: current.TransactionID,
+				Description:   fmt.Sprintf("points expired (lot %d)", current.ID),
+			})
+		})
 		if err != nil {
+			return expired, fmt.Errorf("failed to expire lot %d: %v", lot.ID, err)
+		}
+		if debited {
+			expired++
+		}
+	}
+	return expired, nil
+}
+
+// creditLot stores credited points as a new lot that expires according to the policy of the store.
+// It must be called after the balance was credited: when the balance was in debt, the debt is paid
+// first and only the points left over go to the lot.
+func (s *accumulatedRewardService) creditLot(tx config.IDatabaseConnection, userID uint, storeID uint, points float64, transactionID *uint) error {
+	balance, err := s.repo.WithTx(tx).GetByUserAndStoreForUpdate(userID, storeID)
+	if err != nil {
+		return err
+	}
+	points = math.Min(points, balance.PointsAccumulated)
+	if points <= 0 {
+		return nil
+	}
+	store, err := s.storeRepo.WithTx(tx).GetById(storeID)
+	if err != nil {
+		return fmt.Errorf("store not found")
+	}
+
+	earnedAt := time.Now()
+	return s.lotRepo.WithTx(tx).Create(&models.PointsLot{
+		UserID:        userID,
+		StoreID:       storeID,
+		TransactionID: transactionID,
+		Points:        points,
+		Remaining:     points,
+		EarnedAt:      earnedAt,
+		ExpiresAt:     store.PointsExpiresAt(earnedAt),
+	})
+}
+
+// creditTransferredLots stores the points received in a transfer as lots that keep the earn and expiry dates
+// of the lots taken from the sender. Points not covered by those lots are credited as a new lot. Like creditLot,
+// it must be called after the balance was credited, so a debt is paid before any lot is created.
+func (s *accumulatedRewardService) creditTransferredLots(tx config.IDatabaseConnection, userID uint, storeID uint, points float64, taken []lotTake) error {
+	balance, err := s.repo.WithTx(tx).GetByUserAndStoreForUpdate(userID, storeID)
+	if err != nil {
+		return err
+	}
+	available := math.Min(points, balance.PointsAccumulated)
+
+	credited := 0.0
+	for _, take := range taken {
+		lotPoints := math.Min(take.points, available-credited)
+		if lotPoints <= 0 {
+			break
+		}
+		if err := s.lotRepo.WithTx(tx).Create(&models.PointsLot{
+			UserID:    userID,
+			StoreID:   storeID,
+			Points:    lotPoints,
+			Remaining: lotPoints,
+			EarnedAt:  take.lot.EarnedAt,
+			ExpiresAt: take.lot.ExpiresAt,
+		}); err != nil {
 			return err
 		}
+		credited += lotPoints
 	}
-	return nil
+	return s.creditLot(tx, userID, storeID, roundReward(available-credited), nil)
 }
 
-func (s *accumulatedRewardService) ClaimReward(claim dtos.ClaimRewardRequest) (string, error) {
-	if claim.PointsAccumulated < claim.RewardRequired {
-		return "", fmt.Errorf("insufficient points")
+// lotTake is the part of a lot taken by a debit
+type lotTake struct {
+	lot    models.PointsLot
+	points float64
+}
+
+// consumeLots takes points from the oldest lots first, or from the lot of transactionID first when it is
+// given, and returns what it took from each lot. The consumption of a redemption is recorded so a
+// cancellation can give the points back to the same lots.
+func (s *accumulatedRewardService) consumeLots(tx config.IDatabaseConnection, userID uint, storeID uint, points float64, redemptionID *uint, transactionID *uint) ([]lotTake, error) {
+	lots, err := s.lotRepo.WithTx(tx).GetAvailableForUpdate(userID, storeID)
+	if err != nil {
+		return nil, err
+	}
+	if transactionID != nil {
+		sort.SliceStable(lots, func(i, j int) bool {
+			return lots[i].TransactionID != nil && *lots[i].TransactionID == *transactionID &&
+				(lots[j].TransactionID == nil || *lots[j].TransactionID != *transactionID)
+		})
 	}
-	acumulate := models.AccumulatedReward{
-		PointsAccumulated: claim.PointsAccumulated - claim.RewardRequired,
+
+	var taken []lotTake
+	pending := points
+	for _, lot := range lots {
+		if pending <= 0 {
+			break
+		}
+		take := math.Min(lot.Remaining, pending)
+		if err := s.lotRepo.WithTx(tx).UpdateRemaining(lot.ID, lot.Remaining-take); err != nil {
+			return nil, err
+		}
+		if redemptionID != nil {
+			if err := s.lotRepo.WithTx(tx).CreateConsumption(&models.PointsLotConsumption{
+				LotID:        lot.ID,
+				RedemptionID: redemptionID,
+				Points:       take,
+			}); err != nil {
+				return nil, err
+			}
+		}
+		taken = append(taken, lotTake{lot: lot, points: take})
+		pending -= take
 	}
-	err := s.repo.UpdateAcumulateReward(claim.UserID, &acumulate)
+	return taken, nil
+}
+
+// restoreLots gives the points of a redemption back to the lots it consumed while they are still valid.
+// Points taken from lots that have expired since, or not taken from any lot, such as redemptions made
+// before lots existed, are credited as a new lot with a fresh expiry.
+func (s *accumulatedRewardService) restoreLots(tx config.IDatabaseConnection, userID uint, storeID uint, points float64, redemptionID uint) error {
+	consumptions, err := s.lotRepo.WithTx(tx).GetConsumptionsByRedemption(redemptionID)
 	if err != nil {
-		return "", err
+		return err
+	}
+
+	now := time.Now()
+	pending := points
+	for _, consumption := range consumptions {
+		restored, err := s.lotRepo.WithTx(tx).Restore(consumption.LotID, consumption.Points, now)
+		if err != nil {
+			return err
+		}
+		if restored {
+			pending -= consumption.Points
+		}
 	}
-	return claim.Description, nil
+	return s.creditLot(tx, userID, storeID, roundReward(pending), nil)
 }
@@ -14,19 +14,31 @@ type BranchService interface {
 	DeleteBranch(id uint) error
 	UpdateBranch(branch *models.Branch) error
 	CreateBranch(branch *models.Branch) error
+	WithScope(scope config.Scope) BranchService
 }
 
 // branchService struct
 type branchService struct {
-	repo repository.BranchRepository
-	log  config.ILogger
+	repo  repository.BranchRepository
+	log   config.ILogger
+	scope config.Scope
 }
 
 // NewBranchService constructor
 func NewBranchService(repo repository.BranchRepository) BranchService {
 	return &branchService{
-		repo: repo,
-		log:  config.NewLogger(),
+		repo:  repo,
+		log:   config.NewLogger(),
+		scope: config.Unrestricted(),
+	}
+}
+
+// WithScope returns a copy of the service limited to the stores of the scope
+func (s *branchService) WithScope(scope config.Scope) BranchService {
+	return &branchService{
+		repo:  s.repo,
+		log:   s.log,
+		scope: scope,
 	}
 }
 
@@ -37,7 +49,13 @@ func (s *branchService) GetAllBranches() ([]models.Branch, error) {
 		s.log.Error("Error retrieving all branches: ", err)
 		return nil, err
 	}
-	return branches, nil
+	inScope := make([]models.Branch, 0, len(branches))
+	for _, branch := range branches {
+		if s.scope.Allows(branch.StoreID) {
+			inScope = append(inScope, branch)
+		}
+	}
+	return inScope, nil
 }
 
 // GetBranchById retrieves a branch by its ID
@@ -47,12 +65,22 @@ func (s *branchService) GetBranchById(id uint) (*models.Branch, error) {
 		s.log.Error("Error retrieving branch by ID: ", err)
 		return nil, err
 	}
+	if err := checkScope(s.scope, branch.StoreID); err != nil {
+		return nil, err
+	}
 	return branch, nil
 }
 
 // DeleteBranch deletes a branch by its ID
 func (s *branchService) DeleteBranch(id uint) error {
-	err := s.repo.Delete(id)
+	branch, err := s.repo.GetById(id)
+	if err != nil {
+		return fmt.Errorf("branch does not exist")
+	}
+	if err := checkScope(s.scope, branch.StoreID); err != nil {
+		return err
+	}
+	err = s.repo.Delete(id)
 	if err != nil {
 		s.log.Error("Error deleting branch by ID: ", err)
 		return err
@@ -66,8 +94,19 @@ func (s *branchService) UpdateBranch(branch *models.Branch) error {
 	if !exist {
 		return fmt.Errorf("branch does not exist")
 	}
+	// Tanto la tienda actual de la sucursal como la nueva deben estar en el alcance
+	existing, err := s.repo.GetById(branch.ID)
+	if err != nil {
+		return fmt.Errorf("branch does not exist")
+	}
+	if err := checkScope(s.scope, existing.StoreID); err != nil {
+		return err
+	}
+	if err := checkScope(s.scope, branch.StoreID); err != nil {
+		return err
+	}
 
-	err := s.repo.Put(branch)
+	err = s.repo.Put(branch)
 	if err != nil {
 		s.log.Error("Error updating branch: ", err)
 		return err
@@ -77,6 +116,9 @@ func (s *branchService) UpdateBranch(branch *models.Branch) error {
 
 // CreateBranch creates a new branch
 func (s *branchService) CreateBranch(branch *models.Branch) error {
+	if err := checkScope(s.scope, branch.StoreID); err != nil {
+		return err
+	}
 	exis := s.repo.ExistsByName(branch.Name)
 	if exis {
 		return fmt.Errorf("branch already exists")
@@ -1,7 +1,10 @@
 package services
 
 import (
+	"fmt"
+	"leal-technical-test/config"
 	"leal-technical-test/internal/domain/models"
+	"leal-technical-test/internal/domain/rules"
 	"leal-technical-test/internal/infra/repository"
 )
 
@@ -12,17 +15,32 @@ type CampaignService interface {
 	DeleteCampaign(id uint) error
 	UpdateCampaign(id uint, campaign *models.Campaign) error
 	CreateCampaign(campaign *models.Campaign) error
+	GetCampaignTypes() []string
+	WithScope(scope config.Scope) CampaignService
 }
 
 // campaignService struct
 type campaignService struct {
-	repo repository.CampaignRepository
+	repo       repository.CampaignRepository
+	repoBranch repository.BranchRepository
+	scope      config.Scope
 }
 
 // NewCampaignService constructor
-func NewCampaignService(repo repository.CampaignRepository) CampaignService {
+func NewCampaignService(repo repository.CampaignRepository, repoBranch repository.BranchRepository) CampaignService {
 	return &campaignService{
-		repo: repo,
+		repo:       repo,
+		repoBranch: repoBranch,
+		scope:      config.Unrestricted(),
+	}
+}
+
+// WithScope returns a copy of the service limited to the stores of the scope
+func (s *campaignService) WithScope(scope config.Scope) CampaignService {
+	return &campaignService{
+		repo:       s.repo,
+		repoBranch: s.repoBranch,
+		scope:      scope,
 	}
 }
 
@@ -32,7 +50,13 @@ func (s *campaignService) GetAllCampaigns() ([]models.Campaign, error) {
 	if err != nil {
 		return nil, err
 	}
-	return campaigns, nil
+	inScope := make([]models.Campaign, 0, len(campaigns))
+	for _, campaign := range campaigns {
+		if s.scope.Allows(campaign.Branch.StoreID) {
+			inScope = append(inScope, campaign)
+		}
+	}
+	return inScope, nil
 }
 
 // GetCampaignById retrieves a campaign by its ID
@@ -41,11 +65,17 @@ func (s *campaignService) GetCampaignById(id uint) (*models.Campaign, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err := checkScope(s.scope, campaign.Branch.StoreID); err != nil {
+		return nil, err
+	}
 	return campaign, nil
 }
 
 // DeleteCampaign deletes a campaign by its ID
 func (s *campaignService) DeleteCampaign(id uint) error {
+	if _, err := s.GetCampaignById(id); err != nil {
+		return err
+	}
 	err := s.repo.Delete(id)
 	if err != nil {
 		return err
@@ -55,7 +85,21 @@ func (s *campaignService) DeleteCampaign(id uint) error {
 
 // UpdateCampaign updates an existing campaign
 func (s *campaignService) UpdateCampaign(id uint, campaign *models.Campaign) error {
-	err := s.repo.Update(id, campaign)
+	// Validar la campaña que resulta de aplicar los cambios sobre la existente
+	existing, err := s.GetCampaignById(id)
+	if err != nil {
+		return err
+	}
+	if campaign.BranchID != 0 {
+		if err := s.checkBranchScope(campaign.BranchID); err != nil {
+			return err
+		}
+	}
+	merged := mergeCampaign(*existing, *campaign)
+	if err := rules.Validate(merged); err != nil {
+		return err
+	}
+	err = s.repo.Update(id, &merged)
 	if err != nil {
 		return err
 	}
@@ -64,9 +108,66 @@ func (s *campaignService) UpdateCampaign(id uint, campaign *models.Campaign) err
 
 // CreateCampaign creates a new campaign
 func (s *campaignService) CreateCampaign(campaign *models.Campaign) error {
+	if err := rules.Validate(*campaign); err != nil {
+		return err
+	}
+	if err := s.checkBranchScope(campaign.BranchID); err != nil {
+		return err
+	}
 	err := s.repo.Create(campaign)
 	if err != nil {
 		return err
 	}
 	return nil
 }
+
+// GetCampaignTypes returns the campaign types that have a registered earning rule
+func (s *campaignService) GetCampaignTypes() []string {
+	return rules.Types()
+}
+
+// checkBranchScope checks that the store of a branch is in the scope
+func (s *campaignService) checkBranchScope(branchID uint) error {
+	branch, err := s.repoBranch.GetById(branchID)
+	if err != nil {
+		return fmt.Errorf("branch %d not found", branchID)
+	}
+	return checkScope(s.scope, branch.StoreID)
+}
+
+// mergeCampaign applies an update on top of an existing campaign. The priority, the exclusive flag,
+// the minimum amount and the limits are replaced as they come, so omitting them resets them and a
+// limit of 0 removes it; the other fields keep their value when they are not sent.
+func mergeCampaign(existing models.Campaign, update models.Campaign) models.Campaign {
+	if update.Name != "" {
+		existing.Name = update.Name
+	}
+	if update.BranchID != 0 {
+		existing.BranchID = update.BranchID
+	}
+	if !update.StartDate.IsZero() {
+		existing.StartDate = update.StartDate
+	}
+	if !update.EndDate.IsZero() {
+		existing.EndDate = update.EndDate
+	}
+	if update.Type != "" {
+		existing.Type = update.Type
+	}
+	if update.Percentage != 0 {
+		existing.Percentage = update.Percentage
+	}
+	if update.Multiplier != 0 {
+		existing.Multiplier = update.Multiplier
+	}
+	if update.BonusPoints != 0 {
+		existing.BonusPoints = update.BonusPoints
+	}
+	existing.MinAmount = update.MinAmount
+	existing.Priority = update.Priority
+	existing.Exclusive = update.Exclusive
+	existing.BudgetPoints = update.BudgetPoints
+	existing.PerCustomerCap = update.PerCustomerCap
+	existing.PerTransactionCap = update.PerTransactionCap
+	return existing
+}
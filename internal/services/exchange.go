@@ -0,0 +1,281 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"leal-technical-test/config"
+	"leal-technical-test/internal/domain/models"
+	"leal-technical-test/internal/infra/repository"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrExchangeRateNotFound is returned when there is no active rate between two stores
+var ErrExchangeRateNotFound = errors.New("no active exchange rate between these stores")
+
+// ErrExchangeDailyCap is returned when a conversion would exceed the daily cap of a pair of stores
+var ErrExchangeDailyCap = errors.New("exchange exceeds the daily cap")
+
+// ExchangeService interface
+type ExchangeService interface {
+	GetRatesByStoreId(storeID uint) ([]models.ExchangeRate, error)
+	CreateRate(rate *models.ExchangeRate) error
+	UpdateRate(id uint, rate *models.ExchangeRate) error
+	DeleteRate(id uint) error
+	ExchangePoints(userID uint, fromStoreID uint, toStoreID uint, points float64) (*models.PointsExchange, error)
+	GetExchangesByUserId(userID uint) ([]models.PointsExchange, error)
+	GetSettlements(storeID uint) ([]models.StoreSettlement, error)
+	WithScope(scope config.Scope) ExchangeService
+}
+
+// exchangeService struct
+type exchangeService struct {
+	uow                config.IUnitOfWork
+	repo               repository.ExchangeRepository
+	repoUser           repository.UserRepository
+	repoStore          repository.StoreRepository
+	accumulatedService AccumulatedRewardService
+	scope              config.Scope
+}
+
+// NewExchangeService constructor
+func NewExchangeService(
+	uow config.IUnitOfWork,
+	repo repository.ExchangeRepository,
+	repoUser repository.UserRepository,
+	repoStore repository.StoreRepository,
+	accumulatedService AccumulatedRewardService,
+) ExchangeService {
+	return &exchangeService{
+		uow:                uow,
+		repo:               repo,
+		repoUser:           repoUser,
+		repoStore:          repoStore,
+		accumulatedService: accumulatedService,
+		scope:              config.Unrestricted(),
+	}
+}
+
+// WithScope returns a copy of the service limited to the stores of the scope
+func (s *exchangeService) WithScope(scope config.Scope) ExchangeService {
+	return &exchangeService{
+		uow:                s.uow,
+		repo:               s.repo,
+		repoUser:           s.repoUser,
+		repoStore:          s.repoStore,
+		accumulatedService: s.accumulatedService,
+		scope:              scope,
+	}
+}
+
+// GetRatesByStoreId retrieves the rates that convert points from or into a store
+func (s *exchangeService) GetRatesByStoreId(storeID uint) ([]models.ExchangeRate, error) {
+	if err := checkScope(s.scope, storeID); err != nil {
+		return nil, err
+	}
+	rates, err := s.repo.GetRatesByStoreId(storeID)
+	if err != nil {
+		return nil, err
+	}
+	return rates, nil
+}
+
+// CreateRate publishes the rate between two stores
+func (s *exchangeService) CreateRate(rate *models.ExchangeRate) error {
+	if rate.FromStoreID == rate.ToStoreID {
+		return fmt.Errorf("an exchange rate needs two different stores")
+	}
+	if err := validateExchangeRate(rate); err != nil {
+		return err
+	}
+	if err := s.checkRateScope(rate); err != nil {
+		return err
+	}
+	for _, storeID := range []uint{rate.FromStoreID, rate.ToStoreID} {
+		if _, err := s.repoStore.GetById(storeID); err != nil {
+			return fmt.Errorf("store %d not found", storeID)
+		}
+	}
+	return s.repo.CreateRate(rate)
+}
+
+// UpdateRate replaces the terms of a rate
+func (s *exchangeService) UpdateRate(id uint, rate *models.ExchangeRate) error {
+	existing, err := s.repo.GetRateById(id)
+	if err != nil {
+		return err
+	}
+	if err := s.checkRateScope(existing); err != nil {
+		return err
+	}
+	if err := validateExchangeRate(rate); err != nil {
+		return err
+	}
+	return s.repo.UpdateRate(id, rate)
+}
+
+// DeleteRate deletes a rate. Past conversions keep the rate they were made with.
+func (s *exchangeService) DeleteRate(id uint) error {
+	existing, err := s.repo.GetRateById(id)
+	if err != nil {
+		return err
+	}
+	if err := s.checkRateScope(existing); err != nil {
+		return err
+	}
+	return s.repo.DeleteRate(id)
+}
+
+// checkRateScope requires both stores of a rate to be in the scope, since the rate binds both of them
+func (s *exchangeService) checkRateScope(rate *models.ExchangeRate) error {
+	for _, storeID := range []uint{rate.FromStoreID, rate.ToStoreID} {
+		if err := checkScope(s.scope, storeID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ExchangePoints converts points of a user from one store into another at the published rate and records
+// what the source store owes the destination store. The rate is locked for the whole conversion so the
+// daily cap of the pair holds under concurrent conversions.
+func (s *exchangeService) ExchangePoints(userID uint, fromStoreID uint, toStoreID uint, points float64) (*models.PointsExchange, error) {
+	if points <= 0 {
+		return nil, fmt.Errorf("exchange points must be greater than zero")
+	}
+	// Los puntos se gastan en la tienda de origen
+	if err := checkScope(s.scope, fromStoreID); err != nil {
+		return nil, err
+	}
+	user, err := s.repoUser.GetById(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.Suspended {
+		return nil, fmt.Errorf("%w: user %d", ErrUserSuspended, userID)
+	}
+	fromStore, err := s.repoStore.GetById(fromStoreID)
+	if err != nil {
+		return nil, fmt.Errorf("store not found")
+	}
+
+	var exchange *models.PointsExchange
+	err = s.uow.Do(func(tx config.IDatabaseConnection) error {
+		rate, err := s.repo.WithTx(tx).GetRateByPairForUpdate(fromStoreID, toStoreID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrExchangeRateNotFound
+			}
+			return err
+		}
+		if !rate.Active {
+			return ErrExchangeRateNotFound
+		}
+		if rate.MaxPointsPerExchange > 0 && points > rate.MaxPointsPerExchange {
+			return fmt.Errorf("exchange must be at most %.2f points", rate.MaxPointsPerExchange)
+		}
+		if rate.DailyCap > 0 {
+			convertedToday, err := s.repo.WithTx(tx).SumDebitedSince(fromStoreID, toStoreID, startOfDay(time.Now()))
+			if err != nil {
+				return err
+			}
+			if convertedToday+points > rate.DailyCap {
+				return fmt.Errorf("%w of %.2f points", ErrExchangeDailyCap, rate.DailyCap)
+			}
+		}
+
+		// El valor de los puntos es lo que se gastó en la tienda de origen para ganarlos
+		settlementValue := 0.0
+		if fromStore.ConversionFactor > 0 {
+			settlementValue = roundReward(points / fromStore.ConversionFactor)
+		}
+		exchange = &models.PointsExchange{
+			UserID:          userID,
+			ExchangeRateID:  rate.ID,
+			FromStoreID:     fromStoreID,
+			ToStoreID:       toStoreID,
+			Rate:            rate.Rate,
+			PointsDebited:   points,
+			PointsCredited:  roundReward(points * rate.Rate),
+			SettlementValue: settlementValue,
+		}
+		if exchange.PointsCredited <= 0 {
+			return fmt.Errorf("exchange is too small to credit any points")
+		}
+		if err := s.repo.WithTx(tx).CreateExchange(exchange); err != nil {
+			return err
+		}
+		return s.accumulatedService.WithTx(tx).ExchangePoints(exchange)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return exchange, nil
+}
+
+// GetExchangesByUserId retrieves the conversions of a user
+func (s *exchangeService) GetExchangesByUserId(userID uint) ([]models.PointsExchange, error) {
+	exchanges, err := s.repo.GetExchangesByUserId(userID)
+	if err != nil {
+		return nil, err
+	}
+	inScope := make([]models.PointsExchange, 0, len(exchanges))
+	for _, exchange := range exchanges {
+		if s.scope.Allows(exchange.FromStoreID) || s.scope.Allows(exchange.ToStoreID) {
+			inScope = append(inScope, exchange)
+		}
+	}
+	return inScope, nil
+}
+
+// GetSettlements nets what a store owes and is owed by every other store it exchanged points with
+func (s *exchangeService) GetSettlements(storeID uint) ([]models.StoreSettlement, error) {
+	if err := checkScope(s.scope, storeID); err != nil {
+		return nil, err
+	}
+	totals, err := s.repo.GetSettlementTotals(storeID)
+	if err != nil {
+		return nil, err
+	}
+
+	byCounterparty := map[uint]*models.StoreSettlement{}
+	for _, total := range totals {
+		counterpartyID := total.FromStoreID
+		if counterpartyID == storeID {
+			counterpartyID = total.ToStoreID
+		}
+		settlement, ok := byCounterparty[counterpartyID]
+		if !ok {
+			settlement = &models.StoreSettlement{StoreID: storeID, CounterpartyID: counterpartyID}
+			byCounterparty[counterpartyID] = settlement
+		}
+		if total.ToStoreID == storeID {
+			settlement.Receivable += total.Value
+		} else {
+			settlement.Payable += total.Value
+		}
+	}
+
+	settlements := make([]models.StoreSettlement, 0, len(byCounterparty))
+	for _, settlement := range byCounterparty {
+		settlement.Balance = roundReward(settlement.Receivable - settlement.Payable)
+		settlements = append(settlements, *settlement)
+	}
+	sort.Slice(settlements, func(i, j int) bool {
+		return settlements[i].CounterpartyID < settlements[j].CounterpartyID
+	})
+	return settlements, nil
+}
+
+// validateExchangeRate checks the terms of a rate
+func validateExchangeRate(rate *models.ExchangeRate) error {
+	if rate.Rate <= 0 {
+		return fmt.Errorf("exchange rate must be greater than zero")
+	}
+	if rate.MaxPointsPerExchange < 0 || rate.DailyCap < 0 {
+		return fmt.Errorf("exchange limits must not be negative")
+	}
+	return nil
+}
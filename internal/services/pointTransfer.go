@@ -0,0 +1,146 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"leal-technical-test/config"
+	"leal-technical-test/internal/domain/models"
+	"leal-technical-test/internal/infra/repository"
+	"time"
+)
+
+// ErrUserSuspended is returned when a suspended user takes part in a transfer
+var ErrUserSuspended = errors.New("user is suspended")
+
+// ErrAccountTooNew is returned when a user was created too recently to transfer points
+var ErrAccountTooNew = errors.New("account is too new to transfer points")
+
+// ErrTransferDailyCap is returned when a transfer would exceed the points a user can send per day
+var ErrTransferDailyCap = errors.New("transfer exceeds the daily cap")
+
+// PointTransferService interface
+type PointTransferService interface {
+	TransferPoints(transfer *models.PointTransfer) error
+	GetTransfersByUserAndStore(userID uint, storeID uint) ([]models.PointTransfer, error)
+	WithScope(scope config.Scope) PointTransferService
+}
+
+// pointTransferService struct
+type pointTransferService struct {
+	uow                config.IUnitOfWork
+	repo               repository.PointTransferRepository
+	repoUser           repository.UserRepository
+	repoStore          repository.StoreRepository
+	accumulatedService AccumulatedRewardService
+	scope              config.Scope
+}
+
+// NewPointTransferService constructor
+func NewPointTransferService(
+	uow config.IUnitOfWork,
+	repo repository.PointTransferRepository,
+	repoUser repository.UserRepository,
+	repoStore repository.StoreRepository,
+	accumulatedService AccumulatedRewardService,
+) PointTransferService {
+	return &pointTransferService{
+		uow:                uow,
+		repo:               repo,
+		repoUser:           repoUser,
+		repoStore:          repoStore,
+		accumulatedService: accumulatedService,
+		scope:              config.Unrestricted(),
+	}
+}
+
+// WithScope returns a copy of the service limited to the stores of the scope
+func (s *pointTransferService) WithScope(scope config.Scope) PointTransferService {
+	return &pointTransferService{
+		uow:                s.uow,
+		repo:               s.repo,
+		repoUser:           s.repoUser,
+		repoStore:          s.repoStore,
+		accumulatedService: s.accumulatedService,
+		scope:              scope,
+	}
+}
+
+// TransferPoints moves points between two users of a store within the limits of the store.
+// The fee is calculated here and charged to the sender on top of the points.
+func (s *pointTransferService) TransferPoints(transfer *models.PointTransfer) error {
+	if transfer.FromUserID == transfer.ToUserID {
+		return fmt.Errorf("a user can't transfer points to themselves")
+	}
+	if transfer.Points <= 0 {
+		return fmt.Errorf("transfer points must be greater than zero")
+	}
+	if err := checkScope(s.scope, transfer.StoreID); err != nil {
+		return err
+	}
+
+	store, err := s.repoStore.GetById(transfer.StoreID)
+	if err != nil {
+		return fmt.Errorf("store not found")
+	}
+	if transfer.Points < store.TransferMinPoints {
+		return fmt.Errorf("transfer must be at least %.2f points", store.TransferMinPoints)
+	}
+	if store.TransferMaxPoints > 0 && transfer.Points > store.TransferMaxPoints {
+		return fmt.Errorf("transfer must be at most %.2f points", store.TransferMaxPoints)
+	}
+
+	now := time.Now()
+	for _, userID := range []uint{transfer.FromUserID, transfer.ToUserID} {
+		user, err := s.repoUser.GetById(userID)
+		if err != nil {
+			return err
+		}
+		if user.Suspended {
+			return fmt.Errorf("%w: user %d", ErrUserSuspended, userID)
+		}
+		if user.CreatedAt.After(now.AddDate(0, 0, -store.TransferMinAccountDays)) {
+			return fmt.Errorf("%w: user %d must be at least %d days old", ErrAccountTooNew, userID, store.TransferMinAccountDays)
+		}
+	}
+
+	transfer.Fee = roundReward(transfer.Points * store.TransferFeePercentage / 100)
+	return s.uow.Do(func(tx config.IDatabaseConnection) error {
+		if err := s.repo.WithTx(tx).Create(transfer); err != nil {
+			return err
+		}
+		if err := s.accumulatedService.WithTx(tx).TransferPoints(transfer); err != nil {
+			return err
+		}
+
+		// El saldo del emisor queda bloqueado hasta el final de la transacción, así que la suma ya
+		// incluye cualquier traspaso concurrente del mismo usuario y este mismo traspaso
+		if store.TransferDailyCap > 0 {
+			sentToday, err := s.repo.WithTx(tx).SumSentSince(transfer.FromUserID, transfer.StoreID, startOfDay(now))
+			if err != nil {
+				return err
+			}
+			if sentToday > store.TransferDailyCap {
+				return fmt.Errorf("%w of %.2f points", ErrTransferDailyCap, store.TransferDailyCap)
+			}
+		}
+		return nil
+	})
+}
+
+// GetTransfersByUserAndStore retrieves the transfers sent or received by a user in a store
+func (s *pointTransferService) GetTransfersByUserAndStore(userID uint, storeID uint) ([]models.PointTransfer, error) {
+	if err := checkScope(s.scope, storeID); err != nil {
+		return nil, err
+	}
+	transfers, err := s.repo.GetByUserAndStore(userID, storeID)
+	if err != nil {
+		return nil, err
+	}
+	return transfers, nil
+}
+
+// startOfDay is midnight of the day of t in its location
+func startOfDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
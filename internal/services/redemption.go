@@ -0,0 +1,325 @@
+package services
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"leal-technical-test/config"
+	"leal-technical-test/internal/domain/models"
+	"leal-technical-test/internal/infra/repository"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// ErrRewardUnavailable is returned when a reward is claimed outside its availability window or at a branch where it isn't offered
+var ErrRewardUnavailable = errors.New("reward is not available")
+
+// ErrRewardLimitReached is returned when a user already claimed a reward as many times as it allows
+var ErrRewardLimitReached = errors.New("reward claim limit reached")
+
+// ErrVoucherNotFound is returned when no redemption has the given voucher code
+var ErrVoucherNotFound = errors.New("voucher not found")
+
+// ErrVoucherWrongStore is returned when a voucher is presented at a branch of another store
+var ErrVoucherWrongStore = errors.New("voucher belongs to another store")
+
+// ErrVoucherNotUsable is returned when a voucher was already used, cancelled or expired
+var ErrVoucherNotUsable = errors.New("voucher can't be used")
+
+// voucherAlphabet leaves out 0, O, 1 and I so a code can be read aloud and typed without mistakes
+const voucherAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// voucherLength gives 60 random bits per code
+const voucherLength = 12
+
+// RedemptionService interface
+type RedemptionService interface {
+	ClaimReward(userID uint, rewardID uint, branchID *uint) (*models.Redemption, error)
+	FulfillRedemption(id uint) error
+	CancelRedemption(id uint) error
+	UseVoucher(branchID uint, code string) (*models.Redemption, error)
+	ExpireVouchers(now time.Time) (int, error)
+	GetRedemptionById(id uint) (*models.Redemption, error)
+	GetRedemptionsByStoreId(storeID uint, status string) ([]models.Redemption, error)
+	GetRedemptionsByUserId(userID uint) ([]models.Redemption, error)
+	WithScope(scope config.Scope) RedemptionService
+}
+
+// redemptionService struct
+type redemptionService struct {
+	uow                config.IUnitOfWork
+	repo               repository.RedemptionRepository
+	repoReward         repository.RewardRepository
+	repoBranch         repository.BranchRepository
+	accumulatedService AccumulatedRewardService
+	scope              config.Scope
+}
+
+// NewRedemptionService constructor
+func NewRedemptionService(
+	uow config.IUnitOfWork,
+	repo repository.RedemptionRepository,
+	repoReward repository.RewardRepository,
+	repoBranch repository.BranchRepository,
+	accumulatedService AccumulatedRewardService,
+) RedemptionService {
+	return &redemptionService{
+		uow:                uow,
+		repo:               repo,
+		repoReward:         repoReward,
+		repoBranch:         repoBranch,
+		accumulatedService: accumulatedService,
+		scope:              config.Unrestricted(),
+	}
+}
+
+// WithScope returns a copy of the service limited to the stores of the scope
+func (s *redemptionService) WithScope(scope config.Scope) RedemptionService {
+	return &redemptionService{
+		uow:                s.uow,
+		repo:               s.repo,
+		repoReward:         s.repoReward,
+		repoBranch:         s.repoBranch,
+		accumulatedService: s.accumulatedService,
+		scope:              scope,
+	}
+}
+
+// ClaimReward reserves a reward for a user and debits its points in the same transaction. The reward is
+// locked while its availability, eligible branches, per-user limits and stock are checked, so concurrent
+// claims can't exceed any of them. The redemption gets a voucher code that expires according to the store.
+func (s *redemptionService) ClaimReward(userID uint, rewardID uint, branchID *uint) (*models.Redemption, error) {
+	var redemption *models.Redemption
+	err := s.uow.Do(func(tx config.IDatabaseConnection) error {
+		reward, err := s.repoReward.WithTx(tx).GetByIdForUpdate(rewardID)
+		if err != nil {
+			return fmt.Errorf("reward not found")
+		}
+		if err := checkScope(s.scope, reward.StoreID); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if !reward.AvailableAt(now) {
+			return fmt.Errorf("%w: outside its availability window", ErrRewardUnavailable)
+		}
+		if len(reward.Branches) > 0 && (branchID == nil || !reward.EligibleBranch(*branchID)) {
+			return fmt.Errorf("%w: it can only be claimed at its eligible branches", ErrRewardUnavailable)
+		}
+		if err := s.checkClaimLimits(tx, userID, reward, now); err != nil {
+			return err
+		}
+		if reward.Stock != nil {
+			if err := s.repoReward.WithTx(tx).DecrementStock(reward.ID); err != nil {
+				return err
+			}
+		}
+
+		code, err := newVoucherCode()
+		if err != nil {
+			return err
+		}
+		redemption = &models.Redemption{
+			UserID:      userID,
+			StoreID:     reward.StoreID,
+			RewardID:    reward.ID,
+			BranchID:    branchID,
+			PointsSpent: reward.PointsRequired,
+			Status:      models.RedemptionStatusReserved,
+			VoucherCode: &code,
+		}
+		if reward.Store.VoucherValidityDays > 0 {
+			expiresAt := now.AddDate(0, 0, reward.Store.VoucherValidityDays)
+			redemption.ExpiresAt = &expiresAt
+		}
+		if err := s.repo.WithTx(tx).Create(redemption); err != nil {
+			return err
+		}
+		return s.accumulatedService.WithTx(tx).RedeemPoints(userID, reward.StoreID, reward.PointsRequired, redemption.ID, reward.Description)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return redemption, nil
+}
+
+// checkClaimLimits checks that a user can claim a reward once more, over its lifetime and in the current period
+func (s *redemptionService) checkClaimLimits(tx config.IDatabaseConnection, userID uint, reward *models.Reward, now time.Time) error {
+	if reward.PerUserLimit > 0 {
+		claims, err := s.repo.WithTx(tx).CountActiveByUserAndReward(userID, reward.ID, nil)
+		if err != nil {
+			return err
+		}
+		if claims >= int64(reward.PerUserLimit) {
+			return fmt.Errorf("%w: %d per user", ErrRewardLimitReached, reward.PerUserLimit)
+		}
+	}
+	if reward.PeriodLimit > 0 && reward.PeriodDays > 0 {
+		since := now.AddDate(0, 0, -reward.PeriodDays)
+		claims, err := s.repo.WithTx(tx).CountActiveByUserAndReward(userID, reward.ID, &since)
+		if err != nil {
+			return err
+		}
+		if claims >= int64(reward.PeriodLimit) {
+			return fmt.Errorf("%w: %d every %d days", ErrRewardLimitReached, reward.PeriodLimit, reward.PeriodDays)
+		}
+	}
+	return nil
+}
+
+// FulfillRedemption marks a reserved redemption as handed over to the customer
+func (s *redemptionService) FulfillRedemption(id uint) error {
+	redemption, err := s.repo.GetById(id)
+	if err != nil {
+		return fmt.Errorf("redemption not found")
+	}
+	if err := checkScope(s.scope, redemption.StoreID); err != nil {
+		return err
+	}
+	return s.repo.UpdateStatus(id, models.RedemptionStatusReserved, models.RedemptionStatusFulfilled)
+}
+
+// CancelRedemption cancels a reserved redemption, gives its points back and returns the unit to the stock
+func (s *redemptionService) CancelRedemption(id uint) error {
+	redemption, err := s.repo.GetById(id)
+	if err != nil {
+		return fmt.Errorf("redemption not found")
+	}
+	if err := checkScope(s.scope, redemption.StoreID); err != nil {
+		return err
+	}
+
+	return s.uow.Do(func(tx config.IDatabaseConnection) error {
+		if err := s.repo.WithTx(tx).UpdateStatus(id, models.RedemptionStatusReserved, models.RedemptionStatusCancelled); err != nil {
+			return err
+		}
+		if err := s.repoReward.WithTx(tx).RestoreStock(redemption.RewardID); err != nil {
+			return err
+		}
+		return s.accumulatedService.WithTx(tx).ReturnRedeemedPoints(redemption.UserID, redemption.StoreID, redemption.PointsSpent, redemption.ID, "redemption cancelled")
+	})
+}
+
+// UseVoucher marks a voucher as used at a branch, checking that the branch belongs to the store of the reward
+// and is one of its eligible branches
+func (s *redemptionService) UseVoucher(branchID uint, code string) (*models.Redemption, error) {
+	redemption, err := s.repo.GetByVoucherCode(normalizeVoucherCode(code))
+	if err != nil {
+		return nil, err
+	}
+	if redemption == nil {
+		return nil, ErrVoucherNotFound
+	}
+	if err := checkScope(s.scope, redemption.StoreID); err != nil {
+		return nil, err
+	}
+	branch, err := s.repoBranch.GetById(branchID)
+	if err != nil {
+		return nil, fmt.Errorf("branch not found")
+	}
+	if branch.StoreID != redemption.StoreID {
+		return nil, ErrVoucherWrongStore
+	}
+	if !redemption.Reward.EligibleBranch(branchID) {
+		return nil, fmt.Errorf("%w: the reward is not offered at this branch", ErrRewardUnavailable)
+	}
+
+	now := time.Now()
+	if redemption.Status != models.RedemptionStatusReserved {
+		return nil, fmt.Errorf("%w: it is %s", ErrVoucherNotUsable, redemption.Status)
+	}
+	if redemption.ExpiresAt != nil && !now.Before(*redemption.ExpiresAt) {
+		return nil, fmt.Errorf("%w: it expired", ErrVoucherNotUsable)
+	}
+	if err := s.repo.UseVoucher(redemption.ID, branchID, now); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrVoucherNotUsable, err)
+	}
+	return s.repo.GetById(redemption.ID)
+}
+
+// ExpireVouchers expires the reserved redemptions whose voucher was not used in time, giving back their
+// points and stock. Each redemption is expired in its own transaction. It returns how many expired.
+func (s *redemptionService) ExpireVouchers(now time.Time) (int, error) {
+	redemptions, err := s.repo.GetExpiredVouchers(now)
+	if err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for _, redemption := range redemptions {
+		err := s.uow.Do(func(tx config.IDatabaseConnection) error {
+			if err := s.repo.WithTx(tx).UpdateStatus(redemption.ID, models.RedemptionStatusReserved, models.RedemptionStatusExpired); err != nil {
+				return err
+			}
+			if err := s.repoReward.WithTx(tx).RestoreStock(redemption.RewardID); err != nil {
+				return err
+			}
+			return s.accumulatedService.WithTx(tx).ReturnRedeemedPoints(redemption.UserID, redemption.StoreID, redemption.PointsSpent, redemption.ID, "voucher expired")
+		})
+		if err != nil {
+			return expired, fmt.Errorf("failed to expire redemption %d: %v", redemption.ID, err)
+		}
+		expired++
+	}
+	return expired, nil
+}
+
+// GetRedemptionById retrieves a redemption by its ID
+func (s *redemptionService) GetRedemptionById(id uint) (*models.Redemption, error) {
+	redemption, err := s.repo.GetById(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkScope(s.scope, redemption.StoreID); err != nil {
+		return nil, err
+	}
+	return redemption, nil
+}
+
+// GetRedemptionsByStoreId retrieves the redemptions of a store, optionally filtered by status
+func (s *redemptionService) GetRedemptionsByStoreId(storeID uint, status string) ([]models.Redemption, error) {
+	if err := checkScope(s.scope, storeID); err != nil {
+		return nil, err
+	}
+	redemptions, err := s.repo.GetByStoreId(storeID, status)
+	if err != nil {
+		return nil, err
+	}
+	return redemptions, nil
+}
+
+// GetRedemptionsByUserId retrieves the redemptions of a user
+func (s *redemptionService) GetRedemptionsByUserId(userID uint) ([]models.Redemption, error) {
+	redemptions, err := s.repo.GetByUserId(userID)
+	if err != nil {
+		return nil, err
+	}
+	inScope := make([]models.Redemption, 0, len(redemptions))
+	for _, redemption := range redemptions {
+		if s.scope.Allows(redemption.StoreID) {
+			inScope = append(inScope, redemption)
+		}
+	}
+	return inScope, nil
+}
+
+// newVoucherCode generates a random voucher code with crypto/rand
+func newVoucherCode() (string, error) {
+	code := make([]byte, voucherLength)
+	max := big.NewInt(int64(len(voucherAlphabet)))
+	for i := range code {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate voucher code: %v", err)
+		}
+		code[i] = voucherAlphabet[n.Int64()]
+	}
+	return string(code), nil
+}
+
+// normalizeVoucherCode accepts a code typed in lower case or with spaces and dashes
+func normalizeVoucherCode(code string) string {
+	code = strings.ToUpper(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
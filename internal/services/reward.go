@@ -2,6 +2,7 @@ package services
 
 import (
 	"fmt"
+	"leal-technical-test/config"
 	"leal-technical-test/internal/domain/models"
 	"leal-technical-test/internal/infra/repository"
 )
@@ -14,17 +15,31 @@ type RewardService interface {
 	DeleteReward(id uint) error
 	UpdateReward(id uint, reward *models.Reward) error
 	CreateReward(reward *models.Reward) error
+	WithScope(scope config.Scope) RewardService
 }
 
 // rewardService struct
 type rewardService struct {
-	repo repository.RewardRepository
+	repo       repository.RewardRepository
+	repoBranch repository.BranchRepository
+	scope      config.Scope
 }
 
 // NewRewardService constructor
-func NewRewardService(repo repository.RewardRepository) RewardService {
+func NewRewardService(repo repository.RewardRepository, repoBranch repository.BranchRepository) RewardService {
 	return &rewardService{
-		repo: repo,
+		repo:       repo,
+		repoBranch: repoBranch,
+		scope:      config.Unrestricted(),
+	}
+}
+
+// WithScope returns a copy of the service limited to the stores of the scope
+func (s *rewardService) WithScope(scope config.Scope) RewardService {
+	return &rewardService{
+		repo:       s.repo,
+		repoBranch: s.repoBranch,
+		scope:      scope,
 	}
 }
 
@@ -34,7 +49,13 @@ func (s *rewardService) GetAllRewards() ([]models.Reward, error) {
 	if err != nil {
 		return nil, err
 	}
-	return rewards, nil
+	inScope := make([]models.Reward, 0, len(rewards))
+	for _, reward := range rewards {
+		if s.scope.Allows(reward.StoreID) {
+			inScope = append(inScope, reward)
+		}
+	}
+	return inScope, nil
 }
 
 // GetRewardById retrieves a reward by its ID
@@ -43,11 +64,17 @@ func (s *rewardService) GetRewardById(id uint) (*models.Reward, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err := checkScope(s.scope, reward.StoreID); err != nil {
+		return nil, err
+	}
 	return reward, nil
 }
 
 // GetRewardsByStoreId retrieves rewards by StoreID
 func (s *rewardService) GetRewardsByStoreId(storeID uint) ([]models.Reward, error) {
+	if err := checkScope(s.scope, storeID); err != nil {
+		return nil, err
+	}
 	rewards, err := s.repo.GetByStoreId(storeID)
 	if err != nil {
 		return nil, err
@@ -57,6 +84,9 @@ func (s *rewardService) GetRewardsByStoreId(storeID uint) ([]models.Reward, erro
 
 // DeleteReward deletes a reward by its ID
 func (s *rewardService) DeleteReward(id uint) error {
+	if _, err := s.GetRewardById(id); err != nil {
+		return err
+	}
 	err := s.repo.Delete(id)
 	if err != nil {
 		return err
@@ -70,7 +100,45 @@ func (s *rewardService) UpdateReward(id uint, reward *models.Reward) error {
 	if existe {
 		return fmt.Errorf("reward already exists")
 	}
-	err := s.repo.Put(id, reward)
+	existing, err := s.repo.GetById(id)
+	if err != nil {
+		return fmt.Errorf("reward not found")
+	}
+	if err := checkScope(s.scope, existing.StoreID); err != nil {
+		return err
+	}
+	if reward.StoreID != 0 {
+		if err := checkScope(s.scope, reward.StoreID); err != nil {
+			return err
+		}
+	}
+
+	// El stock, los límites y la ventana se reemplazan tal como llegan, así que omitirlos los quita.
+	// La tienda, la descripción y los puntos conservan su valor si no se envían.
+	merged := *existing
+	merged.Branches = reward.Branches
+	if reward.StoreID != 0 {
+		merged.StoreID = reward.StoreID
+	}
+	if reward.Description != "" {
+		merged.Description = reward.Description
+	}
+	if reward.PointsRequired != 0 {
+		merged.PointsRequired = reward.PointsRequired
+	}
+	merged.Stock = reward.Stock
+	merged.PerUserLimit = reward.PerUserLimit
+	merged.PeriodLimit = reward.PeriodLimit
+	merged.PeriodDays = reward.PeriodDays
+	merged.StartsAt = reward.StartsAt
+	merged.EndsAt = reward.EndsAt
+	if err := validateRewardSettings(&merged); err != nil {
+		return err
+	}
+	if err := s.loadEligibleBranches(existing.StoreID, &merged); err != nil {
+		return err
+	}
+	err = s.repo.Put(id, &merged)
 	if err != nil {
 		return err
 	}
@@ -79,13 +147,55 @@ func (s *rewardService) UpdateReward(id uint, reward *models.Reward) error {
 
 // CreateReward creates a new reward
 func (s *rewardService) CreateReward(reward *models.Reward) error {
+	if err := checkScope(s.scope, reward.StoreID); err != nil {
+		return err
+	}
 	exist := s.repo.Validate(reward.Description)
 	if exist {
 		return fmt.Errorf("reward already exists")
 	}
+	if err := validateRewardSettings(reward); err != nil {
+		return err
+	}
+	if err := s.loadEligibleBranches(reward.StoreID, reward); err != nil {
+		return err
+	}
 	err := s.repo.Create(reward)
 	if err != nil {
 		return err
 	}
 	return nil
 }
+
+// loadEligibleBranches replaces the branch IDs of a reward with the branches they refer to,
+// checking that each one belongs to the store of the reward
+func (s *rewardService) loadEligibleBranches(storeID uint, reward *models.Reward) error {
+	for i, branch := range reward.Branches {
+		loaded, err := s.repoBranch.GetById(branch.ID)
+		if err != nil {
+			return fmt.Errorf("branch %d not found", branch.ID)
+		}
+		if loaded.StoreID != storeID {
+			return fmt.Errorf("branch %d does not belong to store %d", branch.ID, storeID)
+		}
+		reward.Branches[i] = *loaded
+	}
+	return nil
+}
+
+// validateRewardSettings checks the stock, limits and availability window of a reward
+func validateRewardSettings(reward *models.Reward) error {
+	if reward.Stock != nil && *reward.Stock < 0 {
+		return fmt.Errorf("reward stock must not be negative")
+	}
+	if reward.PerUserLimit < 0 || reward.PeriodLimit < 0 || reward.PeriodDays < 0 {
+		return fmt.Errorf("reward limits must not be negative")
+	}
+	if reward.PeriodLimit > 0 && reward.PeriodDays == 0 {
+		return fmt.Errorf("reward period days are required with a period limit")
+	}
+	if reward.StartsAt != nil && reward.EndsAt != nil && !reward.EndsAt.After(*reward.StartsAt) {
+		return fmt.Errorf("reward must end after it starts")
+	}
+	return nil
+}
@@ -0,0 +1,18 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"leal-technical-test/config"
+)
+
+// ErrStoreOutOfScope is returned when a resource belongs to a store the user is not linked to
+var ErrStoreOutOfScope = errors.New("store is out of the scope of the user")
+
+// checkScope fails with ErrStoreOutOfScope when the store is not in the scope
+func checkScope(scope config.Scope, storeID uint) error {
+	if !scope.Allows(storeID) {
+		return fmt.Errorf("%w: store %d", ErrStoreOutOfScope, storeID)
+	}
+	return nil
+}
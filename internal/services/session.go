@@ -0,0 +1,221 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"leal-technical-test/config"
+	"leal-technical-test/internal/domain/models"
+	"leal-technical-test/internal/infra/repository"
+	"time"
+)
+
+// ErrInvalidRefreshToken is returned when a refresh token is unknown, expired or revoked
+var ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+
+// ErrRefreshTokenReused is returned when a refresh token that was already exchanged is presented again.
+// The whole session it belongs to is revoked.
+var ErrRefreshTokenReused = errors.New("refresh token reused, session revoked")
+
+// SessionService interface
+type SessionService interface {
+	Start(user *models.User) (*models.TokenPair, error)
+	Refresh(refreshToken string) (*models.TokenPair, error)
+	Logout(userID uint, refreshToken string, accessTokenID string) error
+	RevokeUser(userID uint) error
+	PurgeExpiredTokens(now time.Time) (int64, error)
+}
+
+// sessionService struct
+type sessionService struct {
+	uow         config.IUnitOfWork
+	repo        repository.RefreshTokenRepository
+	repoRevoked repository.RevokedTokenRepository
+	repoUser    repository.UserRepository
+	token       *config.TokenManager
+}
+
+// NewSessionService constructor
+func NewSessionService(uow config.IUnitOfWork, repo repository.RefreshTokenRepository, repoRevoked repository.RevokedTokenRepository, repoUser repository.UserRepository, token *config.TokenManager) SessionService {
+	return &sessionService{
+		uow:         uow,
+		repo:        repo,
+		repoRevoked: repoRevoked,
+		repoUser:    repoUser,
+		token:       token,
+	}
+}
+
+// Start opens a new session for a user who just logged in
+func (s *sessionService) Start(user *models.User) (*models.TokenPair, error) {
+	familyID, err := config.NewTokenID()
+	if err != nil {
+		return nil, err
+	}
+	return s.issue(s.repo, user, familyID, time.Now())
+}
+
+// Refresh exchanges a refresh token for a new pair of the same session. Each refresh token can be used
+// once; presenting a used one means it leaked, so the whole session is revoked.
+func (s *sessionService) Refresh(refreshToken string) (*models.TokenPair, error) {
+	now := time.Now()
+	var pair *models.TokenPair
+	reused := false
+	err := s.uow.Do(func(tx config.IDatabaseConnection) error {
+		repo := s.repo.WithTx(tx)
+		stored, err := repo.GetByHashForUpdate(hashToken(refreshToken))
+		if err != nil {
+			return err
+		}
+		if stored == nil || stored.RevokedAt != nil || !now.Before(stored.ExpiresAt) {
+			return ErrInvalidRefreshToken
+		}
+
+		// Marcar el token como usado; si ya lo estaba, se está reutilizando
+		marked := false
+		if stored.UsedAt == nil {
+			if marked, err = repo.MarkUsed(stored.ID, now); err != nil {
+				return err
+			}
+		}
+		if !marked {
+			// La revocación debe confirmarse aunque la petición falle, por eso no se devuelve error aquí
+			reused = true
+			return s.revokeFamily(tx, stored.FamilyID, now)
+		}
+
+		// Volver a leer el usuario para que el nuevo token lleve su rol y tiendas actuales
+		user, err := s.repoUser.WithTx(tx).GetById(stored.UserID)
+		if err != nil {
+			return ErrInvalidRefreshToken
+		}
+		pair, err = s.issue(repo, user, stored.FamilyID, now)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if reused {
+		return nil, ErrRefreshTokenReused
+	}
+	return pair, nil
+}
+
+// Logout revokes the access token of the request and, when given, the session of the refresh token.
+// Unknown refresh tokens and those of other users are ignored.
+func (s *sessionService) Logout(userID uint, refreshToken string, accessTokenID string) error {
+	now := time.Now()
+	return s.uow.Do(func(tx config.IDatabaseConnection) error {
+		if err := s.repoRevoked.WithTx(tx).Revoke(accessTokenID, now.Add(config.AccessTokenTTL)); err != nil {
+			return err
+		}
+		if refreshToken == "" {
+			return nil
+		}
+		stored, err := s.repo.WithTx(tx).GetByHashForUpdate(hashToken(refreshToken))
+		if err != nil {
+			return err
+		}
+		if stored == nil || stored.UserID != userID {
+			return nil
+		}
+		return s.revokeFamily(tx, stored.FamilyID, now)
+	})
+}
+
+// RevokeUser revokes every session of a user, for example after a password change
+func (s *sessionService) RevokeUser(userID uint) error {
+	now := time.Now()
+	return s.uow.Do(func(tx config.IDatabaseConnection) error {
+		repo := s.repo.WithTx(tx)
+		if err := repo.RevokeByUser(userID, now); err != nil {
+			return err
+		}
+		tokens, err := repo.GetIssuedSinceByUser(userID, now.Add(-config.AccessTokenTTL))
+		if err != nil {
+			return err
+		}
+		return s.revokeAccessTokens(tx, tokens)
+	})
+}
+
+// PurgeExpiredTokens deletes the refresh tokens and revocation entries that expired before now
+func (s *sessionService) PurgeExpiredTokens(now time.Time) (int64, error) {
+	refreshPurged, err := s.repo.DeleteExpired(now)
+	if err != nil {
+		return 0, err
+	}
+	revokedPurged, err := s.repoRevoked.DeleteExpired(now)
+	if err != nil {
+		return refreshPurged, err
+	}
+	return refreshPurged + revokedPurged, nil
+}
+
+// issue creates an access token and a refresh token of the given session
+func (s *sessionService) issue(repo repository.RefreshTokenRepository, user *models.User, familyID string, now time.Time) (*models.TokenPair, error) {
+	accessToken, claims, err := s.token.GenerateToken(user.ID, user.Name, user.Role, user.StoreIDs())
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, err := newRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+	if err := repo.Create(&models.RefreshToken{
+		UserID:        user.ID,
+		FamilyID:      familyID,
+		TokenHash:     hashToken(refreshToken),
+		AccessTokenID: claims.ID,
+		ExpiresAt:     now.Add(config.RefreshTokenTTL),
+	}); err != nil {
+		return nil, err
+	}
+	return &models.TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresAt:    claims.ExpiresAt.Time,
+	}, nil
+}
+
+// revokeFamily revokes the refresh tokens of a session and the access tokens issued with them that
+// may still be valid
+func (s *sessionService) revokeFamily(tx config.IDatabaseConnection, familyID string, now time.Time) error {
+	repo := s.repo.WithTx(tx)
+	if err := repo.RevokeFamily(familyID, now); err != nil {
+		return err
+	}
+	tokens, err := repo.GetIssuedSinceByFamily(familyID, now.Add(-config.AccessTokenTTL))
+	if err != nil {
+		return err
+	}
+	return s.revokeAccessTokens(tx, tokens)
+}
+
+// revokeAccessTokens adds the access tokens issued with the given refresh tokens to the revocation list
+func (s *sessionService) revokeAccessTokens(tx config.IDatabaseConnection, tokens []models.RefreshToken) error {
+	repoRevoked := s.repoRevoked.WithTx(tx)
+	for _, token := range tokens {
+		if err := repoRevoked.Revoke(token.AccessTokenID, token.CreatedAt.Add(config.AccessTokenTTL)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newRefreshToken generates an opaque refresh token
+func newRefreshToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// hashToken returns the hash under which a refresh token is stored
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"leal-technical-test/config"
 	"leal-technical-test/internal/domain/models"
+	"leal-technical-test/internal/domain/rules"
 	"leal-technical-test/internal/infra/repository"
 )
 
@@ -14,19 +15,31 @@ type StoreService interface {
 	DeleteStore(id uint) error
 	UpdateStore(id uint, store *models.Store) error
 	CreateStore(store *models.Store) error
+	WithScope(scope config.Scope) StoreService
 }
 
 // storeService struct
 type storeService struct {
-	repo repository.StoreRepository
-	log  config.ILogger
+	repo  repository.StoreRepository
+	log   config.ILogger
+	scope config.Scope
 }
 
 // NewStoreService constructor
 func NewStoreService(repo repository.StoreRepository) StoreService {
 	return &storeService{
-		repo: repo,
-		log:  config.NewLogger(),
+		repo:  repo,
+		log:   config.NewLogger(),
+		scope: config.Unrestricted(),
+	}
+}
+
+// WithScope returns a copy of the service limited to the stores of the scope
+func (s *storeService) WithScope(scope config.Scope) StoreService {
+	return &storeService{
+		repo:  s.repo,
+		log:   s.log,
+		scope: scope,
 	}
 }
 
@@ -38,11 +51,20 @@ func (s *storeService) GetAllStores() ([]models.Store, error) {
 		return nil, err
 	}
 
-	return stores, nil
+	inScope := make([]models.Store, 0, len(stores))
+	for _, store := range stores {
+		if s.scope.Allows(store.ID) {
+			inScope = append(inScope, store)
+		}
+	}
+	return inScope, nil
 }
 
 // GetStoreById retrieves a store by its ID
 func (s *storeService) GetStoreById(id uint) (*models.Store, error) {
+	if err := checkScope(s.scope, id); err != nil {
+		return nil, err
+	}
 	store, err := s.repo.GetById(id)
 	if err != nil {
 		s.log.Error("Error retrieving store by ID: ", err)
@@ -53,6 +75,9 @@ func (s *storeService) GetStoreById(id uint) (*models.Store, error) {
 
 // DeleteStore removes a store by its ID
 func (s *storeService) DeleteStore(id uint) error {
+	if err := checkScope(s.scope, id); err != nil {
+		return err
+	}
 	err := s.repo.Delete(id)
 	if err != nil {
 		s.log.Error("Error deleting store: ", err)
@@ -63,6 +88,10 @@ func (s *storeService) DeleteStore(id uint) error {
 
 // UpdateStore updates an existing store
 func (s *storeService) UpdateStore(id uint, store *models.Store) error {
+	if err := checkScope(s.scope, id); err != nil {
+		return err
+	}
+
 	// Verificar si la tienda existe
 	existingStore, err := s.repo.GetById(id)
 	if err != nil {
@@ -74,6 +103,10 @@ func (s *storeService) UpdateStore(id uint, store *models.Store) error {
 		return fmt.Errorf("store not found")
 	}
 
+	if err := validateStoreSettings(store); err != nil {
+		return err
+	}
+
 	// Actualizar la tienda
 	err = s.repo.Put(id, store)
 	if err != nil {
@@ -83,8 +116,15 @@ func (s *storeService) UpdateStore(id uint, store *models.Store) error {
 	return nil
 }
 
-// CreateStore creates a new store
+// CreateStore creates a new store. Users limited to some stores can't create new ones.
 func (s *storeService) CreateStore(store *models.Store) error {
+	if s.scope.Restricted() {
+		return fmt.Errorf("%w: store staff can't create stores", ErrStoreOutOfScope)
+	}
+	if err := validateStoreSettings(store); err != nil {
+		return err
+	}
+
 	err := s.repo.Post(store)
 	if err != nil {
 		s.log.Error("Error creating store: ", err)
@@ -92,3 +132,36 @@ func (s *storeService) CreateStore(store *models.Store) error {
 	}
 	return nil
 }
+
+// validateStoreSettings checks the loyalty settings of a store. Empty values keep the current or default setting.
+func validateStoreSettings(store *models.Store) error {
+	if store.PointsExpirationMonths < 0 {
+		return fmt.Errorf("points expiration months must not be negative")
+	}
+	if store.TierWindowDays < 0 || store.TierGraceDays < 0 {
+		return fmt.Errorf("tier window and grace days must not be negative")
+	}
+	if store.VoucherValidityDays < 0 {
+		return fmt.Errorf("voucher validity days must not be negative")
+	}
+	if store.TransferMinPoints < 0 || store.TransferMaxPoints < 0 || store.TransferDailyCap < 0 || store.TransferMinAccountDays < 0 {
+		return fmt.Errorf("transfer limits must not be negative")
+	}
+	if store.TransferMaxPoints > 0 && store.TransferMaxPoints < store.TransferMinPoints {
+		return fmt.Errorf("transfer max points must not be lower than the min points")
+	}
+	if store.TransferFeePercentage < 0 || store.TransferFeePercentage > 100 {
+		return fmt.Errorf("transfer fee percentage must be between 0 and 100")
+	}
+	switch store.NegativeBalancePolicy {
+	case "", models.NegativeBalanceAllowDebt, models.NegativeBalanceClamp, models.NegativeBalanceReject:
+	default:
+		return fmt.Errorf("unknown negative balance policy %q", store.NegativeBalancePolicy)
+	}
+	if store.StackingPolicy != "" {
+		if err := rules.ValidatePolicy(store.StackingPolicy); err != nil {
+			return err
+		}
+	}
+	return nil
+}
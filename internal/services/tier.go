@@ -0,0 +1,350 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"leal-technical-test/config"
+	"leal-technical-test/internal/domain/models"
+	"leal-technical-test/internal/infra/repository"
+	"time"
+)
+
+// ErrTierHasMembers is returned when deleting a tier that is still the current tier of some users
+var ErrTierHasMembers = errors.New("tier still has members")
+
+// TierService interface
+type TierService interface {
+	GetTiersByStoreId(storeID uint) ([]models.Tier, error)
+	CreateTier(tier *models.Tier) error
+	UpdateTier(id uint, tier *models.Tier) error
+	DeleteTier(id uint) error
+	GetProgress(userID uint, storeID uint) (*models.TierProgress, error)
+	RecalculateTiers(now time.Time) (int, error)
+	RecalculateUserTier(userID uint, storeID uint, now time.Time) (bool, error)
+	WithScope(scope config.Scope) TierService
+}
+
+// tierService struct
+type tierService struct {
+	uow       config.IUnitOfWork
+	repo      repository.TierRepository
+	repoStore repository.StoreRepository
+	repoTx    repository.TransactionRepository
+	scope     config.Scope
+}
+
+// NewTierService constructor
+func NewTierService(
+	uow config.IUnitOfWork,
+	repo repository.TierRepository,
+	repoStore repository.StoreRepository,
+	repoTx repository.TransactionRepository,
+) TierService {
+	return &tierService{
+		uow:       uow,
+		repo:      repo,
+		repoStore: repoStore,
+		repoTx:    repoTx,
+		scope:     config.Unrestricted(),
+	}
+}
+
+// WithScope returns a copy of the service limited to the stores of the scope
+func (s *tierService) WithScope(scope config.Scope) TierService {
+	return &tierService{
+		uow:       s.uow,
+		repo:      s.repo,
+		repoStore: s.repoStore,
+		repoTx:    s.repoTx,
+		scope:     scope,
+	}
+}
+
+// GetTiersByStoreId retrieves the tiers of a store from the lowest to the highest rank
+func (s *tierService) GetTiersByStoreId(storeID uint) ([]models.Tier, error) {
+	if err := checkScope(s.scope, storeID); err != nil {
+		return nil, err
+	}
+	tiers, err := s.repo.GetByStoreId(storeID)
+	if err != nil {
+		return nil, err
+	}
+	return tiers, nil
+}
+
+// CreateTier creates a new tier
+func (s *tierService) CreateTier(tier *models.Tier) error {
+	if tier.EarnMultiplier == 0 {
+		tier.EarnMultiplier = 1
+	}
+	if err := validateTier(tier); err != nil {
+		return err
+	}
+	if err := checkScope(s.scope, tier.StoreID); err != nil {
+		return err
+	}
+	if _, err := s.repoStore.GetById(tier.StoreID); err != nil {
+		return fmt.Errorf("store not found")
+	}
+	return s.repo.Create(tier)
+}
+
+// UpdateTier updates an existing tier
+func (s *tierService) UpdateTier(id uint, tier *models.Tier) error {
+	existing, err := s.repo.GetById(id)
+	if err != nil {
+		return err
+	}
+	if err := checkScope(s.scope, existing.StoreID); err != nil {
+		return err
+	}
+
+	// Validar el nivel que resulta de aplicar los cambios sobre el existente
+	merged := *existing
+	if tier.Name != "" {
+		merged.Name = tier.Name
+	}
+	if tier.Rank != 0 {
+		merged.Rank = tier.Rank
+	}
+	if tier.ThresholdType != "" {
+		merged.ThresholdType = tier.ThresholdType
+	}
+	if tier.Threshold != 0 {
+		merged.Threshold = tier.Threshold
+	}
+	if tier.EarnMultiplier != 0 {
+		merged.EarnMultiplier = tier.EarnMultiplier
+	}
+	if err := validateTier(&merged); err != nil {
+		return err
+	}
+
+	// La tienda de un nivel no cambia
+	tier.StoreID = 0
+	return s.repo.Update(id, tier)
+}
+
+// DeleteTier deletes a tier by its ID. A tier can't be deleted while it is the current tier of any user,
+// since they would be left in a tier that no longer exists.
+func (s *tierService) DeleteTier(id uint) error {
+	existing, err := s.repo.GetById(id)
+	if err != nil {
+		return err
+	}
+	if err := checkScope(s.scope, existing.StoreID); err != nil {
+		return err
+	}
+	members, err := s.repo.CountMembers(id)
+	if err != nil {
+		return err
+	}
+	if members > 0 {
+		return fmt.Errorf("%w: %d users", ErrTierHasMembers, members)
+	}
+	return s.repo.Delete(id)
+}
+
+// GetProgress retrieves the tier of a user in a store and what is still needed for the next one
+func (s *tierService) GetProgress(userID uint, storeID uint) (*models.TierProgress, error) {
+	if err := checkScope(s.scope, storeID); err != nil {
+		return nil, err
+	}
+	store, err := s.repoStore.GetById(storeID)
+	if err != nil {
+		return nil, fmt.Errorf("store not found")
+	}
+	tiers, err := s.repo.GetByStoreId(storeID)
+	if err != nil {
+		return nil, err
+	}
+	spend, points, err := s.repoTx.SumByUserAndStoreSince(userID, storeID, tierWindowStart(store, time.Now()))
+	if err != nil {
+		return nil, err
+	}
+	userTier, err := s.repo.GetUserTier(userID, storeID)
+	if err != nil {
+		return nil, err
+	}
+	history, err := s.repo.GetHistory(userID, storeID)
+	if err != nil {
+		return nil, err
+	}
+
+	progress := &models.TierProgress{
+		UserID:     userID,
+		StoreID:    storeID,
+		WindowDays: store.TierWindowDays,
+		Spend:      spend,
+		Points:     points,
+		History:    history,
+	}
+	currentRank := 0
+	if userTier != nil && userTier.Tier != nil {
+		progress.Current = userTier.Tier
+		progress.DowngradeAt = userTier.DowngradeAt
+		currentRank = userTier.Tier.Rank
+	}
+	for i := range tiers {
+		if tiers[i].Rank > currentRank {
+			progress.Next = &tiers[i]
+			progress.Remaining = tiers[i].Threshold - tierMeasure(&tiers[i], spend, points)
+			if progress.Remaining < 0 {
+				progress.Remaining = 0
+			}
+			break
+		}
+	}
+	return progress, nil
+}
+
+// RecalculateTiers re-evaluates every member of every store with tiers and returns how many changed tier
+func (s *tierService) RecalculateTiers(now time.Time) (int, error) {
+	storeIDs, err := s.repo.GetStoreIdsWithTiers()
+	if err != nil {
+		return 0, err
+	}
+
+	changed := 0
+	for _, storeID := range storeIDs {
+		userIDs, err := s.repo.GetMemberIds(storeID)
+		if err != nil {
+			return changed, err
+		}
+		for _, userID := range userIDs {
+			didChange, err := s.RecalculateUserTier(userID, storeID, now)
+			if err != nil {
+				return changed, fmt.Errorf("failed to recalculate tier of user %d in store %d: %v", userID, storeID, err)
+			}
+			if didChange {
+				changed++
+			}
+		}
+	}
+	return changed, nil
+}
+
+// RecalculateUserTier moves a user to the highest tier they qualify for. Upgrades apply at once;
+// a user who no longer qualifies keeps the tier until the grace period of the store ends.
+// It returns whether the tier changed.
+func (s *tierService) RecalculateUserTier(userID uint, storeID uint, now time.Time) (bool, error) {
+	store, err := s.repoStore.GetById(storeID)
+	if err != nil {
+		return false, fmt.Errorf("store not found")
+	}
+	tiers, err := s.repo.GetByStoreId(storeID)
+	if err != nil {
+		return false, err
+	}
+	spend, points, err := s.repoTx.SumByUserAndStoreSince(userID, storeID, tierWindowStart(store, now))
+	if err != nil {
+		return false, err
+	}
+
+	// El nivel más alto cuyo umbral se alcanza
+	var qualified *models.Tier
+	for i := range tiers {
+		if tierMeasure(&tiers[i], spend, points) >= tiers[i].Threshold {
+			qualified = &tiers[i]
+		}
+	}
+
+	changed := false
+	err = s.uow.Do(func(tx config.IDatabaseConnection) error {
+		repo := s.repo.WithTx(tx)
+		userTier, err := repo.GetUserTier(userID, storeID)
+		if err != nil {
+			return err
+		}
+		if userTier == nil {
+			userTier = &models.UserTier{UserID: userID, StoreID: storeID, Since: now}
+		}
+		userTier.EvaluatedAt = now
+
+		currentRank, qualifiedRank := 0, 0
+		if userTier.Tier != nil {
+			currentRank = userTier.Tier.Rank
+		}
+		if qualified != nil {
+			qualifiedRank = qualified.Rank
+		}
+
+		switch {
+		case qualifiedRank >= currentRank:
+			userTier.DowngradeAt = nil
+			changed = qualifiedRank > currentRank
+		case userTier.DowngradeAt == nil && store.TierGraceDays > 0:
+			downgradeAt := now.AddDate(0, 0, store.TierGraceDays)
+			userTier.DowngradeAt = &downgradeAt
+		case userTier.DowngradeAt == nil || !now.Before(*userTier.DowngradeAt):
+			userTier.DowngradeAt = nil
+			changed = true
+		}
+
+		if changed {
+			reason := models.TierChangeUpgrade
+			if qualifiedRank < currentRank {
+				reason = models.TierChangeDowngrade
+			}
+			history := &models.TierHistory{
+				UserID:     userID,
+				StoreID:    storeID,
+				FromTierID: userTier.TierID,
+				Reason:     reason,
+				Spend:      spend,
+				Points:     points,
+			}
+			userTier.TierID = nil
+			if qualified != nil {
+				history.ToTierID = &qualified.ID
+				userTier.TierID = &qualified.ID
+			}
+			userTier.Since = now
+			if err := repo.CreateHistory(history); err != nil {
+				return err
+			}
+		}
+		return repo.SaveUserTier(userTier)
+	})
+	if err != nil {
+		return false, err
+	}
+	return changed, nil
+}
+
+// tierWindowStart is the beginning of the rolling window used to qualify for the tiers of a store
+func tierWindowStart(store *models.Store, now time.Time) time.Time {
+	windowDays := store.TierWindowDays
+	if windowDays <= 0 {
+		windowDays = 365
+	}
+	return now.AddDate(0, 0, -windowDays)
+}
+
+// tierMeasure is the spend or the points, depending on what the threshold of the tier counts
+func tierMeasure(tier *models.Tier, spend float64, points float64) float64 {
+	if tier.ThresholdType == models.TierThresholdPoints {
+		return points
+	}
+	return spend
+}
+
+// validateTier checks the settings of a tier
+func validateTier(tier *models.Tier) error {
+	if tier.Name == "" {
+		return fmt.Errorf("tier name is required")
+	}
+	if tier.Rank <= 0 {
+		return fmt.Errorf("tier rank must be greater than zero")
+	}
+	if tier.ThresholdType != models.TierThresholdSpend && tier.ThresholdType != models.TierThresholdPoints {
+		return fmt.Errorf("tier threshold type must be %q or %q", models.TierThresholdSpend, models.TierThresholdPoints)
+	}
+	if tier.Threshold < 0 {
+		return fmt.Errorf("tier threshold must not be negative")
+	}
+	if tier.EarnMultiplier < 1 {
+		return fmt.Errorf("tier earn multiplier must be at least 1")
+	}
+	return nil
+}
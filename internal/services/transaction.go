@@ -1,52 +1,97 @@
 package services
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"leal-technical-test/config"
 	"leal-technical-test/internal/domain/models"
+	"leal-technical-test/internal/domain/rules"
 	"leal-technical-test/internal/infra/repository"
+	"math"
 	"time"
 )
 
+// ErrIdempotencyConflict is returned when an idempotency key or a receipt is reused for a different purchase
+var ErrIdempotencyConflict = errors.New("idempotency key or receipt already used for a different purchase")
+
+// ErrRefundExceedsAmount is returned when a refund is larger than the part of the purchase not yet refunded
+var ErrRefundExceedsAmount = errors.New("refund exceeds the amount not yet refunded")
+
+// ErrInvalidCashbackPayment is returned when the cashback to apply is negative or larger than the purchase
+var ErrInvalidCashbackPayment = errors.New("cashback to apply must be between zero and the purchase amount")
+
+// idempotencyKeyTTL is how long a stored idempotency key replays the original response
+const idempotencyKeyTTL = 24 * time.Hour
+
 // TransactionService interface
 type TransactionService interface {
 	GetAllTransactions() ([]models.Transaction, error)
 	GetTransactionById(id uint) (*models.Transaction, error)
 	GetTransactionsByUserId(userID uint) ([]models.Transaction, error)
-	CreateTransaction(transaction *models.Transaction) (*models.Transaction, uint, error)
+	CreateTransaction(transaction *models.Transaction, idempotencyKey string) (*models.Transaction, bool, error)
+	PreviewTransaction(transaction *models.Transaction, at time.Time) (*models.Transaction, error)
+	PurgeExpiredIdempotencyKeys(now time.Time) (int64, error)
+	RefundTransaction(id uint, amount float64, reason string) (*models.Refund, error)
+	GetRefundsByTransactionId(id uint) ([]models.Refund, error)
+	WithScope(scope config.Scope) TransactionService
 }
 
 // transactionService struct
 type transactionService struct {
-	log          config.ILogger
-	repo         repository.TransactionRepository
-	repoBranch   repository.BranchRepository
-	repoCampaign repository.CampaignRepository
+	log                config.ILogger
+	uow                config.IUnitOfWork
+	repo               repository.TransactionRepository
+	repoBranch         repository.BranchRepository
+	repoCampaign       repository.CampaignRepository
+	repoKey            repository.IdempotencyKeyRepository
+	repoRefund         repository.RefundRepository
+	repoTier           repository.TierRepository
+	accumulatedService AccumulatedRewardService
+	scope              config.Scope
 }
 
 // NewTransactionService constructor
 func NewTransactionService(
+	uow config.IUnitOfWork,
 	repo repository.TransactionRepository,
 	repoBranch repository.BranchRepository,
 	repoCampaign repository.CampaignRepository,
-
+	repoKey repository.IdempotencyKeyRepository,
+	repoRefund repository.RefundRepository,
+	repoTier repository.TierRepository,
+	accumulatedService AccumulatedRewardService,
 ) TransactionService {
 	log := config.NewLogger()
 	return &transactionService{
-		log:          log,
-		repo:         repo,
-		repoBranch:   repoBranch,
-		repoCampaign: repoCampaign,
+		log:                log,
+		uow:                uow,
+		repo:               repo,
+		repoBranch:         repoBranch,
+		repoCampaign:       repoCampaign,
+		repoKey:            repoKey,
+		repoRefund:         repoRefund,
+		repoTier:           repoTier,
+		accumulatedService: accumulatedService,
+		scope:              config.Unrestricted(),
 	}
 }
 
+// WithScope returns a copy of the service limited to the purchases at branches of the stores of the scope
+func (s *transactionService) WithScope(scope config.Scope) TransactionService {
+	scoped := *s
+	scoped.scope = scope
+	return &scoped
+}
+
 // GetAllTransactions retrieves all transactions
 func (s *transactionService) GetAllTransactions() ([]models.Transaction, error) {
 	transactions, err := s.repo.GetAll()
 	if err != nil {
 		return nil, err
 	}
-	return transactions, nil
+	return s.filterScope(transactions), nil
 }
 
 // GetTransactionById retrieves a transaction by its ID
@@ -55,6 +100,9 @@ func (s *transactionService) GetTransactionById(id uint) (*models.Transaction, e
 	if err != nil {
 		return nil, err
 	}
+	if err := checkScope(s.scope, transaction.Branch.StoreID); err != nil {
+		return nil, err
+	}
 	return transaction, nil
 }
 
@@ -64,35 +112,418 @@ func (s *transactionService) GetTransactionsByUserId(userID uint) ([]models.Tran
 	if err != nil {
 		return nil, err
 	}
-	return transactions, nil
+	return s.filterScope(transactions), nil
 }
 
-// CreateTransaction creates a new transaction
-func (s *transactionService) CreateTransaction(transaction *models.Transaction) (*models.Transaction, uint, error) {
+// CreateTransaction creates a new transaction. A retry with the same idempotency key or the same
+// receipt of the branch returns the original transaction instead of recording the purchase again;
+// the returned bool reports whether the transaction is such a replay.
+func (s *transactionService) CreateTransaction(transaction *models.Transaction, idempotencyKey string) (*models.Transaction, bool, error) {
 	// Buscar sucursal
 	branch, err := s.repoBranch.GetById(transaction.BranchID)
 	if err != nil {
-		return nil, 0, fmt.Errorf("branch not found")
+		return nil, false, fmt.Errorf("branch not found")
+	}
+	if err := checkScope(s.scope, branch.StoreID); err != nil {
+		return nil, false, err
+	}
+
+	if original, err := s.findOriginal(transaction, idempotencyKey); err != nil || original != nil {
+		return original, original != nil, err
+	}
+
+	if err := s.calculateReward(transaction, branch, time.Now()); err != nil {
+		return nil, false, err
+	}
+
+	// La compra y la acumulación de la recompensa se confirman o se revierten juntas
+	err = s.uow.Do(func(tx config.IDatabaseConnection) error {
+		// Los límites de las campañas se aplican con la campaña bloqueada para no exceder el presupuesto
+		if err := s.settleReward(s.repoCampaign.WithTx(tx), transaction, true); err != nil {
+			return err
+		}
+
+		if err := s.repo.WithTx(tx).Create(transaction); err != nil {
+			return fmt.Errorf("failed to create transaction: %v", err)
+		}
+		// El cashback usado como pago se descuenta antes de acumular la recompensa de la compra
+		if transaction.CashbackApplied > 0 {
+			if err := s.accumulatedService.WithTx(tx).PayWithCashback(transaction.UserID, branch.StoreID, transaction.ID, transaction.CashbackApplied); err != nil {
+				return err
+			}
+		}
+		if err := s.accumulatedService.WithTx(tx).CreateReward(branch.StoreID, transaction); err != nil {
+			return fmt.Errorf("failed to accumulate reward: %v", err)
+		}
+		if idempotencyKey == "" {
+			return nil
+		}
+		transactionID := transaction.ID
+		return s.repoKey.WithTx(tx).Create(&models.IdempotencyKey{
+			Scope:         models.IdempotencyScopeTransactions,
+			Key:           idempotencyKey,
+			RequestHash:   requestHash(transaction),
+			TransactionID: &transactionID,
+			ExpiresAt:     time.Now().Add(idempotencyKeyTTL),
+		})
+	})
+	if err != nil {
+		// Un reintento concurrente con la misma clave o recibo pudo confirmarse primero
+		if original, findErr := s.findOriginal(transaction, idempotencyKey); findErr == nil && original != nil {
+			return original, true, nil
+		}
+		return nil, false, err
 	}
 
-	campaign, err := s.repoCampaign.FindByBranchAndDate(transaction.BranchID, time.Now())
+	return transaction, false, nil
+}
+
+// PreviewTransaction calculates the reward a purchase would earn at the given date, with the same
+// code path CreateTransaction uses, without saving anything or consuming campaign budgets
+func (s *transactionService) PreviewTransaction(transaction *models.Transaction, at time.Time) (*models.Transaction, error) {
+	branch, err := s.repoBranch.GetById(transaction.BranchID)
 	if err != nil {
-		transaction.PointsEarned = transaction.Amount * branch.Store.ConversionFactor
-	} else {
-		if campaign.Type == "double" {
-			transaction.PointsEarned = (transaction.Amount * branch.Store.ConversionFactor) * 2
-		} else if campaign.Type == "additional" && transaction.Amount > 20000 {
-			transaction.PointsEarned = (transaction.Amount * branch.Store.ConversionFactor) * 1.30
+		return nil, fmt.Errorf("branch not found")
+	}
+	if err := checkScope(s.scope, branch.StoreID); err != nil {
+		return nil, err
+	}
+	if err := s.calculateReward(transaction, branch, at); err != nil {
+		return nil, err
+	}
+	if err := s.settleReward(s.repoCampaign, transaction, false); err != nil {
+		return nil, err
+	}
+	return transaction, nil
+}
+
+// calculateReward fills in the reward of a purchase and its breakdown at the given date. The
+// campaign limits are applied afterwards by settleReward.
+func (s *transactionService) calculateReward(transaction *models.Transaction, branch *models.Branch, at time.Time) error {
+	if transaction.CashbackApplied < 0 || transaction.CashbackApplied > transaction.Amount {
+		return ErrInvalidCashbackPayment
+	}
+	transaction.CashbackApplied = roundReward(transaction.CashbackApplied)
+	transaction.NetAmount = roundReward(transaction.Amount - transaction.CashbackApplied)
+
+	switch transaction.RewardType {
+	case "", models.RewardTypePoints:
+		transaction.RewardType = models.RewardTypePoints
+		result, err := s.calculatePoints(transaction, branch, at)
+		if err != nil {
+			return err
+		}
+		transaction.ConversionFactor = branch.Store.ConversionFactor
+		transaction.BasePoints = result.BasePoints
+		for _, applied := range result.Applied {
+			transaction.BonusPoints += applied.Bonus
+			transaction.AppliedCampaigns = append(transaction.AppliedCampaigns, models.TransactionCampaign{
+				CampaignID:  applied.Campaign.ID,
+				BonusPoints: applied.Bonus,
+			})
 		}
+	case models.RewardTypeCashback:
+		if branch.Store.CashbackPercentage <= 0 {
+			return fmt.Errorf("store does not offer cashback")
+		}
+		transaction.CashbackPercentage = branch.Store.CashbackPercentage
+		cashback := earningAmount(transaction, &branch.Store) * branch.Store.CashbackPercentage / 100
+		transaction.CashbackEarned = roundReward(cashback)
+		transaction.RoundingAdjustment = transaction.CashbackEarned - cashback
+	default:
+		return fmt.Errorf("invalid reward type: %s", transaction.RewardType)
+	}
+	return nil
+}
+
+// settleReward applies the campaign limits and sets the points earned by a purchase. Budgets are
+// only consumed when consume is set; otherwise the campaigns are read without locking them.
+func (s *transactionService) settleReward(repoCampaign repository.CampaignRepository, transaction *models.Transaction, consume bool) error {
+	switch transaction.RewardType {
+	case models.RewardTypePoints:
+		if err := s.applyCampaignLimits(repoCampaign, transaction, consume); err != nil {
+			return err
+		}
+		total := transaction.BasePoints + transaction.BonusPoints + transaction.TierBonusPoints + transaction.CapAdjustment
+		transaction.PointsEarned = roundReward(total)
+		transaction.RoundingAdjustment = transaction.PointsEarned - total
+		s.log.Info("transaction.PointsEarned: ", transaction.PointsEarned)
+	case models.RewardTypeCashback:
+		s.log.Info("transaction.CashbackEarned: ", transaction.CashbackEarned)
 	}
+	return nil
+}
 
-	transaction.RewardType = "points"
-	s.log.Info("transaction.PointsEarned: ", transaction.PointsEarned)
-	err = s.repo.Create(transaction)
+// RefundTransaction refunds part of a purchase, or everything not yet refunded when amount is zero.
+// The points and cashback are clawed back in proportion to the refunded amount, and the cashback used
+// to pay the purchase is given back in the same proportion; the last refund takes whatever is left so
+// rounding never leaves points behind. The campaign bonus of the refunded part goes back to the budget
+// of each campaign and no longer counts toward its per customer cap.
+func (s *transactionService) RefundTransaction(id uint, amount float64, reason string) (*models.Refund, error) {
+	if amount < 0 {
+		return nil, fmt.Errorf("refund amount must not be negative")
+	}
+	original, err := s.repo.GetById(id)
+	if err != nil {
+		return nil, fmt.Errorf("transaction not found")
+	}
+	branch, err := s.repoBranch.GetById(original.BranchID)
 	if err != nil {
-		return nil, 0, fmt.Errorf("failed to create transaction: %v", err)
+		return nil, fmt.Errorf("branch not found")
+	}
+	if err := checkScope(s.scope, branch.StoreID); err != nil {
+		return nil, err
 	}
 
-	fmt.Println("transactionService.CreateTransaction", branch.StoreID)
-	return transaction, branch.StoreID, nil
+	refund := &models.Refund{TransactionID: id, Reason: reason}
+	err = s.uow.Do(func(tx config.IDatabaseConnection) error {
+		// Bloquear la compra para que dos devoluciones simultáneas no superen el monto
+		transaction, err := s.repo.WithTx(tx).GetByIdForUpdate(id)
+		if err != nil {
+			return err
+		}
+
+		remaining := roundReward(transaction.Amount - transaction.RefundedAmount)
+		refund.Amount = roundReward(amount)
+		if refund.Amount == 0 {
+			refund.Amount = remaining
+		}
+		if refund.Amount <= 0 || refund.Amount > remaining {
+			return ErrRefundExceedsAmount
+		}
+
+		points := transaction.PointsEarned - transaction.PointsReversed
+		cashback := transaction.CashbackEarned - transaction.CashbackReversed
+		refund.CashbackReturned = roundReward(transaction.CashbackApplied - transaction.CashbackReturned)
+		if refund.Amount < remaining {
+			points = roundReward(transaction.PointsEarned * refund.Amount / transaction.Amount)
+			cashback = roundReward(transaction.CashbackEarned * refund.Amount / transaction.Amount)
+			refund.CashbackReturned = roundReward(transaction.CashbackApplied * refund.Amount / transaction.Amount)
+		}
+
+		refund.PointsReversed, refund.CashbackReversed, err = s.accumulatedService.WithTx(tx).ReversePurchase(
+			transaction.UserID, branch.StoreID, transaction.ID, points, cashback, branch.Store.NegativeBalancePolicy)
+		if err != nil {
+			return err
+		}
+		refund.PointsWaived = roundReward(points - refund.PointsReversed)
+
+		for _, applied := range transaction.AppliedCampaigns {
+			released := applied.BonusPoints
+			if refund.Amount < remaining {
+				released = roundReward(applied.BonusPoints * refund.Amount / remaining)
+			}
+			if released <= 0 {
+				continue
+			}
+			if err := s.repoCampaign.WithTx(tx).ReleaseBonus(applied, released); err != nil {
+				return err
+			}
+		}
+		refund.CashbackWaived = roundReward(cashback - refund.CashbackReversed)
+
+		if refund.CashbackReturned > 0 {
+			if err := s.accumulatedService.WithTx(tx).ReturnCashbackPayment(
+				transaction.UserID, branch.StoreID, transaction.ID, refund.CashbackReturned); err != nil {
+				return err
+			}
+		}
+
+		if err := s.repo.WithTx(tx).AddRefund(transaction.ID, refund.Amount, points, cashback, refund.CashbackReturned); err != nil {
+			return err
+		}
+		return s.repoRefund.WithTx(tx).Create(refund)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return refund, nil
+}
+
+// GetRefundsByTransactionId retrieves the refunds of a transaction
+func (s *transactionService) GetRefundsByTransactionId(id uint) ([]models.Refund, error) {
+	if _, err := s.GetTransactionById(id); err != nil {
+		return nil, err
+	}
+	refunds, err := s.repoRefund.GetByTransactionId(id)
+	if err != nil {
+		return nil, err
+	}
+	return refunds, nil
+}
+
+// PurgeExpiredIdempotencyKeys deletes the idempotency keys that can no longer be replayed
+func (s *transactionService) PurgeExpiredIdempotencyKeys(now time.Time) (int64, error) {
+	return s.repoKey.DeleteExpired(now)
+}
+
+// filterScope keeps the transactions made at branches of the stores of the scope
+func (s *transactionService) filterScope(transactions []models.Transaction) []models.Transaction {
+	inScope := make([]models.Transaction, 0, len(transactions))
+	for _, transaction := range transactions {
+		if s.scope.Allows(transaction.Branch.StoreID) {
+			inScope = append(inScope, transaction)
+		}
+	}
+	return inScope
+}
+
+// findOriginal looks for the transaction already recorded for the idempotency key or the receipt
+// of the request. It returns nil when the purchase was never recorded.
+func (s *transactionService) findOriginal(transaction *models.Transaction, idempotencyKey string) (*models.Transaction, error) {
+	hash := requestHash(transaction)
+
+	if idempotencyKey != "" {
+		stored, err := s.repoKey.GetByKey(models.IdempotencyScopeTransactions, idempotencyKey)
+		if err != nil {
+			return nil, err
+		}
+		if stored != nil && !stored.ExpiresAt.After(time.Now()) {
+			// La clave venció: se puede volver a usar
+			if err := s.repoKey.Delete(stored.ID); err != nil {
+				return nil, err
+			}
+			stored = nil
+		}
+		if stored != nil {
+			if stored.RequestHash != hash {
+				return nil, ErrIdempotencyConflict
+			}
+			if stored.TransactionID != nil {
+				return s.repo.GetById(*stored.TransactionID)
+			}
+		}
+	}
+
+	if transaction.ExternalReceiptID != nil {
+		original, err := s.repo.GetByBranchAndReceipt(transaction.BranchID, *transaction.ExternalReceiptID)
+		if err != nil {
+			return nil, err
+		}
+		if original != nil && requestHash(original) != hash {
+			return nil, ErrIdempotencyConflict
+		}
+		return original, nil
+	}
+	return nil, nil
+}
+
+// requestHash is the fingerprint of the purchase data a retry must repeat exactly
+func requestHash(transaction *models.Transaction) string {
+	rewardType := transaction.RewardType
+	if rewardType == "" {
+		rewardType = models.RewardTypePoints
+	}
+	receiptID := ""
+	if transaction.ExternalReceiptID != nil {
+		receiptID = *transaction.ExternalReceiptID
+	}
+	data := fmt.Sprintf("%d|%d|%.2f|%s|%s", transaction.UserID, transaction.BranchID, transaction.Amount, rewardType, receiptID)
+	// Solo las compras pagadas en parte con cashback lo incluyen, para no cambiar el hash de las demás
+	if transaction.CashbackApplied > 0 {
+		data += fmt.Sprintf("|%.2f", transaction.CashbackApplied)
+	}
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
+
+// calculatePoints combines the active campaigns of the branch on top of the base points
+// following the stacking policy of the store. The tier multiplier of the user is recorded on
+// the transaction as a separate bonus over the base points.
+func (s *transactionService) calculatePoints(transaction *models.Transaction, branch *models.Branch, at time.Time) (rules.Result, error) {
+	amount := earningAmount(transaction, &branch.Store)
+	basePoints := amount * branch.Store.ConversionFactor
+
+	transaction.TierMultiplier = 1
+	userTier, err := s.repoTier.GetUserTier(transaction.UserID, branch.StoreID)
+	if err != nil {
+		return rules.Result{}, err
+	}
+	if userTier != nil && userTier.Tier != nil {
+		transaction.TierID = userTier.TierID
+		transaction.TierMultiplier = userTier.Tier.EarnMultiplier
+		transaction.TierBonusPoints = basePoints * (userTier.Tier.EarnMultiplier - 1)
+	}
+
+	campaigns, err := s.repoCampaign.FindActiveByBranchAndDate(transaction.BranchID, at)
+	if err != nil {
+		return rules.Result{}, err
+	}
+
+	purchases, err := s.repo.CountByUserAndStore(transaction.UserID, branch.StoreID)
+	if err != nil {
+		return rules.Result{}, err
+	}
+
+	return rules.Stack(branch.Store.StackingPolicy, rules.Context{
+		Amount:          amount,
+		BasePoints:      basePoints,
+		Date:            at,
+		IsFirstPurchase: purchases == 0,
+	}, campaigns)
+}
+
+// applyCampaignLimits caps the bonus of each applied campaign by its per-transaction cap, the part of
+// its per-customer cap the user has not received yet and its remaining budget, and consumes the budget
+// when consume is set. The points removed are recorded as a cap adjustment; campaigns left without
+// bonus are not applied.
+func (s *transactionService) applyCampaignLimits(repoCampaign repository.CampaignRepository, transaction *models.Transaction, consume bool) error {
+	applied := transaction.AppliedCampaigns[:0]
+	for _, candidate := range transaction.AppliedCampaigns {
+		getCampaign := repoCampaign.GetById
+		if consume {
+			getCampaign = repoCampaign.GetByIdForUpdate
+		}
+		campaign, err := getCampaign(candidate.CampaignID)
+		if err != nil {
+			return err
+		}
+
+		granted := candidate.BonusPoints
+		if campaign.PerTransactionCap > 0 {
+			granted = math.Min(granted, campaign.PerTransactionCap)
+		}
+		if campaign.PerCustomerCap > 0 {
+			received, err := repoCampaign.SumBonusByUser(campaign.ID, transaction.UserID)
+			if err != nil {
+				return err
+			}
+			granted = math.Min(granted, math.Max(0, campaign.PerCustomerCap-received))
+		}
+		if remaining := campaign.RemainingBudget(); remaining != nil {
+			granted = math.Min(granted, *remaining)
+		}
+		granted = roundReward(granted)
+		transaction.CapAdjustment += granted - candidate.BonusPoints
+
+		if granted <= 0 {
+			continue
+		}
+		if consume {
+			if err := repoCampaign.ConsumeBudget(campaign.ID, granted); err != nil {
+				return err
+			}
+		} else {
+			// La vista previa no se guarda: se adjunta la campaña para mostrar su nombre
+			candidate.Campaign = *campaign
+		}
+		candidate.BonusPoints = granted
+		applied = append(applied, candidate)
+	}
+	transaction.AppliedCampaigns = applied
+	return nil
+}
+
+// earningAmount is the part of the purchase that earns rewards. The part paid with cashback only
+// earns when the store allows it.
+func earningAmount(transaction *models.Transaction, store *models.Store) float64 {
+	if store.CashbackPaymentEarns {
+		return transaction.Amount
+	}
+	return transaction.NetAmount
+}
+
+// roundReward redondea una recompensa a los dos decimales con los que se guarda
+func roundReward(value float64) float64 {
+	return math.Round(value*100) / 100
 }
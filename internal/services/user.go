@@ -1,6 +1,7 @@
 package services
 
 import (
+	"errors"
 	"fmt"
 	"leal-technical-test/config"
 	"leal-technical-test/internal/domain/models"
@@ -10,35 +11,61 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
+// ErrInvalidRole is returned when a role is not one of the known roles
+var ErrInvalidRole = errors.New("invalid role")
+
 // UserService interface
 type UserService interface {
 	GetAllUsers() ([]models.User, error)
 	GetUserById(id uint) (*models.User, error)
 	DeleteUser(id uint) error
 	UpdateUser(id uint, user *models.User) error
+	SetSuspended(id uint, suspended bool) error
+	SetRole(id uint, role string) error
+	SetStores(id uint, storeIDs []uint) error
 	CreateUser(user *models.User) error
-	Login(email string, password string) (string, error)
+	Login(email string, password string) (*models.TokenPair, error)
+	WithScope(scope config.Scope) UserService
 }
 
 // userService struct
 type userService struct {
-	repo  repository.UserRepository
-	token *config.TokenManager
+	repo      repository.UserRepository
+	repoStore repository.StoreRepository
+	sessions  SessionService
+	scope     config.Scope
 }
 
 // NewUserService constructor
-func NewUserService(repo repository.UserRepository) UserService {
-	token := config.NewTokenManager()
-	return &userService{repo: repo, token: token}
+func NewUserService(repo repository.UserRepository, repoStore repository.StoreRepository, sessions SessionService) UserService {
+	return &userService{repo: repo, repoStore: repoStore, sessions: sessions, scope: config.Unrestricted()}
+}
+
+// WithScope returns a copy of the service that only sees the users of the stores of the scope:
+// the staff linked to them and the customers holding a balance in them
+func (s *userService) WithScope(scope config.Scope) UserService {
+	return &userService{repo: s.repo, repoStore: s.repoStore, sessions: s.sessions, scope: scope}
 }
 
 // GetAllUsers retrieves all users
 func (s *userService) GetAllUsers() ([]models.User, error) {
+	if s.scope.Restricted() {
+		return s.repo.GetByStoreIds(s.scope.StoreIDs())
+	}
 	return s.repo.GetAll()
 }
 
 // GetUserById retrieves a user by its ID
 func (s *userService) GetUserById(id uint) (*models.User, error) {
+	if s.scope.Restricted() {
+		belongs, err := s.repo.BelongsToStores(id, s.scope.StoreIDs())
+		if err != nil {
+			return nil, err
+		}
+		if !belongs {
+			return nil, fmt.Errorf("%w: user %d", ErrStoreOutOfScope, id)
+		}
+	}
 	user, err := s.repo.GetById(id)
 	if err != nil {
 		return nil, err
@@ -51,13 +78,54 @@ func (s *userService) DeleteUser(id uint) error {
 	return s.repo.Delete(id)
 }
 
-// UpdateUser updates an existing user
+// UpdateUser updates an existing user. A new password is hashed and closes every session of the user,
+// so tokens issued with the old password stop working.
 func (s *userService) UpdateUser(id uint, user *models.User) error {
-	return s.repo.Update(id, user)
+	if user.Password == "" {
+		return s.repo.Update(id, user)
+	}
+
+	hashedPassword, err := s.HashPassword(user.Password)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %v", err)
+	}
+	user.Password = hashedPassword
+	if err := s.repo.Update(id, user); err != nil {
+		return err
+	}
+	return s.sessions.RevokeUser(id)
+}
+
+// SetSuspended suspends or reactivates a user
+func (s *userService) SetSuspended(id uint, suspended bool) error {
+	return s.repo.SetSuspended(id, suspended)
+}
+
+// SetRole changes the role of a user
+func (s *userService) SetRole(id uint, role string) error {
+	if !models.ValidRole(role) {
+		return fmt.Errorf("%w: %q", ErrInvalidRole, role)
+	}
+	return s.repo.SetRole(id, role)
 }
 
-// CreateUser creates a new user
+// SetStores links a user to the stores they work at, replacing the previous ones
+func (s *userService) SetStores(id uint, storeIDs []uint) error {
+	if _, err := s.repo.GetById(id); err != nil {
+		return err
+	}
+	for _, storeID := range storeIDs {
+		if _, err := s.repoStore.GetById(storeID); err != nil {
+			return fmt.Errorf("store %d not found", storeID)
+		}
+	}
+	return s.repo.SetStores(id, storeIDs)
+}
+
+// CreateUser creates a new customer. Other roles are granted afterwards with SetRole.
 func (s *userService) CreateUser(user *models.User) error {
+	user.Role = models.RoleCustomer
+
 	// Hash the user's password
 	hashedPassword, err := s.HashPassword(user.Password)
 	if err != nil {
@@ -88,26 +156,27 @@ func (s *userService) CheckPasswordHash(password, hash string) bool {
 	return err == nil
 }
 
-func (s *userService) Login(email string, password string) (string, error) {
+// Login checks the credentials of a user and opens a new session
+func (s *userService) Login(email string, password string) (*models.TokenPair, error) {
 	id, err := s.repo.GetIdByEmail(email)
 	if err != nil {
-		return "", fmt.Errorf("user not found")
+		return nil, fmt.Errorf("user not found")
 	}
 
 	user, err := s.repo.GetById(id)
 	if err != nil {
-		return "", fmt.Errorf("error retrieving user")
+		return nil, fmt.Errorf("error retrieving user")
 	}
 
 	validate := s.CheckPasswordHash(password, user.Password)
 	if !validate {
-		return "", fmt.Errorf("invalid password")
+		return nil, fmt.Errorf("invalid password")
 	}
 
-	token, err := s.token.GenerateToken(user.Name)
+	pair, err := s.sessions.Start(user)
 	if err != nil {
-		return "", fmt.Errorf("error generating token")
+		return nil, fmt.Errorf("error generating token")
 	}
 
-	return token, nil
+	return pair, nil
 }
@@ -3,6 +3,7 @@ package router
 import (
 	"leal-technical-test/config"
 	"leal-technical-test/internal/infra/controllers"
+	"leal-technical-test/internal/infra/repository"
 
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
@@ -14,11 +15,16 @@ type Router struct {
 	engine                      *gin.Engine
 	storeController             *controllers.StoreController
 	userController              *controllers.UserController
+	authController              *controllers.AuthController
 	branchController            *controllers.BranchController
 	campaignController          *controllers.CampaignController
 	accumulatedRewardController *controllers.AccumulatedRewardController
 	rewardController            *controllers.RewardController
 	transactionController       *controllers.TransactionController
+	redemptionController        *controllers.RedemptionController
+	tierController              *controllers.TierController
+	pointTransferController     *controllers.PointTransferController
+	exchangeController          *controllers.ExchangeController
 }
 
 // NewRouter constructor
@@ -27,11 +33,16 @@ func NewRouter(engine *gin.Engine) *Router {
 		engine:                      engine,
 		storeController:             controllers.NewStoreController(),
 		userController:              controllers.NewUserController(),
+		authController:              controllers.NewAuthController(),
 		branchController:            controllers.NewBranchController(),
 		campaignController:          controllers.NewCampaignController(),
 		accumulatedRewardController: controllers.NewAccumulatedRewardController(),
 		rewardController:            controllers.NewRewardController(),
 		transactionController:       controllers.NewTransactionController(),
+		redemptionController:        controllers.NewRedemptionController(),
+		tierController:              controllers.NewTierController(),
+		pointTransferController:     controllers.NewPointTransferController(),
+		exchangeController:          controllers.NewExchangeController(),
 	}
 }
 
@@ -44,52 +55,99 @@ func (r *Router) InitializeRoutes() {
 	{
 		// Public routes
 		lealTestGroup.POST("/login", r.userController.Login)
+		lealTestGroup.POST("/refresh", r.userController.Refresh)
+		lealTestGroup.GET("/.well-known/jwks.json", r.authController.GetJWKS)
 		lealTestGroup.POST("/users", r.userController.CreateUser)
 
-		// Protected routes
+		// Protected routes: each route requires a permission of the role in the token
 		protected := lealTestGroup.Group("/")
-		protected.Use(config.NewTokenManager().AuthMiddleware())
+		revocations := repository.NewRevokedTokenRepository(config.NewPostgresConnection())
+		protected.Use(config.NewTokenManager().WithRevocationList(revocations).AuthMiddleware())
+		can := config.RequirePermission
+		own := config.RequireOwnUser("user_id")
 		{
+			protected.POST("/logout", r.userController.Logout)
+
+			// Routes of the authenticated user, resolved from the token subject
+			protected.GET("/me", can(config.PermProfileRead), r.userController.GetMe)
+			protected.GET("/me/balances", can(config.PermProfileRead), r.accumulatedRewardController.GetMyRewards)
+			protected.GET("/me/transactions", can(config.PermProfileRead), r.transactionController.GetMyTransactions)
+			protected.GET("/me/redemptions", can(config.PermProfileRead), r.redemptionController.GetMyRedemptions)
+
 			// Store routes
-			protected.GET("/stores", r.storeController.GetAllStores)
-			protected.GET("/stores/:id", r.storeController.GetStoreById)
-			protected.DELETE("/stores/:id", r.storeController.DeleteStore)
-			protected.PUT("/stores/:id", r.storeController.UpdateStore)
-			protected.POST("/stores", r.storeController.CreateStore)
-
-			protected.GET("/users", r.userController.GetAllUsers)
-			protected.GET("/users/:id", r.userController.GetUserById)
-			protected.DELETE("/users/:id", r.userController.DeleteUser)
-			protected.PUT("/users/:id", r.userController.UpdateUser)
-
-			protected.GET("/branches", r.branchController.GetAllBranches)
-			protected.GET("/branches/:id", r.branchController.GetBranchById)
-			protected.DELETE("/branches/:id", r.branchController.DeleteBranch)
-			protected.PUT("/branches/:id", r.branchController.UpdateBranch)
-			protected.POST("/branches", r.branchController.CreateBranch)
-
-			protected.GET("/campaigns", r.campaignController.GetAllCampaigns)
-			protected.GET("/campaigns/:id", r.campaignController.GetCampaignById)
-			protected.POST("/campaigns", r.campaignController.CreateCampaign)
-			protected.PUT("/campaigns/:id", r.campaignController.UpdateCampaign)
-			protected.DELETE("/campaigns/:id", r.campaignController.DeleteCampaign)
-
-			protected.GET("/acumulaterewards", r.accumulatedRewardController.GetAllRewards)
-			protected.GET("/acumulaterewards/:id", r.accumulatedRewardController.GetRewardById)
-			protected.GET("/acumulaterewards/user/:user_id/store/:store_id", r.accumulatedRewardController.GetRewardByUserAndStore)
-
-			protected.GET("/rewards", r.rewardController.GetAllRewards)
-			protected.GET("/rewards/:id", r.rewardController.GetRewardById)
-			protected.GET("/rewards/store/:store_id", r.rewardController.GetRewardsByStoreId)
-			protected.POST("/rewards", r.rewardController.CreateReward)
-			protected.PUT("/rewards/:id", r.rewardController.UpdateReward)
-			protected.DELETE("/rewards/:id", r.rewardController.DeleteReward)
-			protected.GET("/rewards/claim/:user_id/:reward_id/:store_id", r.rewardController.GetClaimRewardPoints)
-
-			protected.GET("/transactions", r.transactionController.GetAllTransactions)
-			protected.GET("/transactions/:id", r.transactionController.GetTransactionById)
-			protected.GET("/transactions/user/:user_id", r.transactionController.GetTransactionsByUserId)
-			protected.POST("/transactions", r.transactionController.CreateTransaction)
+			protected.GET("/stores", can(config.PermStoresRead), r.storeController.GetAllStores)
+			protected.GET("/stores/:id", can(config.PermStoresRead), r.storeController.GetStoreById)
+			protected.DELETE("/stores/:id", can(config.PermStoresWrite), r.storeController.DeleteStore)
+			protected.PUT("/stores/:id", can(config.PermStoresWrite), r.storeController.UpdateStore)
+			protected.POST("/stores", can(config.PermStoresWrite), r.storeController.CreateStore)
+
+			protected.GET("/users", can(config.PermUsersRead), r.userController.GetAllUsers)
+			protected.GET("/users/:id", can(config.PermUsersRead), r.userController.GetUserById)
+			protected.DELETE("/users/:id", can(config.PermUsersWrite), r.userController.DeleteUser)
+			protected.PUT("/users/:id", can(config.PermUsersWrite), r.userController.UpdateUser)
+			protected.PUT("/users/:id/suspension", can(config.PermUsersWrite), r.userController.SetUserSuspension)
+			protected.PUT("/users/:id/role", can(config.PermUsersWrite), r.userController.SetUserRole)
+			protected.PUT("/users/:id/stores", can(config.PermUsersWrite), r.userController.SetUserStores)
+
+			protected.GET("/branches", can(config.PermBranchesRead), r.branchController.GetAllBranches)
+			protected.GET("/branches/:id", can(config.PermBranchesRead), r.branchController.GetBranchById)
+			protected.DELETE("/branches/:id", can(config.PermBranchesWrite), r.branchController.DeleteBranch)
+			protected.PUT("/branches/:id", can(config.PermBranchesWrite), r.branchController.UpdateBranch)
+			protected.POST("/branches", can(config.PermBranchesWrite), r.branchController.CreateBranch)
+			protected.POST("/branches/:id/vouchers/:code/use", can(config.PermRedemptionsWrite), r.redemptionController.UseVoucher)
+
+			protected.GET("/campaigns", can(config.PermCampaignsRead), r.campaignController.GetAllCampaigns)
+			protected.GET("/campaigns/types", can(config.PermCampaignsRead), r.campaignController.GetCampaignTypes)
+			protected.GET("/campaigns/:id", can(config.PermCampaignsRead), r.campaignController.GetCampaignById)
+			protected.POST("/campaigns", can(config.PermCampaignsWrite), r.campaignController.CreateCampaign)
+			protected.PUT("/campaigns/:id", can(config.PermCampaignsWrite), r.campaignController.UpdateCampaign)
+			protected.DELETE("/campaigns/:id", can(config.PermCampaignsWrite), r.campaignController.DeleteCampaign)
+
+			protected.GET("/acumulaterewards", can(config.PermBalancesReadAll), r.accumulatedRewardController.GetAllRewards)
+			protected.GET("/acumulaterewards/:id", can(config.PermBalancesReadAll), r.accumulatedRewardController.GetRewardById)
+			protected.GET("/acumulaterewards/user/:user_id/store/:store_id", can(config.PermBalancesRead), own, r.accumulatedRewardController.GetRewardByUserAndStore)
+			protected.GET("/acumulaterewards/user/:user_id/store/:store_id/ledger", can(config.PermBalancesRead), own, r.accumulatedRewardController.GetLedgerByUserAndStore)
+			protected.GET("/acumulaterewards/user/:user_id/store/:store_id/expiring", can(config.PermBalancesRead), own, r.accumulatedRewardController.GetExpiringPoints)
+			protected.POST("/acumulaterewards/user/:user_id/store/:store_id/adjust", can(config.PermBalancesAdjust), r.accumulatedRewardController.AdjustPoints)
+			protected.POST("/acumulaterewards/user/:user_id/store/:store_id/cashback/redeem", can(config.PermCashbackRedeem), r.accumulatedRewardController.RedeemCashback)
+
+			protected.GET("/rewards", can(config.PermRewardsRead), r.rewardController.GetAllRewards)
+			protected.GET("/rewards/:id", can(config.PermRewardsRead), r.rewardController.GetRewardById)
+			protected.GET("/rewards/store/:store_id", can(config.PermRewardsRead), r.rewardController.GetRewardsByStoreId)
+			protected.POST("/rewards", can(config.PermRewardsWrite), r.rewardController.CreateReward)
+			protected.PUT("/rewards/:id", can(config.PermRewardsWrite), r.rewardController.UpdateReward)
+			protected.DELETE("/rewards/:id", can(config.PermRewardsWrite), r.rewardController.DeleteReward)
+			protected.POST("/rewards/claim", can(config.PermRewardsClaim), r.redemptionController.ClaimReward)
+
+			protected.GET("/redemptions/:id", can(config.PermRedemptionsRead), r.redemptionController.GetRedemptionById)
+			protected.GET("/redemptions/store/:store_id", can(config.PermRedemptionsRead), r.redemptionController.GetRedemptionsByStoreId)
+			protected.POST("/redemptions/:id/fulfill", can(config.PermRedemptionsWrite), r.redemptionController.FulfillRedemption)
+			protected.POST("/redemptions/:id/cancel", can(config.PermRedemptionsWrite), r.redemptionController.CancelRedemption)
+
+			protected.GET("/transactions", can(config.PermTransactionsAll), r.transactionController.GetAllTransactions)
+			protected.GET("/transactions/:id", can(config.PermTransactionsRead), r.transactionController.GetTransactionById)
+			protected.GET("/transactions/user/:user_id", can(config.PermTransactionsRead), own, r.transactionController.GetTransactionsByUserId)
+			protected.POST("/transactions", can(config.PermTransactionsWrite), r.transactionController.CreateTransaction)
+			protected.POST("/transactions/preview", can(config.PermTransactionsQuote), r.transactionController.PreviewTransaction)
+			protected.POST("/transactions/:id/refund", can(config.PermTransactionsRefund), r.transactionController.RefundTransaction)
+			protected.GET("/transactions/:id/refunds", can(config.PermTransactionsRead), r.transactionController.GetRefundsByTransactionId)
+
+			protected.GET("/tiers/store/:store_id", can(config.PermTiersRead), r.tierController.GetTiersByStoreId)
+			protected.GET("/tiers/user/:user_id/store/:store_id/progress", can(config.PermTiersRead), own, r.tierController.GetTierProgress)
+			protected.POST("/tiers", can(config.PermTiersWrite), r.tierController.CreateTier)
+			protected.PUT("/tiers/:id", can(config.PermTiersWrite), r.tierController.UpdateTier)
+			protected.DELETE("/tiers/:id", can(config.PermTiersWrite), r.tierController.DeleteTier)
+
+			protected.POST("/transfers", can(config.PermTransfersWrite), r.pointTransferController.TransferPoints)
+			protected.GET("/transfers/user/:user_id/store/:store_id", can(config.PermTransfersRead), own, r.pointTransferController.GetTransfersByUserAndStore)
+
+			protected.GET("/exchange-rates/store/:store_id", can(config.PermExchangesRead), r.exchangeController.GetRatesByStoreId)
+			protected.POST("/exchange-rates", can(config.PermExchangeRatesWrite), r.exchangeController.CreateRate)
+			protected.PUT("/exchange-rates/:id", can(config.PermExchangeRatesWrite), r.exchangeController.UpdateRate)
+			protected.DELETE("/exchange-rates/:id", can(config.PermExchangeRatesWrite), r.exchangeController.DeleteRate)
+			protected.POST("/exchanges", can(config.PermExchangesWrite), r.exchangeController.ExchangePoints)
+			protected.GET("/exchanges/user/:user_id", can(config.PermExchangesRead), own, r.exchangeController.GetExchangesByUserId)
+			protected.GET("/exchanges/settlements/store/:store_id", can(config.PermSettlementsRead), r.exchangeController.GetSettlements)
 		}
 	}
 }